@@ -15,9 +15,159 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
+        "/auth/logout": {
+            "post": {
+                "description": "Revoke access and refresh tokens of the session and clear token cookies",
+                "consumes": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Logout",
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/logout-all": {
+            "post": {
+                "description": "Revoke all access and refresh tokens of the user(which id contains in cookie: jwt token)\nand clear token cookies",
+                "consumes": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Logout on all devices",
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/refresh": {
+            "post": {
+                "description": "Exchange refresh token(which contains in cookie refresh_token or in the body) for a new pair\nof access and refresh tokens and return user info.\nEvery refresh token can be used only once, reuse of it revokes all tokens of the session",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Refresh tokens",
+                "parameters": [
+                    {
+                        "description": "Refresh token for clients without cookies",
+                        "name": "refresh",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/entities.RefreshRequest"
+                        }
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Return tokens in the body, response is entities.Session",
+                        "name": "token_in_body",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/entities.User"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    }
+                }
+            }
+        },
         "/drink": {
+            "get": {
+                "description": "Get all drinks page by page in id order.\nTo get the next page pass next_cursor of the current page as cursor,\nthere are no more drinks when next_cursor is absent",
+                "consumes": [
+                    "text/plain"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "drink"
+                ],
+                "summary": "Get all drinks",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "page size, 20 by default, max 100",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "next_cursor from the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/pagination.Page-entities_Drink"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad limit or cursor",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    }
+                }
+            },
             "put": {
-                "description": "Updates drink tags with the specified name(old tags will be deleted)",
+                "description": "Updates drink tags and recipe with the specified name(old tags and ingredients will be deleted)",
                 "consumes": [
                     "application/json"
                 ],
@@ -27,7 +177,7 @@ const docTemplate = `{
                 "tags": [
                     "drink"
                 ],
-                "summary": "Updates drink tags",
+                "summary": "Updates drink",
                 "parameters": [
                     {
                         "description": "Drink what we update with optional tags,if tags not: set tags will be empty, name is required,",
@@ -46,16 +196,40 @@ const docTemplate = `{
                             "$ref": "#/definitions/entities.Drink"
                         }
                     },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    },
+                    "401": {
+                        "description": "Not authenticated",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    },
+                    "403": {
+                        "description": "Not admin",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/errlib.Response"
                         }
                     }
                 }
             },
             "post": {
-                "description": "Creates a drink with the specified name and tags",
+                "description": "Creates a drink with the specified name, tags and recipe",
                 "consumes": [
                     "application/json"
                 ],
@@ -68,7 +242,7 @@ const docTemplate = `{
                 "summary": "Creates a drink",
                 "parameters": [
                     {
-                        "description": "Drink what we add with optional tags,if tags not: set tags will be empty, name is required,",
+                        "description": "Drink what we add with optional tags and recipe,if tags not: set tags will be empty, name is required,",
                         "name": "drink",
                         "in": "body",
                         "required": true,
@@ -84,18 +258,42 @@ const docTemplate = `{
                             "$ref": "#/definitions/entities.Drink"
                         }
                     },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    },
+                    "401": {
+                        "description": "Not authenticated",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    },
+                    "403": {
+                        "description": "Not admin",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    },
+                    "409": {
+                        "description": "Name is taken by another drink",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/errlib.Response"
                         }
                     }
                 }
             }
         },
-        "/drink/id/{id}": {
+        "/drink/name/{name}": {
             "get": {
-                "description": "Get all drinks with offset = id",
+                "description": "Get drink by name",
                 "consumes": [
                     "text/plain"
                 ],
@@ -105,12 +303,12 @@ const docTemplate = `{
                 "tags": [
                     "drink"
                 ],
-                "summary": "Get all drinks",
+                "summary": "Get drink by name",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "id",
-                        "name": "id",
+                        "type": "string",
+                        "description": "Name of the drink",
+                        "name": "name",
                         "in": "path",
                         "required": true
                     }
@@ -119,24 +317,27 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/entities.Drink"
-                            }
+                            "$ref": "#/definitions/entities.Drink"
+                        }
+                    },
+                    "404": {
+                        "description": "Not found",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
                         }
                     },
                     "500": {
                         "description": "Internal server error",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/errlib.Response"
                         }
                     }
                 }
             }
         },
-        "/drink/name/{name}": {
+        "/drink/search": {
             "get": {
-                "description": "Get drink by name",
+                "description": "Search drinks by name and tags with prefix, typo-tolerant and full-text matching,\ndrinks are ordered by relevance: \"blu fary\" finds \"Blue Fairy\"",
                 "consumes": [
                     "text/plain"
                 ],
@@ -146,33 +347,42 @@ const docTemplate = `{
                 "tags": [
                     "drink"
                 ],
-                "summary": "Get drink by name",
+                "summary": "Search drinks by name",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Name of the drink",
-                        "name": "name",
-                        "in": "path",
+                        "description": "search text",
+                        "name": "q",
+                        "in": "query",
                         "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "max count of drinks, 20 by default, max 100",
+                        "name": "limit",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/entities.Drink"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/entities.Drink"
+                            }
                         }
                     },
-                    "404": {
-                        "description": "Not found",
+                    "400": {
+                        "description": "Empty query or bad limit",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/errlib.Response"
                         }
                     },
                     "500": {
                         "description": "Internal server error",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/errlib.Response"
                         }
                     }
                 }
@@ -198,36 +408,51 @@ const docTemplate = `{
                         "name": "tag",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "page size, 20 by default, max 100",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "next_cursor from the previous page",
+                        "name": "cursor",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/entities.Drink"
-                            }
+                            "$ref": "#/definitions/pagination.Page-entities_Drink"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad limit or cursor",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
                         }
                     },
                     "404": {
                         "description": "Not found",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/errlib.Response"
                         }
                     },
                     "500": {
                         "description": "Internal server error",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/errlib.Response"
                         }
                     }
                 }
             }
         },
-        "/drink/{name}": {
-            "delete": {
-                "description": "Deletes a drink with the specified name,other fields will be ignored",
+        "/drink/tags": {
+            "get": {
+                "description": "Search drinks by tags with modes: drink must have all tags from \"all\",\nat least one tag from \"any\" and no tags from \"none\".\nEvery param can be repeated or contain comma separated tags, matching is exact and case-insensitive\nExample: /drink/tags?all=Sweet,Girly\u0026none=Soft",
                 "consumes": [
                     "text/plain"
                 ],
@@ -237,41 +462,70 @@ const docTemplate = `{
                 "tags": [
                     "drink"
                 ],
-                "summary": "Deletes a drink",
+                "summary": "Search drinks by several tags",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Name of the drink to delete",
-                        "name": "name",
-                        "in": "path",
-                        "required": true
+                        "description": "drink has all of these tags",
+                        "name": "all",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "drink has at least one of these tags",
+                        "name": "any",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "drink has none of these tags",
+                        "name": "none",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "page size, 20 by default, max 100",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "next_cursor from the previous page",
+                        "name": "cursor",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/pagination.Page-entities_Drink"
+                        }
+                    },
+                    "400": {
+                        "description": "No tags in query or bad limit or cursor",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
                         }
                     },
                     "404": {
-                        "description": "Not Found",
+                        "description": "Not found",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/errlib.Response"
                         }
                     },
                     "500": {
-                        "description": "Internal Server Error",
+                        "description": "Internal server error",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/errlib.Response"
                         }
                     }
                 }
             }
         },
-        "/user": {
-            "post": {
-                "description": "field id will be ignored\nid will be in response\nCreate a user,with his favourite drinks(optional),if such drinks non-existent: error,\notherwise return created user",
+        "/drink/{id}": {
+            "put": {
+                "description": "Replaces all fields of the drink with the specified id, name can be changed too.\nTags and ingredients which aren't passed will be removed",
                 "consumes": [
                     "application/json"
                 ],
@@ -279,45 +533,74 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "user"
+                    "drink"
                 ],
-                "summary": "Create a user",
+                "summary": "Replaces drink",
                 "parameters": [
                     {
-                        "description": "User object",
-                        "name": "user",
+                        "type": "integer",
+                        "description": "Drink id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "New drink, name is required, id is ignored",
+                        "name": "drink",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/entities.User"
+                            "$ref": "#/definitions/entities.Drink"
                         }
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/entities.User"
+                            "$ref": "#/definitions/entities.Drink"
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    },
+                    "401": {
+                        "description": "Not authenticated",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    },
+                    "403": {
+                        "description": "Not admin",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not found",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    },
+                    "409": {
+                        "description": "Name is taken by another drink",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/errlib.Response"
                         }
                     }
                 }
-            }
-        },
-        "/user/fav": {
+            },
             "patch": {
-                "description": "Add a favourite drink to user",
+                "description": "Updates drink with the specified id by JSON merge patch (RFC 7386):\nonly passed fields are changed, null resets the field, tags and ingredients are replaced entirely",
                 "consumes": [
                     "application/json"
                 ],
@@ -325,43 +608,76 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "user"
+                    "drink"
                 ],
-                "summary": "Add a favourite drink to user",
+                "summary": "Partially updates drink",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Drink name",
-                        "name": "drinkname",
+                        "type": "integer",
+                        "description": "Drink id",
+                        "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "description": "Only fields to change, e.g. new name and tags",
+                        "name": "patch",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/entities.Drink"
+                        }
                     }
                 ],
                 "responses": {
-                    "202": {
-                        "description": "Accepted",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/entities.User"
+                            "$ref": "#/definitions/entities.Drink"
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    },
+                    "401": {
+                        "description": "Not authenticated",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    },
+                    "403": {
+                        "description": "Not admin",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not found",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    },
+                    "409": {
+                        "description": "Name is taken by another drink",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/errlib.Response"
                         }
                     }
                 }
             }
         },
-        "/user/login": {
-            "get": {
-                "description": "Login user,if user non-existent: error\notherwise return user info\nassigment to user cookie(encoded jwt token) wih user info",
+        "/drink/{id}/rating": {
+            "put": {
+                "description": "Rates the drink from 1 to 5 with an optional review by the user which id contains in token,\nrating the drink again replaces the previous rating",
                 "consumes": [
                     "application/json"
                 ],
@@ -369,34 +685,111 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "user"
+                    "rating"
+                ],
+                "summary": "Rates a drink",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Drink id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Score and review",
+                        "name": "rating",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/entities.NewRating"
+                        }
+                    }
                 ],
-                "summary": "Login",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/entities.User"
+                            "$ref": "#/definitions/entities.Rating"
+                        }
+                    },
+                    "400": {
+                        "description": "Score isn't from 1 to 5 or review is too long, every failed rule is in errors",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
                         }
                     },
                     "401": {
-                        "description": "Unauthorized",
+                        "description": "Not authenticated",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Drink not found",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "consumes": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "rating"
+                ],
+                "summary": "Deletes own rating of a drink",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Drink id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    },
+                    "401": {
+                        "description": "Not authenticated",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Drink isn't rated by the user",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
                         }
                     }
                 }
             }
         },
-        "/user/{id}": {
+        "/drink/{id}/ratings": {
             "get": {
-                "description": "Get user by ID(which contains in cookie: jwt token)",
+                "description": "Get ratings and reviews of the drink page by page in user id order",
                 "consumes": [
                     "text/plain"
                 ],
@@ -404,73 +797,1692 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "user"
+                    "rating"
+                ],
+                "summary": "Get ratings of a drink",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Drink id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "page size, 20 by default, max 100",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "next_cursor from the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    }
                 ],
-                "summary": "Get user",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/entities.User"
+                            "$ref": "#/definitions/pagination.Page-entities_Rating"
                         }
                     },
                     "400": {
-                        "description": "Bad Request",
+                        "description": "Bad id, limit or cursor",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Drink not found",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/errlib.Response"
                         }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "entities.Drink": {
-            "type": "object",
-            "properties": {
-                "id": {
-                    "type": "integer",
-                    "example": 12
-                },
-                "name": {
-                    "type": "string",
-                    "example": "Coca Cola"
-                },
-                "tags": {
-                    "type": "array",
-                    "items": {
-                        "type": "string"
+        },
+        "/drink/{name}": {
+            "delete": {
+                "description": "Deletes a drink with the specified name,other fields will be ignored",
+                "consumes": [
+                    "text/plain"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "drink"
+                ],
+                "summary": "Deletes a drink",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Name of the drink to delete",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
                     },
-                    "example": [
-                        "[\"soda\"",
-                        "\"cola\"]"
-                    ]
+                    "401": {
+                        "description": "Not authenticated",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    },
+                    "403": {
+                        "description": "Not admin",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    }
                 }
             }
         },
-        "entities.User": {
-            "type": "object",
-            "properties": {
-                "drinknames": {
-                    "type": "array",
-                    "items": {
-                        "type": "string"
+        "/ingredient": {
+            "get": {
+                "description": "Get ingredient catalog page by page in id order",
+                "consumes": [
+                    "text/plain"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "ingredient"
+                ],
+                "summary": "Get all ingredients",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "page size, 20 by default, max 100",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "next_cursor from the previous page",
+                        "name": "cursor",
+                        "in": "query"
                     }
-                },
-                "id": {
-                    "type": "integer"
-                },
-                "password": {
-                    "type": "string"
-                },
-                "username": {
-                    "type": "string"
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/pagination.Page-github_com_SapolovichSV_backprogeng_internal_ingredient_entities_Ingredient"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad limit or cursor",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Adds an ingredient to the catalog, names are unique case-insensitive",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "ingredient"
+                ],
+                "summary": "Creates an ingredient",
+                "parameters": [
+                    {
+                        "description": "Ingredient, id will be ignored",
+                        "name": "ingredient",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_SapolovichSV_backprogeng_internal_ingredient_entities.Ingredient"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_SapolovichSV_backprogeng_internal_ingredient_entities.Ingredient"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    },
+                    "401": {
+                        "description": "Not authenticated",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    },
+                    "403": {
+                        "description": "Not admin",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    },
+                    "409": {
+                        "description": "Ingredient with such name exists",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/ingredient/mix": {
+            "post": {
+                "description": "Returns drinks which can be made from the posted ingredients\nand drinks which miss only one ingredient, with this ingredient named.\nIngredients are compared case-insensitive",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "ingredient"
+                ],
+                "summary": "What can I mix",
+                "parameters": [
+                    {
+                        "description": "Ingredients on hand",
+                        "name": "inventory",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/entities.Inventory"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/entities.MixResult"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/ingredient/{id}": {
+            "get": {
+                "consumes": [
+                    "text/plain"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "ingredient"
+                ],
+                "summary": "Get ingredient",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Ingredient id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_SapolovichSV_backprogeng_internal_ingredient_entities.Ingredient"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Renames an ingredient, recipes which use it will show the new name",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "ingredient"
+                ],
+                "summary": "Renames an ingredient",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Ingredient id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Ingredient with a new name, id will be ignored",
+                        "name": "ingredient",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_SapolovichSV_backprogeng_internal_ingredient_entities.Ingredient"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_SapolovichSV_backprogeng_internal_ingredient_entities.Ingredient"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    },
+                    "401": {
+                        "description": "Not authenticated",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    },
+                    "403": {
+                        "description": "Not admin",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    },
+                    "409": {
+                        "description": "Ingredient with such name exists",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Deletes an ingredient which isn't used in any recipe",
+                "consumes": [
+                    "text/plain"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "ingredient"
+                ],
+                "summary": "Deletes an ingredient",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Ingredient id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    },
+                    "401": {
+                        "description": "Not authenticated",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    },
+                    "403": {
+                        "description": "Not admin",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    },
+                    "409": {
+                        "description": "Ingredient is used in recipes",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/user": {
+            "post": {
+                "description": "field id will be ignored\nid will be in response\nCreate a user,with his favourite drinks(optional),if such drinks non-existent: error,\notherwise return created user",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Create a user",
+                "parameters": [
+                    {
+                        "description": "User object",
+                        "name": "user",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/entities.User"
+                        }
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Return tokens in the body, response is entities.Session",
+                        "name": "token_in_body",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/entities.User"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid username or password, every failed rule is in errors",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Favourite drink not found",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    },
+                    "409": {
+                        "description": "Username is taken",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/apikeys": {
+            "get": {
+                "description": "List personal API keys of the user, only prefixes of the keys are shown",
+                "consumes": [
+                    "text/plain"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "apikeys"
+                ],
+                "summary": "List API keys",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/github_com_SapolovichSV_backprogeng_internal_user_entities.APIKey"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    },
+                    "403": {
+                        "description": "Request is made with API key",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Create a personal API key of the user for scripts and services.\nThe key is shown only in this response, store it at once.\nSend it in X-API-Key header or as Authorization: Bearer token.\nScope read allows GET requests, write allows the others, key without scopes can only read",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "apikeys"
+                ],
+                "summary": "Create API key",
+                "parameters": [
+                    {
+                        "description": "Name, scopes and optional expiry of the key",
+                        "name": "key",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/entities.NewAPIKey"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/entities.CreatedAPIKey"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    },
+                    "403": {
+                        "description": "Request is made with API key",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    },
+                    "409": {
+                        "description": "Key with such name exists",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/apikeys/{id}": {
+            "delete": {
+                "description": "Revoke personal API key of the user, requests with it are rejected at once",
+                "consumes": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "apikeys"
+                ],
+                "summary": "Revoke API key",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "API key ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    },
+                    "403": {
+                        "description": "Request is made with API key",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/fav": {
+            "get": {
+                "description": "List favourite drinks of the user(which id contains in cookie: jwt token)\npage by page in drink id order.\nTo get the next page pass next_cursor of the current page as cursor",
+                "consumes": [
+                    "text/plain"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "List favourite drinks",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "page size, 20 by default, max 100",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "next_cursor from the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/pagination.Page-entities_Drink"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "description": "Add a favourite drink to user, use PUT /user/fav/{drink} instead",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Add a favourite drink to user",
+                "deprecated": true,
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Drink name",
+                        "name": "drinkname",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/entities.User"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/fav/{drink}": {
+            "put": {
+                "description": "Add the drink to favourites of the user(which id contains in token) and return the user.\nAdding the drink which is already favourite changes nothing",
+                "consumes": [
+                    "text/plain"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Add a favourite drink",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Drink name",
+                        "name": "drink",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/entities.User"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Drink not found",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Remove the drink from favourites of the user(which id contains in token)",
+                "consumes": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Remove a favourite drink",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Drink name",
+                        "name": "drink",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Drink not found or isn't favourite",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/login": {
+            "post": {
+                "description": "Login user by username and password,\nassigment to user cookie(encoded jwt token) wih user info and return user info.\nAfter several failed attempts client is blocked for a while",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Login",
+                "parameters": [
+                    {
+                        "description": "Username and password",
+                        "name": "credentials",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/entities.Credentials"
+                        }
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Return tokens in the body, response is entities.Session",
+                        "name": "token_in_body",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/entities.User"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    },
+                    "401": {
+                        "description": "Wrong username or password",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many failed attempts, see Retry-After header",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/recommendations": {
+            "get": {
+                "description": "Drinks the user(which id contains in token) hasn't favourited, best first.\nScore is 0.6 * tag_score + 0.4 * co_score: tag_score is similarity of the drink tags\nand tags of favourite drinks, co_score is a share of users with common favourites\nwho have favourited the drink. Every result explains its score.\nUser without favourites gets an empty list",
+                "consumes": [
+                    "text/plain"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Recommended drinks",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "max count of drinks, 20 by default, max 100",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/entities.Recommendation"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad limit",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/{id}": {
+            "get": {
+                "description": "Get user by ID, users can get only themselves, admins can get anyone",
+                "consumes": [
+                    "text/plain"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Get user",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/entities.User"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    },
+                    "403": {
+                        "description": "Not the user itself or admin",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/{id}/ratings": {
+            "get": {
+                "description": "Get ratings and reviews given by the user page by page in drink id order",
+                "consumes": [
+                    "text/plain"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "rating"
+                ],
+                "summary": "Get ratings given by a user",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "User id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "page size, 20 by default, max 100",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "next_cursor from the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/pagination.Page-entities_Rating"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad id, limit or cursor",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/{id}/role": {
+            "put": {
+                "description": "Promote user to admin or demote to user, available only to admins.\nAccess tokens of the user are revoked, so the new role comes into force\non the next refresh or login and a demoted admin loses admin rights at once",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Change role of user",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "New role: user or admin",
+                        "name": "role",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/entities.RoleChange"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/entities.User"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    },
+                    "403": {
+                        "description": "Not admin",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/errlib.Response"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "entities.AlmostDrink": {
+            "type": "object",
+            "properties": {
+                "drink": {
+                    "$ref": "#/definitions/entities.Drink"
+                },
+                "missing": {
+                    "type": "string",
+                    "example": "Flanergide"
+                }
+            }
+        },
+        "entities.CreatedAPIKey": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "key": {
+                    "type": "string",
+                    "example": "bpg_3fK9aQ1zXn..."
+                },
+                "name": {
+                    "type": "string",
+                    "example": "catalog-seeder"
+                },
+                "prefix": {
+                    "type": "string",
+                    "example": "bpg_3fK9aQ1z"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "read",
+                        "write"
+                    ]
+                }
+            }
+        },
+        "entities.Credentials": {
+            "type": "object",
+            "properties": {
+                "password": {
+                    "type": "string",
+                    "example": "amahasla"
+                },
+                "username": {
+                    "type": "string",
+                    "example": "Stas001"
+                }
+            }
+        },
+        "entities.Drink": {
+            "type": "object",
+            "properties": {
+                "alcoholic": {
+                    "type": "boolean",
+                    "example": false
+                },
+                "description": {
+                    "type": "string",
+                    "example": "Sweet soda with a lot of sugar"
+                },
+                "id": {
+                    "type": "integer",
+                    "example": 12
+                },
+                "ingredients": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_SapolovichSV_backprogeng_internal_drink_entities.Ingredient"
+                    }
+                },
+                "name": {
+                    "type": "string",
+                    "example": "Coca Cola"
+                },
+                "preparation": {
+                    "enum": [
+                        "mixed",
+                        "aged",
+                        "iced",
+                        "blended"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/entities.Preparation"
+                        }
+                    ],
+                    "example": "iced"
+                },
+                "price": {
+                    "type": "integer",
+                    "example": 150
+                },
+                "rating_average": {
+                    "description": "RatingAverage and RatingCount are computed from ratings, they are ignored in requests",
+                    "type": "number",
+                    "example": 4.5
+                },
+                "rating_count": {
+                    "type": "integer",
+                    "example": 12
+                },
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "[\"soda\"",
+                        "\"cola\"]"
+                    ]
+                }
+            }
+        },
+        "entities.Explanation": {
+            "type": "object",
+            "properties": {
+                "co_favourites": {
+                    "description": "CoFavourites is how many users with common favourite drinks have favourited the drink",
+                    "type": "integer",
+                    "example": 3
+                },
+                "co_score": {
+                    "description": "CoScore is CoFavourites divided by the count of users with common favourite drinks",
+                    "type": "number",
+                    "example": 0.6
+                },
+                "reason": {
+                    "type": "string",
+                    "example": "shares tags sour, sweet with your favourites; favourited by 3 users who like your drinks"
+                },
+                "shared_tags": {
+                    "description": "SharedTags are tags the drink shares with favourite drinks of the user",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "sour",
+                        "sweet"
+                    ]
+                },
+                "tag_score": {
+                    "description": "TagScore is Jaccard similarity of tags of the drink and tags of favourite drinks",
+                    "type": "number",
+                    "example": 0.5
+                }
+            }
+        },
+        "entities.Inventory": {
+            "type": "object",
+            "properties": {
+                "ingredients": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "Adelhyde",
+                        "Karmotrine"
+                    ]
+                }
+            }
+        },
+        "entities.MixResult": {
+            "type": "object",
+            "properties": {
+                "almost": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/entities.AlmostDrink"
+                    }
+                },
+                "full": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/entities.Drink"
+                    }
+                }
+            }
+        },
+        "entities.NewAPIKey": {
+            "type": "object",
+            "properties": {
+                "expires_at": {
+                    "type": "string",
+                    "example": "2026-01-01T00:00:00Z"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "catalog-seeder"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "read",
+                        "write"
+                    ]
+                }
+            }
+        },
+        "entities.NewRating": {
+            "type": "object",
+            "properties": {
+                "review": {
+                    "type": "string",
+                    "example": "Sour and tasty"
+                },
+                "score": {
+                    "type": "integer",
+                    "maximum": 5,
+                    "minimum": 1,
+                    "example": 5
+                }
+            }
+        },
+        "entities.Preparation": {
+            "type": "string",
+            "enum": [
+                "mixed",
+                "aged",
+                "iced",
+                "blended"
+            ],
+            "x-enum-varnames": [
+                "PreparationMixed",
+                "PreparationAged",
+                "PreparationIced",
+                "PreparationBlended"
+            ]
+        },
+        "entities.Rating": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "drink_id": {
+                    "type": "integer",
+                    "example": 12
+                },
+                "drink_name": {
+                    "type": "string",
+                    "example": "Bad Touch"
+                },
+                "review": {
+                    "type": "string",
+                    "example": "Sour and tasty"
+                },
+                "score": {
+                    "type": "integer",
+                    "maximum": 5,
+                    "minimum": 1,
+                    "example": 5
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "integer",
+                    "example": 7
+                },
+                "username": {
+                    "type": "string",
+                    "example": "Stas001"
+                }
+            }
+        },
+        "entities.Recommendation": {
+            "type": "object",
+            "properties": {
+                "drink": {
+                    "$ref": "#/definitions/entities.Drink"
+                },
+                "explanation": {
+                    "$ref": "#/definitions/entities.Explanation"
+                },
+                "score": {
+                    "type": "number",
+                    "example": 0.55
+                }
+            }
+        },
+        "entities.RefreshRequest": {
+            "type": "object",
+            "properties": {
+                "refresh_token": {
+                    "type": "string"
+                }
+            }
+        },
+        "entities.RoleChange": {
+            "type": "object",
+            "properties": {
+                "role": {
+                    "type": "string",
+                    "example": "admin"
+                }
+            }
+        },
+        "entities.User": {
+            "type": "object",
+            "properties": {
+                "drinknames": {
+                    "description": "FavouritesDrinkName are names of favourite drinks sent on registration,\nresponses have whole drinks in Favourites instead",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "favourites": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/entities.Drink"
+                    }
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "password": {
+                    "type": "string"
+                },
+                "role": {
+                    "description": "Role is ignored in requests, it is changed only by admins",
+                    "type": "string"
+                },
+                "username": {
+                    "type": "string"
+                }
+            }
+        },
+        "errlib.FieldError": {
+            "type": "object",
+            "properties": {
+                "field": {
+                    "type": "string",
+                    "example": "username"
+                },
+                "message": {
+                    "type": "string",
+                    "example": "must be at most 32 characters"
+                },
+                "rule": {
+                    "type": "string",
+                    "example": "max_length"
+                }
+            }
+        },
+        "errlib.Response": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string",
+                    "example": "not_found"
+                },
+                "details": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "errors": {
+                    "description": "Errors lists every failed rule when validation fails",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/errlib.FieldError"
+                    }
+                },
+                "message": {
+                    "type": "string",
+                    "example": "drink not found in drinks"
+                },
+                "request_id": {
+                    "type": "string",
+                    "example": "rMsRkJvUXbkUfhdJCxJjSDWYYJXTMWVo"
+                }
+            }
+        },
+        "github_com_SapolovichSV_backprogeng_internal_drink_entities.Ingredient": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number",
+                    "example": 2
+                },
+                "name": {
+                    "type": "string",
+                    "example": "Adelhyde"
+                },
+                "unit": {
+                    "type": "string",
+                    "example": "oz"
+                }
+            }
+        },
+        "github_com_SapolovichSV_backprogeng_internal_ingredient_entities.Ingredient": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "integer",
+                    "example": 3
+                },
+                "name": {
+                    "type": "string",
+                    "example": "Adelhyde"
+                }
+            }
+        },
+        "github_com_SapolovichSV_backprogeng_internal_user_entities.APIKey": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "catalog-seeder"
+                },
+                "prefix": {
+                    "type": "string",
+                    "example": "bpg_3fK9aQ1z"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "read",
+                        "write"
+                    ]
+                }
+            }
+        },
+        "pagination.Page-entities_Drink": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/entities.Drink"
+                    }
+                },
+                "next_cursor": {
+                    "type": "string",
+                    "example": "eyJpZCI6MjB9"
+                }
+            }
+        },
+        "pagination.Page-entities_Rating": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/entities.Rating"
+                    }
+                },
+                "next_cursor": {
+                    "type": "string",
+                    "example": "eyJpZCI6MjB9"
+                }
+            }
+        },
+        "pagination.Page-github_com_SapolovichSV_backprogeng_internal_ingredient_entities_Ingredient": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_SapolovichSV_backprogeng_internal_ingredient_entities.Ingredient"
+                    }
+                },
+                "next_cursor": {
+                    "type": "string",
+                    "example": "eyJpZCI6MjB9"
                 }
             }
         }
@@ -0,0 +1,157 @@
+package authmiddleware
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"slices"
+
+	authEnt "github.com/SapolovichSV/backprogeng/internal/authmiddleware/entities"
+	"github.com/SapolovichSV/backprogeng/internal/authmiddleware/queries"
+	"github.com/SapolovichSV/backprogeng/internal/errlib"
+	"github.com/SapolovichSV/backprogeng/internal/user/entities"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/labstack/echo/v4"
+)
+
+// apiKeyStore is implemented by SQLAPIKeyStore
+type apiKeyStore interface {
+	Create(ctx context.Context, k authEnt.APIKey) (authEnt.APIKey, error)
+	ByHash(ctx context.Context, hash string) (authEnt.APIKey, error)
+	OfUser(ctx context.Context, userID int) ([]authEnt.APIKey, error)
+	Delete(ctx context.Context, userID int, id int) error
+}
+
+// Scopes of API keys
+const (
+	ScopeRead  = entities.ScopeRead
+	ScopeWrite = entities.ScopeWrite
+)
+
+const (
+	// APIKeyPrefix starts every API key, so keys sent as Bearer tokens are told apart from JWTs
+	APIKeyPrefix = "bpg_"
+	// apiKeyHeader is an alternative to Authorization: Bearer for API keys
+	apiKeyHeader = "X-API-Key"
+	// apiKeyShownPrefix is the length of the beginning of the key kept to show in the list
+	apiKeyShownPrefix = len(APIKeyPrefix) + 8
+	apiKeyMaxName     = 64
+)
+
+var (
+	ErrAPIKeyUnknown = errors.New("api key is unknown")
+	ErrAPIKeyExpired = errors.New("api key is expired")
+)
+
+// DB:
+// api_keys
+// id | key_hash | prefix | user_id | name | scopes | expires_at | created_at
+type SQLAPIKeyStore struct {
+	db *pgxpool.Pool
+}
+
+func NewSQLAPIKeyStore(db *pgxpool.Pool) *SQLAPIKeyStore {
+	return &SQLAPIKeyStore{
+		db: db,
+	}
+}
+func (s *SQLAPIKeyStore) Create(ctx context.Context, k authEnt.APIKey) (authEnt.APIKey, error) {
+	return queries.New(ctx, s.db).CreateAPIKey(k)
+}
+func (s *SQLAPIKeyStore) ByHash(ctx context.Context, hash string) (authEnt.APIKey, error) {
+	return queries.New(ctx, s.db).APIKeyByHash(hash)
+}
+func (s *SQLAPIKeyStore) OfUser(ctx context.Context, userID int) ([]authEnt.APIKey, error) {
+	return queries.New(ctx, s.db).APIKeysByUserID(userID)
+}
+
+// Delete deletes the key of the user, errlib.NotFoundErr is returned
+// if the user has no such key
+func (s *SQLAPIKeyStore) Delete(ctx context.Context, userID int, id int) error {
+	return queries.New(ctx, s.db).DeleteAPIKey(userID, id)
+}
+
+// CreateAPIKey creates a personal API key of the user, the key is returned
+// only here, later only its prefix is shown
+func (a *authMiddle) CreateAPIKey(c echo.Context, userID int, req entities.NewAPIKey) (entities.CreatedAPIKey, error) {
+	if req.Name == "" || len(req.Name) > apiKeyMaxName {
+		return entities.CreatedAPIKey{}, errlib.InvalidInputErr{What: "api key name", Reason: "must be from 1 to 64 characters"}
+	}
+	scopes := req.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{ScopeRead}
+	}
+	for _, scope := range scopes {
+		if scope != ScopeRead && scope != ScopeWrite {
+			return entities.CreatedAPIKey{}, errlib.InvalidInputErr{What: "api key scope " + scope, Reason: "must be read or write"}
+		}
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(a.now()) {
+		return entities.CreatedAPIKey{}, errlib.InvalidInputErr{What: "api key expires_at", Reason: "must be in the future"}
+	}
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return entities.CreatedAPIKey{}, errlib.WrapErr(err, "failing to generate api key")
+	}
+	key := APIKeyPrefix + base64.RawURLEncoding.EncodeToString(b)
+	stored, err := a.apiKeys.Create(c.Request().Context(), authEnt.APIKey{
+		Hash:      hashToken(key),
+		Prefix:    key[:apiKeyShownPrefix],
+		UserID:    userID,
+		Name:      req.Name,
+		Scopes:    slices.Compact(slices.Sorted(slices.Values(scopes))),
+		ExpiresAt: req.ExpiresAt,
+	})
+	if err != nil {
+		return entities.CreatedAPIKey{}, err
+	}
+	return entities.CreatedAPIKey{APIKey: toAPIKey(stored), Key: key}, nil
+}
+
+// APIKeys returns keys of the user without the keys themselves
+func (a *authMiddle) APIKeys(c echo.Context, userID int) ([]entities.APIKey, error) {
+	stored, err := a.apiKeys.OfUser(c.Request().Context(), userID)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]entities.APIKey, 0, len(stored))
+	for _, k := range stored {
+		keys = append(keys, toAPIKey(k))
+	}
+	return keys, nil
+}
+
+// RevokeAPIKey deletes the key of the user, requests with it get 401 at once
+func (a *authMiddle) RevokeAPIKey(c echo.Context, userID int, keyID int) error {
+	return a.apiKeys.Delete(c.Request().Context(), userID, keyID)
+}
+
+// authenticateAPIKey проверяет ключ, роль берём текущую роль владельца,
+// а не ту, что была при создании ключа
+func (a *authMiddle) authenticateAPIKey(ctx context.Context, key string) (Principal, error) {
+	stored, err := a.apiKeys.ByHash(ctx, hashToken(key))
+	if errlib.CheckErrNotFound(err) {
+		return Principal{}, ErrAPIKeyUnknown
+	} else if err != nil {
+		return Principal{}, errlib.WrapErr(err, "failing to get api key")
+	}
+	if stored.ExpiresAt != nil && !a.now().Before(*stored.ExpiresAt) {
+		return Principal{}, ErrAPIKeyExpired
+	}
+	role, err := a.roles.RoleByUserID(ctx, stored.UserID)
+	if err != nil {
+		return Principal{}, errlib.WrapErr(err, "failing to get role of user")
+	}
+	return Principal{UserID: stored.UserID, Role: role, APIKeyID: stored.ID, Scopes: stored.Scopes}, nil
+}
+func toAPIKey(k authEnt.APIKey) entities.APIKey {
+	return entities.APIKey{
+		ID:        k.ID,
+		Name:      k.Name,
+		Prefix:    k.Prefix,
+		Scopes:    k.Scopes,
+		ExpiresAt: k.ExpiresAt,
+		CreatedAt: k.CreatedAt,
+	}
+}
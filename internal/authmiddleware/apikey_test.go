@@ -0,0 +1,197 @@
+package authmiddleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	authEnt "github.com/SapolovichSV/backprogeng/internal/authmiddleware/entities"
+	"github.com/SapolovichSV/backprogeng/internal/errlib"
+	"github.com/SapolovichSV/backprogeng/internal/user/entities"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memAPIKeyStore повторяет логику SQLAPIKeyStore в памяти
+type memAPIKeyStore struct {
+	keys   map[string]authEnt.APIKey
+	lastID int
+}
+
+func newMemAPIKeyStore() *memAPIKeyStore {
+	return &memAPIKeyStore{keys: map[string]authEnt.APIKey{}}
+}
+func (s *memAPIKeyStore) Create(ctx context.Context, k authEnt.APIKey) (authEnt.APIKey, error) {
+	for _, other := range s.keys {
+		if other.UserID == k.UserID && other.Name == k.Name {
+			return authEnt.APIKey{}, errlib.ConflictErr{Where: "api_keys", What: "api key " + k.Name}
+		}
+	}
+	s.lastID++
+	k.ID = s.lastID
+	k.CreatedAt = testNow
+	s.keys[k.Hash] = k
+	return k, nil
+}
+func (s *memAPIKeyStore) ByHash(ctx context.Context, hash string) (authEnt.APIKey, error) {
+	k, ok := s.keys[hash]
+	if !ok {
+		return authEnt.APIKey{}, errlib.NotFoundErr{Where: "api_keys", What: "api key"}
+	}
+	return k, nil
+}
+func (s *memAPIKeyStore) OfUser(ctx context.Context, userID int) ([]authEnt.APIKey, error) {
+	keys := []authEnt.APIKey{}
+	for _, k := range s.keys {
+		if k.UserID == userID {
+			keys = append(keys, k)
+		}
+	}
+	return keys, nil
+}
+func (s *memAPIKeyStore) Delete(ctx context.Context, userID int, id int) error {
+	for hash, k := range s.keys {
+		if k.ID == id && k.UserID == userID {
+			delete(s.keys, hash)
+			return nil
+		}
+	}
+	return errlib.NotFoundErr{Where: "api_keys", What: "api key"}
+}
+
+func createAPIKey(t *testing.T, a *authMiddle, userID int, req entities.NewAPIKey) entities.CreatedAPIKey {
+	key, err := a.CreateAPIKey(echo.New().NewContext(httptest.NewRequest(http.MethodPost, "/", nil), httptest.NewRecorder()), userID, req)
+	require.NoError(t, err)
+	return key
+}
+
+// apiKeyRequest проходит через Middleware и возвращает принципала
+func apiKeyRequest(a *authMiddle, method string, header string, value string) (Principal, error) {
+	req := httptest.NewRequest(method, "/api/drink", nil)
+	req.Header.Set(header, value)
+	var got Principal
+	err := a.Middleware(func(c echo.Context) error {
+		got, _ = PrincipalFrom(c)
+		return nil
+	})(echo.New().NewContext(req, httptest.NewRecorder()))
+	return got, err
+}
+
+func Test_authMiddle_APIKey(t *testing.T) {
+	a := newTestAuth("testkey")
+	key := createAPIKey(t, a, 7, entities.NewAPIKey{Name: "seeder", Scopes: []string{ScopeWrite, ScopeRead, ScopeWrite}})
+	assert.True(t, strings.HasPrefix(key.Key, APIKeyPrefix))
+	assert.Equal(t, key.Key[:apiKeyShownPrefix], key.Prefix)
+	assert.Equal(t, []string{ScopeRead, ScopeWrite}, key.Scopes)
+
+	p, err := apiKeyRequest(a, http.MethodPost, apiKeyHeader, key.Key)
+	require.NoError(t, err)
+	// роль берётся у владельца ключа
+	assert.Equal(t, Principal{UserID: 7, Role: RoleAdmin, APIKeyID: key.ID, Scopes: key.Scopes}, p)
+	p, err = apiKeyRequest(a, http.MethodGet, echo.HeaderAuthorization, "Bearer "+key.Key)
+	require.NoError(t, err)
+	assert.Equal(t, 7, p.UserID)
+
+	_, err = apiKeyRequest(a, http.MethodGet, apiKeyHeader, APIKeyPrefix+"unknown")
+	assert.ErrorIs(t, err, ErrAPIKeyUnknown)
+	assert.True(t, errlib.CheckErrUnauthorized(err))
+
+	keys, err := a.APIKeys(echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/", nil), httptest.NewRecorder()), 7)
+	require.NoError(t, err)
+	assert.Equal(t, []entities.APIKey{key.APIKey}, keys)
+
+	c := echo.New().NewContext(httptest.NewRequest(http.MethodDelete, "/", nil), httptest.NewRecorder())
+	assert.True(t, errlib.CheckErrNotFound(a.RevokeAPIKey(c, 8, key.ID)))
+	require.NoError(t, a.RevokeAPIKey(c, 7, key.ID))
+	_, err = apiKeyRequest(a, http.MethodGet, apiKeyHeader, key.Key)
+	assert.ErrorIs(t, err, ErrAPIKeyUnknown)
+}
+
+func Test_authMiddle_APIKeyScopes(t *testing.T) {
+	a := newTestAuth("testkey")
+	readOnly := createAPIKey(t, a, 7, entities.NewAPIKey{Name: "reader"})
+	assert.Equal(t, []string{ScopeRead}, readOnly.Scopes)
+
+	_, err := apiKeyRequest(a, http.MethodGet, apiKeyHeader, readOnly.Key)
+	assert.NoError(t, err)
+	_, err = apiKeyRequest(a, http.MethodPost, apiKeyHeader, readOnly.Key)
+	assert.True(t, errlib.CheckErrForbidden(err))
+}
+
+func Test_authMiddle_APIKeyExpired(t *testing.T) {
+	a := newTestAuth("testkey")
+	expiresAt := testNow.Add(time.Hour)
+	key := createAPIKey(t, a, 7, entities.NewAPIKey{Name: "temporary", ExpiresAt: &expiresAt})
+
+	_, err := apiKeyRequest(a, http.MethodGet, apiKeyHeader, key.Key)
+	assert.NoError(t, err)
+	a.now = func() time.Time { return expiresAt }
+	_, err = apiKeyRequest(a, http.MethodGet, apiKeyHeader, key.Key)
+	assert.ErrorIs(t, err, ErrAPIKeyExpired)
+}
+
+func Test_authMiddle_CreateAPIKey_invalid(t *testing.T) {
+	past := testNow.Add(-time.Minute)
+	for name, req := range map[string]entities.NewAPIKey{
+		"no_name":       {},
+		"long_name":     {Name: strings.Repeat("a", 65)},
+		"unknown_scope": {Name: "key", Scopes: []string{"admin"}},
+		"expired":       {Name: "key", ExpiresAt: &past},
+	} {
+		t.Run(name, func(t *testing.T) {
+			a := newTestAuth("testkey")
+			_, err := a.CreateAPIKey(echo.New().NewContext(httptest.NewRequest(http.MethodPost, "/", nil), httptest.NewRecorder()), 7, req)
+			assert.True(t, errlib.CheckErrInvalidInput(err))
+		})
+	}
+}
+
+func TestDenyAPIKeys(t *testing.T) {
+	handler := DenyAPIKeys(func(c echo.Context) error { return nil })
+	c := echo.New().NewContext(httptest.NewRequest(http.MethodPost, "/", nil), httptest.NewRecorder())
+	SetPrincipal(c, Principal{UserID: 7})
+	assert.NoError(t, handler(c))
+	SetPrincipal(c, Principal{UserID: 7, APIKeyID: 1, Scopes: []string{ScopeWrite}})
+	assert.True(t, errlib.CheckErrForbidden(handler(c)))
+}
+
+func TestSQLAPIKeyStore(t *testing.T) {
+	ctx := context.Background()
+	db, err := pgxpool.New(ctx, "host=localhost user=username password=password dbname=dbname sslmode=disable")
+	if err != nil {
+		t.Fatalf("Failed to connect to the database: %v", err)
+	}
+	defer db.Close()
+	_, err = db.Exec(ctx, QUERY_CREATE_TABLES)
+	defer db.Exec(ctx, QUERY_DROP_TABLES)
+	if err != nil {
+		t.Fatalf("Failed to create tables: %v", err)
+	}
+	s := NewSQLAPIKeyStore(db)
+	created, err := s.Create(ctx, authEnt.APIKey{
+		Hash: hashToken("key"), Prefix: "bpg_key", UserID: 1, Name: "seeder", Scopes: []string{ScopeRead},
+	})
+	require.NoError(t, err)
+	_, err = s.Create(ctx, authEnt.APIKey{
+		Hash: hashToken("other"), Prefix: "bpg_oth", UserID: 1, Name: "seeder", Scopes: []string{ScopeRead},
+	})
+	assert.True(t, errlib.CheckErrConflict(err))
+
+	got, err := s.ByHash(ctx, hashToken("key"))
+	require.NoError(t, err)
+	assert.Equal(t, created.ID, got.ID)
+	assert.Equal(t, []string{ScopeRead}, got.Scopes)
+	keys, err := s.OfUser(ctx, 1)
+	require.NoError(t, err)
+	assert.Len(t, keys, 1)
+
+	assert.True(t, errlib.CheckErrNotFound(s.Delete(ctx, 2, created.ID)))
+	require.NoError(t, s.Delete(ctx, 1, created.ID))
+	_, err = s.ByHash(ctx, hashToken("key"))
+	assert.True(t, errlib.CheckErrNotFound(err))
+}
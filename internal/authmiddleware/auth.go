@@ -1,12 +1,20 @@
 package authmiddleware
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
-	"fmt"
 	"net/http"
-	"os"
+	"strconv"
+	"strings"
 	"time"
 
+	authEnt "github.com/SapolovichSV/backprogeng/internal/authmiddleware/entities"
+	"github.com/SapolovichSV/backprogeng/internal/config"
 	"github.com/SapolovichSV/backprogeng/internal/errlib"
 	"github.com/SapolovichSV/backprogeng/internal/user/entities"
 	"github.com/golang-jwt/jwt/v5"
@@ -18,118 +26,417 @@ import (
 // TODO: refactor to use only one copy of this code
 type authService interface {
 	Auth(c echo.Context) (entities.User, error)
-	Login(c echo.Context) (entities.User, error)
-	Register(c echo.Context, user entities.User) error
+	Register(c echo.Context, user entities.User) (entities.Tokens, error)
+	Refresh(c echo.Context) (entities.Session, error)
+	Logout(c echo.Context) error
+	LogoutAll(c echo.Context) error
+	RevokeAccess(c echo.Context, userID int) error
+	CreateAPIKey(c echo.Context, userID int, req entities.NewAPIKey) (entities.CreatedAPIKey, error)
+	APIKeys(c echo.Context, userID int) ([]entities.APIKey, error)
+	RevokeAPIKey(c echo.Context, userID int, keyID int) error
 }
-type secretKey struct {
-	key string
+
+// refreshStore is implemented by SQLRefreshStore
+type refreshStore interface {
+	Create(ctx context.Context, t authEnt.RefreshToken) error
+	Rotate(ctx context.Context, hash string, next authEnt.RefreshToken, now time.Time) (authEnt.RefreshToken, error)
+	RevokeFamily(ctx context.Context, hash string) error
+	RevokeUser(ctx context.Context, userID int) error
 }
 
-func parseSecretKey() secretKey {
-	key := os.Getenv("SECRET")
-	if key == "" {
-		key = "simple"
-	}
-	return secretKey{
-		key: key,
-	}
+// roleSource is implemented by user model, role is read on every refresh
+// so the change of it comes into tokens without new login
+type roleSource interface {
+	RoleByUserID(ctx context.Context, userID int) (string, error)
+}
+
+var (
+	ErrTokenRevoked = errors.New("token is revoked")
+	ErrNoToken      = errors.New("no access token or api key in the request")
+)
+
+// Roles put into tokens
+const (
+	RoleUser  = entities.RoleUser
+	RoleAdmin = entities.RoleAdmin
+	// DefaultRole is the role of users without role, e.g. in tokens of the old format
+	DefaultRole = RoleUser
+)
+
+const (
+	accessCookie  = "token"
+	refreshCookie = "refresh_token"
+	// refresh токен нужен только ручкам /api/auth, остальным его не отправляем
+	refreshCookiePath = "/api/auth"
+)
+
+// iat и exp пишем с точностью до микросекунд, как revoked_before в бд,
+// иначе токен, выпущенный в ту же секунду до выхода со всех устройств, остался бы рабочим
+func init() {
+	jwt.TimePrecision = time.Microsecond
 }
 
-type jwtCustomClaims struct {
+// jwtClaims are standard sub, iat, exp, jti, iss and aud claims plus role of the user
+type jwtClaims struct {
+	Role string `json:"role"`
+	jwt.RegisteredClaims
+}
+
+// legacyClaims are claims of tokens issued before standard claims,
+// they are accepted only until the grace period ends
+type legacyClaims struct {
 	Id       int    `json:"id"`
 	Username string `json:"username"`
 	Password string `json:"password"`
 	jwt.RegisteredClaims
 }
 type authMiddle struct {
-	secretKey secretKey
+	keys        *KeySet
+	cfg         config.Auth
+	refresh     refreshStore
+	revocations RevocationStore
+	roles       roleSource
+	apiKeys     apiKeyStore
+	now         func() time.Time
 }
 
-func New() *authMiddle {
+func New(cfg config.Auth, keys *KeySet, refresh refreshStore, revocations RevocationStore, roles roleSource, apiKeys apiKeyStore) *authMiddle {
 	return &authMiddle{
-		secretKey: parseSecretKey(),
+		keys:        keys,
+		cfg:         cfg,
+		refresh:     refresh,
+		revocations: revocations,
+		roles:       roles,
+		apiKeys:     apiKeys,
+		now:         time.Now,
 	}
 }
-func (a *authMiddle) Register(c echo.Context, user entities.User) error {
 
-	claims := jwtCustomClaims{
-		user.ID,
-		user.Username,
-		user.Password,
-		jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour * 2)),
-		},
+// Register starts a new session of the user: sets cookies with an access token
+// and a refresh token of a new family and returns the tokens
+func (a *authMiddle) Register(c echo.Context, user entities.User) (entities.Tokens, error) {
+	familyID, err := newTokenID()
+	if err != nil {
+		return entities.Tokens{}, errlib.WrapErr(err, "failing to generate token family id")
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	t, err := token.SignedString([]byte(a.secretKey.key))
+	refresh, stored, err := a.newRefreshToken()
 	if err != nil {
-		return errlib.WrapErr(err, "failing to sign token")
+		return entities.Tokens{}, err
 	}
-	cookie := http.Cookie{
-		Name:  "token",
-		Value: t,
+	stored.UserID = user.ID
+	stored.FamilyID = familyID
+	if err := a.refresh.Create(c.Request().Context(), stored); err != nil {
+		return entities.Tokens{}, errlib.WrapErr(err, "failing to store refresh token")
 	}
-	c.SetCookie(&cookie)
-	return nil
+	role := user.Role
+	if role == "" {
+		role = DefaultRole
+	}
+	return a.setTokens(c, user.ID, role, refresh)
 }
-func (a *authMiddle) Auth(c echo.Context) (entities.User, error) {
-	cookie, err := c.Cookie("token")
+
+// tokenFromRequest returns the access token from the first source of
+// cfg.TokenSources which has it: Authorization: Bearer header or cookie
+func (a *authMiddle) tokenFromRequest(c echo.Context) string {
+	for _, source := range a.cfg.TokenSources {
+		switch source {
+		case "header":
+			scheme, token, ok := strings.Cut(c.Request().Header.Get(echo.HeaderAuthorization), " ")
+			if ok && strings.EqualFold(scheme, "Bearer") && token != "" {
+				return strings.TrimSpace(token)
+			}
+		case "cookie":
+			if cookie, err := c.Cookie(accessCookie); err == nil && cookie.Value != "" {
+				return cookie.Value
+			}
+		}
+	}
+	return ""
+}
+
+// Refresh exchanges the refresh token from cookie or body for a new pair of tokens,
+// the old refresh token can't be used anymore
+func (a *authMiddle) Refresh(c echo.Context) (entities.Session, error) {
+	token := refreshFromRequest(c)
+	if token == "" {
+		return entities.Session{}, errlib.UnauthorizedErr{Err: ErrRefreshUnknown}
+	}
+	refresh, next, err := a.newRefreshToken()
+	if err != nil {
+		return entities.Session{}, err
+	}
+	old, err := a.refresh.Rotate(c.Request().Context(), hashToken(token), next, a.now())
+	if errors.Is(err, ErrRefreshUnknown) || errors.Is(err, ErrRefreshExpired) || errors.Is(err, ErrRefreshReused) {
+		return entities.Session{}, errlib.UnauthorizedErr{Err: err}
+	} else if err != nil {
+		return entities.Session{}, errlib.WrapErr(err, "failing to rotate refresh token")
+	}
+	role, err := a.roles.RoleByUserID(c.Request().Context(), old.UserID)
 	if err != nil {
-		return entities.User{}, errlib.WrapErr(err, "failing to get cookie with user Info")
+		return entities.Session{}, errlib.WrapErr(err, "failing to get role of user")
 	}
-	claims, err := getClaims(cookie, a.secretKey.key)
+	tokens, err := a.setTokens(c, old.UserID, role, refresh)
 	if err != nil {
-		return entities.User{}, errlib.WrapErr(err, "failing to get claims from token")
+		return entities.Session{}, err
 	}
-	user := entities.User{
-		ID:       claims.Id,
-		Username: claims.Username,
-		Password: claims.Password,
+	return entities.Session{User: entities.User{ID: old.UserID, Role: role}, Tokens: tokens}, nil
+}
+
+// refreshFromRequest берёт refresh токен из куки,
+// а клиентам без кук разрешает прислать его в теле
+func refreshFromRequest(c echo.Context) string {
+	if cookie, err := c.Cookie(refreshCookie); err == nil && cookie.Value != "" {
+		return cookie.Value
+	}
+	var body entities.RefreshRequest
+	if err := json.NewDecoder(c.Request().Body).Decode(&body); err != nil {
+		return ""
+	}
+	return body.RefreshToken
+}
+
+// Logout revokes the access token and the refresh token family of the session
+// and clears cookies
+func (a *authMiddle) Logout(c echo.Context) error {
+	ctx := c.Request().Context()
+	if token := a.tokenFromRequest(c); token != "" {
+		// у токенов старого формата нет jti, их отозвать можно только через LogoutAll
+		if claims, err := a.getClaims(token); err == nil && claims.ID != "" {
+			if err := a.revocations.Revoke(ctx, claims.ID, claims.ExpiresAt.Time); err != nil {
+				return errlib.WrapErr(err, "failing to revoke access token")
+			}
+		}
+	}
+	if token := refreshFromRequest(c); token != "" {
+		if err := a.refresh.RevokeFamily(ctx, hashToken(token)); err != nil {
+			return errlib.WrapErr(err, "failing to revoke refresh token")
+		}
+	}
+	a.clearCookies(c)
+	return nil
+}
+
+// LogoutAll revokes all access and refresh tokens of the authenticated user
+// issued till now, so the user is logged out on all devices
+func (a *authMiddle) LogoutAll(c echo.Context) error {
+	p, err := a.authenticate(c)
+	if err != nil {
+		return errlib.UnauthorizedErr{Err: err}
+	}
+	if err := a.RevokeAccess(c, p.UserID); err != nil {
+		return err
+	}
+	if err := a.refresh.RevokeUser(c.Request().Context(), p.UserID); err != nil {
+		return errlib.WrapErr(err, "failing to revoke refresh tokens")
+	}
+	a.clearCookies(c)
+	return nil
+}
+
+// RevokeAccess revokes access tokens of the user issued till now,
+// refresh tokens stay valid and the next refresh reads the current role of the user
+func (a *authMiddle) RevokeAccess(c echo.Context, userID int) error {
+	if err := a.revocations.RevokeUser(c.Request().Context(), userID, a.now()); err != nil {
+		return errlib.WrapErr(err, "failing to revoke access tokens")
 	}
-	fmt.Println(user)
-	return user, nil
+	return nil
 }
-func (a *authMiddle) Login(c echo.Context) (entities.User, error) {
-	cookie, err := c.Cookie("token")
+func (a *authMiddle) clearCookies(c echo.Context) {
+	access := a.accessCookie("")
+	access.MaxAge = -1
+	c.SetCookie(access)
+	refresh := a.refreshCookie("")
+	refresh.MaxAge = -1
+	c.SetCookie(refresh)
+}
+
+// setTokens подписывает access токен и ставит куки с ним и с refresh токеном
+func (a *authMiddle) setTokens(c echo.Context, userID int, role string, refresh string) (entities.Tokens, error) {
+	access, err := a.signAccessToken(userID, role)
 	if err != nil {
-		return entities.User{}, errlib.WrapErr(err, "failing to get cookie with user Info")
+		return entities.Tokens{}, err
+	}
+	c.SetCookie(a.accessCookie(access))
+	refreshCookie := a.refreshCookie(refresh)
+	refreshCookie.Expires = a.now().Add(a.cfg.RefreshTTL)
+	c.SetCookie(refreshCookie)
+	return entities.Tokens{
+		AccessToken:  access,
+		RefreshToken: refresh,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(a.cfg.AccessTTL.Seconds()),
+	}, nil
+}
+
+// accessCookie returns the cookie of the access token with attributes from config
+func (a *authMiddle) accessCookie(value string) *http.Cookie {
+	return &http.Cookie{
+		Name:     accessCookie,
+		Value:    value,
+		Path:     a.cfg.Cookie.Path,
+		Secure:   a.cfg.Cookie.Secure,
+		HttpOnly: a.cfg.Cookie.HttpOnly,
+		SameSite: a.cfg.Cookie.SameSite,
+	}
+}
+
+// refreshCookie returns the cookie of the refresh token, it is sent only
+// to /api/auth and is never readable from scripts
+func (a *authMiddle) refreshCookie(value string) *http.Cookie {
+	return &http.Cookie{
+		Name:     refreshCookie,
+		Value:    value,
+		Path:     refreshCookiePath,
+		Secure:   a.cfg.Cookie.Secure,
+		HttpOnly: true,
+		SameSite: a.cfg.Cookie.SameSite,
 	}
-	claims, err := getClaims(cookie, a.secretKey.key)
+}
+func (a *authMiddle) signAccessToken(userID int, role string) (string, error) {
+	jti, err := newTokenID()
 	if err != nil {
-		return entities.User{}, errlib.WrapErr(err, "failing to get claims from token")
+		return "", errlib.WrapErr(err, "failing to generate token id")
+	}
+	now := a.now()
+	claims := jwtClaims{
+		Role: role,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   strconv.Itoa(userID),
+			Issuer:    a.cfg.Issuer,
+			Audience:  jwt.ClaimStrings{a.cfg.Audience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(a.cfg.AccessTTL)),
+			ID:        jti,
+		},
 	}
-	claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(time.Hour * 2))
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	t, err := token.SignedString([]byte(a.secretKey.key))
+	t, err := a.keys.Sign(claims)
 	if err != nil {
-		return entities.User{}, errlib.WrapErr(err, "failing to sign token")
+		return "", errlib.WrapErr(err, "failing to sign token")
 	}
-	cookie = &http.Cookie{
-		Name:  "token",
-		Value: t,
+	return t, nil
+}
+
+// newRefreshToken returns a new random refresh token and its stored form
+// without user and family
+func (a *authMiddle) newRefreshToken() (string, authEnt.RefreshToken, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", authEnt.RefreshToken{}, errlib.WrapErr(err, "failing to generate refresh token")
 	}
-	c.SetCookie(cookie)
-	user := entities.User{
-		ID:       claims.Id,
-		Username: claims.Username,
-		Password: claims.Password,
+	token := base64.RawURLEncoding.EncodeToString(b)
+	return token, authEnt.RefreshToken{
+		Hash:      hashToken(token),
+		ExpiresAt: a.now().Add(a.cfg.RefreshTTL),
+	}, nil
+}
+func (a *authMiddle) Auth(c echo.Context) (entities.User, error) {
+	p, err := a.authenticate(c)
+	if err != nil {
+		return entities.User{}, err
 	}
-	return user, nil
+	return entities.User{ID: p.UserID}, nil
 }
 
-func getClaims(cookie *http.Cookie, key string) (jwtCustomClaims, error) {
-	if cookie == nil {
-		return jwtCustomClaims{}, errlib.WrapErr(errors.New("no token cookie"), "no token cookie")
+// authenticate проверяет токен из запроса и что он не отозван,
+// API ключи принимаются из X-API-Key или вместо Bearer токена
+func (a *authMiddle) authenticate(c echo.Context) (Principal, error) {
+	if key := c.Request().Header.Get(apiKeyHeader); key != "" {
+		return a.authenticateAPIKey(c.Request().Context(), key)
+	}
+	token := a.tokenFromRequest(c)
+	if token == "" {
+		return Principal{}, ErrNoToken
 	}
-	token, err := jwt.ParseWithClaims(cookie.Value, &jwtCustomClaims{}, func(token *jwt.Token) (interface{}, error) {
-		return []byte(key), nil
-	})
+	if strings.HasPrefix(token, APIKeyPrefix) {
+		return a.authenticateAPIKey(c.Request().Context(), token)
+	}
+	claims, err := a.getClaims(token)
 	if err != nil {
-		return jwtCustomClaims{}, errlib.WrapErr(err, "failing to parse token")
+		return Principal{}, errlib.WrapErr(err, "failing to get claims from token")
 	}
-	claims, ok := token.Claims.(*jwtCustomClaims)
-	if !ok {
-		return jwtCustomClaims{}, errlib.WrapErr(errors.New("failing to get claims from token"), "token broken")
+	id, err := strconv.Atoi(claims.Subject)
+	if err != nil {
+		return Principal{}, errlib.WrapErr(err, "token subject is not user id")
 	}
-	return *claims, nil
+	var issuedAt time.Time
+	if claims.IssuedAt != nil {
+		issuedAt = claims.IssuedAt.Time
+	}
+	revoked, err := a.revocations.IsRevoked(c.Request().Context(), claims.ID, id, issuedAt)
+	if err != nil {
+		return Principal{}, errlib.WrapErr(err, "failing to check token revocation")
+	} else if revoked {
+		return Principal{}, ErrTokenRevoked
+	}
+	role := claims.Role
+	if role == "" {
+		role = DefaultRole
+	}
+	return Principal{UserID: id, Role: role}, nil
+}
+
+// getClaims parses and validates the token, tokens of the old format
+// are converted to jwtClaims while the grace period lasts
+func (a *authMiddle) getClaims(token string) (jwtClaims, error) {
+	if token == "" {
+		return jwtClaims{}, ErrNoToken
+	}
+	claims := jwtClaims{}
+	_, err := jwt.ParseWithClaims(token, &claims, a.keys.keyFunc,
+		jwt.WithIssuer(a.cfg.Issuer),
+		jwt.WithAudience(a.cfg.Audience),
+		jwt.WithExpirationRequired(),
+		jwt.WithTimeFunc(a.now),
+	)
+	if err == nil {
+		return claims, nil
+	}
+	if legacy, ok := a.legacyClaims(token); ok {
+		return legacy, nil
+	}
+	return jwtClaims{}, errlib.WrapErr(err, "failing to parse token")
+}
+
+// legacyClaims принимает токен старого формата, пока не кончился льготный период
+func (a *authMiddle) legacyClaims(token string) (jwtClaims, bool) {
+	if !a.now().Before(a.cfg.LegacyTokensUntil) {
+		return jwtClaims{}, false
+	}
+	legacy := legacyClaims{}
+	_, err := jwt.ParseWithClaims(token, &legacy, a.keys.legacyKeyFunc,
+		jwt.WithExpirationRequired(),
+		jwt.WithTimeFunc(a.now),
+	)
+	if err != nil || legacy.Id == 0 || legacy.Issuer != "" {
+		return jwtClaims{}, false
+	}
+	return jwtClaims{
+		Role: DefaultRole,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   strconv.Itoa(legacy.Id),
+			ExpiresAt: legacy.ExpiresAt,
+		},
+	}, true
+}
+
+// JWKS serves public keys which verify access tokens in JSON Web Key Set format
+// at /.well-known/jwks.json, outside of /api so it isn't in swagger
+func (a *authMiddle) JWKS(c echo.Context) error {
+	c.Response().Header().Set(echo.HeaderCacheControl, "public, max-age=300")
+	return c.JSON(http.StatusOK, a.keys.JWKS())
+}
+
+// newTokenID генерирует случайный jti
+func newTokenID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// hashToken возвращает sha256 токена, в бд хранится только он
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
 }
@@ -1,18 +1,26 @@
 package authmiddleware
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 
+	authEnt "github.com/SapolovichSV/backprogeng/internal/authmiddleware/entities"
+	"github.com/SapolovichSV/backprogeng/internal/config"
 	"github.com/SapolovichSV/backprogeng/internal/user/entities"
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func Test_authMiddle_Register(t *testing.T) {
 	type fields struct {
-		secretKey secretKey
+		secret string
 	}
 	type args struct {
 		c    echo.Context
@@ -26,7 +34,7 @@ func Test_authMiddle_Register(t *testing.T) {
 	}{
 		{
 			name:   "Valid registration",
-			fields: fields{secretKey: secretKey{key: "testkey"}},
+			fields: fields{secret: "testkey"},
 			args: func() args {
 				e := echo.New()
 				req := httptest.NewRequest(http.MethodPost, "/", nil)
@@ -39,10 +47,8 @@ func Test_authMiddle_Register(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			a := &authMiddle{
-				secretKey: tt.fields.secretKey,
-			}
-			if err := a.Register(tt.args.c, tt.args.user); (err != nil) != tt.wantErr {
+			a := newTestAuth(tt.fields.secret)
+			if _, err := a.Register(tt.args.c, tt.args.user); (err != nil) != tt.wantErr {
 				t.Errorf("authMiddle.Register() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
@@ -51,7 +57,7 @@ func Test_authMiddle_Register(t *testing.T) {
 
 func Test_authMiddle_Auth(t *testing.T) {
 	type fields struct {
-		secretKey secretKey
+		secret string
 	}
 	type args struct {
 		c echo.Context
@@ -65,7 +71,7 @@ func Test_authMiddle_Auth(t *testing.T) {
 	}{
 		{
 			name:   "No token cookie",
-			fields: fields{secretKey: secretKey{key: "testkey"}},
+			fields: fields{secret: "testkey"},
 			args: func() args {
 				e := echo.New()
 				req := httptest.NewRequest(http.MethodGet, "/", nil)
@@ -77,7 +83,7 @@ func Test_authMiddle_Auth(t *testing.T) {
 		},
 		{
 			name:   "Invalid token cookie",
-			fields: fields{secretKey: secretKey{key: "testkey"}},
+			fields: fields{secret: "testkey"},
 			args: func() args {
 				e := echo.New()
 				req := httptest.NewRequest(http.MethodGet, "/", nil)
@@ -92,9 +98,7 @@ func Test_authMiddle_Auth(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			a := &authMiddle{
-				secretKey: tt.fields.secretKey,
-			}
+			a := newTestAuth(tt.fields.secret)
 			got, err := a.Auth(tt.args.c)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("authMiddle.Auth() error = %v, wantErr %v", err, tt.wantErr)
@@ -107,103 +111,37 @@ func Test_authMiddle_Auth(t *testing.T) {
 	}
 }
 
-func Test_authMiddle_Login(t *testing.T) {
-	type fields struct {
-		secretKey secretKey
-	}
-	type args struct {
-		c echo.Context
-	}
-	tests := []struct {
-		name    string
-		fields  fields
-		args    args
-		want    entities.User
-		wantErr bool
-	}{
-		{
-			name:   "No cookie",
-			fields: fields{secretKey: secretKey{key: "testkey"}},
-			args: func() args {
-				e := echo.New()
-				req := httptest.NewRequest(http.MethodGet, "/", nil)
-				rec := httptest.NewRecorder()
-				return args{c: e.NewContext(req, rec)}
-			}(),
-			want:    entities.User{},
-			wantErr: true,
-		},
-		{
-			name:   "Invalid token cookie",
-			fields: fields{secretKey: secretKey{key: "testkey"}},
-			args: func() args {
-				e := echo.New()
-				req := httptest.NewRequest(http.MethodGet, "/", nil)
-				cookie := &http.Cookie{Name: "token", Value: "broken.token"}
-				req.AddCookie(cookie)
-				rec := httptest.NewRecorder()
-				return args{c: e.NewContext(req, rec)}
-			}(),
-			want:    entities.User{},
-			wantErr: true,
-		},
-	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			a := &authMiddle{
-				secretKey: tt.fields.secretKey,
-			}
-			got, err := a.Login(tt.args.c)
-			if (err != nil) != tt.wantErr {
-				t.Errorf("authMiddle.Login() error = %v, wantErr %v", err, tt.wantErr)
-				return
-			}
-			if !reflect.DeepEqual(got, tt.want) {
-				t.Errorf("authMiddle.Login() = %v, want %v", got, tt.want)
-			}
-		})
-	}
-}
-
 func Test_getClaims(t *testing.T) {
 	type args struct {
-		cookie *http.Cookie
-		key    string
+		token string
+		key   string
 	}
 	tests := []struct {
 		name    string
 		args    args
-		want    jwtCustomClaims
+		want    jwtClaims
 		wantErr bool
 	}{
 		{
-			name: "No cookie",
-			args: args{
-				cookie: nil,
-				key:    "testkey",
-			},
-			wantErr: true,
-		},
-		{
-			name: "Empty cookie",
+			name: "Empty token",
 			args: args{
-				cookie: &http.Cookie{Name: "token", Value: ""},
-				key:    "testkey",
+				token: "",
+				key:   "testkey",
 			},
 			wantErr: true,
 		},
 		{
 			name: "Broken token",
 			args: args{
-				cookie: &http.Cookie{Name: "token", Value: "broken.token"},
-				key:    "testkey",
+				token: "broken.token",
+				key:   "testkey",
 			},
 			wantErr: true,
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := getClaims(tt.args.cookie, tt.args.key)
+			got, err := newTestAuth(tt.args.key).getClaims(tt.args.token)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("getClaims() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -214,3 +152,373 @@ func Test_getClaims(t *testing.T) {
 		})
 	}
 }
+
+var testNow = time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+func newTestAuth(key string) *authMiddle {
+	return &authMiddle{
+		keys: NewHMACKeySet(key),
+		cfg: config.Auth{
+			Issuer:            "backprogeng",
+			Audience:          "backprogeng-api",
+			LegacyTokensUntil: testNow.Add(time.Hour),
+			AccessTTL:         15 * time.Minute,
+			RefreshTTL:        24 * time.Hour,
+			TokenSources:      []string{"header", "cookie"},
+			Cookie: config.Cookie{
+				Path:     "/",
+				Secure:   true,
+				HttpOnly: true,
+				SameSite: http.SameSiteStrictMode,
+			},
+		},
+		refresh:     newMemRefreshStore(),
+		revocations: NewMemoryRevocationStore(),
+		roles:       testRoles{7: RoleAdmin},
+		apiKeys:     newMemAPIKeyStore(),
+		now:         func() time.Time { return testNow },
+	}
+}
+
+func Test_authMiddle_RegisterThenAuth(t *testing.T) {
+	a := newTestAuth("testkey")
+	e := echo.New()
+	rec := httptest.NewRecorder()
+	_, err := a.Register(e.NewContext(httptest.NewRequest(http.MethodPost, "/", nil), rec), entities.User{ID: 7, Username: "TestUser", Password: "123"})
+	require.NoError(t, err)
+	cookie := cookieByName(rec, accessCookie)
+
+	claims, err := a.getClaims(cookie.Value)
+	require.NoError(t, err)
+	assert.Equal(t, "7", claims.Subject)
+	assert.Equal(t, DefaultRole, claims.Role)
+	assert.Equal(t, "backprogeng", claims.Issuer)
+	assert.NotEmpty(t, claims.ID)
+	assert.Equal(t, testNow.Add(a.cfg.AccessTTL).Unix(), claims.ExpiresAt.Unix())
+	// пароль и имя больше не попадают в токен
+	assert.NotContains(t, cookie.Value, "password")
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(cookie)
+	user, err := a.Auth(e.NewContext(req, httptest.NewRecorder()))
+	require.NoError(t, err)
+	assert.Equal(t, entities.User{ID: 7}, user)
+
+	other := newTestAuth("testkey")
+	other.cfg.Audience = "another-service"
+	_, err = other.getClaims(cookie.Value)
+	assert.Error(t, err)
+
+	other = newTestAuth("testkey")
+	other.cfg.Issuer = "another-issuer"
+	_, err = other.getClaims(cookie.Value)
+	assert.Error(t, err)
+}
+
+func Test_authMiddle_legacyToken(t *testing.T) {
+	legacy := jwt.NewWithClaims(jwt.SigningMethodHS256, legacyClaims{
+		Id:       7,
+		Username: "TestUser",
+		Password: "123",
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(testNow.Add(time.Hour)),
+		},
+	})
+	token, err := legacy.SignedString([]byte("testkey"))
+	require.NoError(t, err)
+	cookie := &http.Cookie{Name: "token", Value: token}
+
+	a := newTestAuth("testkey")
+	claims, err := a.getClaims(cookie.Value)
+	require.NoError(t, err)
+	assert.Equal(t, "7", claims.Subject)
+
+	a.cfg.LegacyTokensUntil = testNow
+	_, err = a.getClaims(cookie.Value)
+	assert.Error(t, err)
+}
+
+// testRoles отдаёт роль пользователя по id, по умолчанию обычный пользователь
+type testRoles map[int]string
+
+func (r testRoles) RoleByUserID(ctx context.Context, userID int) (string, error) {
+	if role, ok := r[userID]; ok {
+		return role, nil
+	}
+	return RoleUser, nil
+}
+
+// memRefreshStore повторяет логику SQLRefreshStore в памяти
+type memRefreshStore struct {
+	tokens map[string]*authEnt.RefreshToken
+}
+
+func newMemRefreshStore() *memRefreshStore {
+	return &memRefreshStore{tokens: map[string]*authEnt.RefreshToken{}}
+}
+func (s *memRefreshStore) Create(ctx context.Context, t authEnt.RefreshToken) error {
+	s.tokens[t.Hash] = &t
+	return nil
+}
+func (s *memRefreshStore) Rotate(ctx context.Context, hash string, next authEnt.RefreshToken, now time.Time) (authEnt.RefreshToken, error) {
+	old, ok := s.tokens[hash]
+	if !ok {
+		return authEnt.RefreshToken{}, ErrRefreshUnknown
+	}
+	if old.UsedAt != nil || old.RevokedAt != nil {
+		s.revokeFamily(old.FamilyID, now)
+		return authEnt.RefreshToken{}, ErrRefreshReused
+	}
+	if !now.Before(old.ExpiresAt) {
+		return authEnt.RefreshToken{}, ErrRefreshExpired
+	}
+	old.UsedAt = &now
+	next.UserID = old.UserID
+	next.FamilyID = old.FamilyID
+	return *old, s.Create(ctx, next)
+}
+func (s *memRefreshStore) RevokeFamily(ctx context.Context, hash string) error {
+	if t, ok := s.tokens[hash]; ok {
+		s.revokeFamily(t.FamilyID, testNow)
+	}
+	return nil
+}
+func (s *memRefreshStore) RevokeUser(ctx context.Context, userID int) error {
+	for _, t := range s.tokens {
+		if t.UserID == userID && t.RevokedAt == nil {
+			t.RevokedAt = &testNow
+		}
+	}
+	return nil
+}
+func (s *memRefreshStore) revokeFamily(familyID string, now time.Time) {
+	for _, t := range s.tokens {
+		if t.FamilyID == familyID && t.RevokedAt == nil {
+			t.RevokedAt = &now
+		}
+	}
+}
+
+func cookieByName(rec *httptest.ResponseRecorder, name string) *http.Cookie {
+	for _, cookie := range rec.Result().Cookies() {
+		if cookie.Name == name {
+			return cookie
+		}
+	}
+	return nil
+}
+func refreshRequest(a *authMiddle, refresh *http.Cookie) (entities.User, *httptest.ResponseRecorder, error) {
+	req := httptest.NewRequest(http.MethodPost, "/api/auth/refresh", nil)
+	if refresh != nil {
+		req.AddCookie(refresh)
+	}
+	rec := httptest.NewRecorder()
+	session, err := a.Refresh(echo.New().NewContext(req, rec))
+	return session.User, rec, err
+}
+
+func Test_authMiddle_RefreshRotation(t *testing.T) {
+	a := newTestAuth("testkey")
+	rec := httptest.NewRecorder()
+	_, err := a.Register(echo.New().NewContext(httptest.NewRequest(http.MethodPost, "/", nil), rec), entities.User{ID: 7})
+	require.NoError(t, err)
+	first := cookieByName(rec, refreshCookie)
+	require.NotNil(t, first)
+	assert.True(t, first.HttpOnly)
+	assert.Equal(t, refreshCookiePath, first.Path)
+
+	user, rec, err := refreshRequest(a, first)
+	require.NoError(t, err)
+	assert.Equal(t, entities.User{ID: 7, Role: RoleAdmin}, user)
+	second := cookieByName(rec, refreshCookie)
+	require.NotNil(t, second)
+	assert.NotEqual(t, first.Value, second.Value)
+	claims, err := a.getClaims(cookieByName(rec, accessCookie).Value)
+	require.NoError(t, err)
+	assert.Equal(t, "7", claims.Subject)
+	// роль перечитывается при обновлении
+	assert.Equal(t, RoleAdmin, claims.Role)
+
+	// повторное использование отзывает всю семью, в том числе новый токен
+	_, _, err = refreshRequest(a, first)
+	assert.ErrorIs(t, err, ErrRefreshReused)
+	_, _, err = refreshRequest(a, second)
+	assert.ErrorIs(t, err, ErrRefreshReused)
+
+	_, _, err = refreshRequest(a, nil)
+	assert.ErrorIs(t, err, ErrRefreshUnknown)
+	_, _, err = refreshRequest(a, &http.Cookie{Name: refreshCookie, Value: "unknown"})
+	assert.ErrorIs(t, err, ErrRefreshUnknown)
+}
+
+func Test_authMiddle_RefreshExpired(t *testing.T) {
+	a := newTestAuth("testkey")
+	rec := httptest.NewRecorder()
+	_, err := a.Register(echo.New().NewContext(httptest.NewRequest(http.MethodPost, "/", nil), rec), entities.User{ID: 7})
+	require.NoError(t, err)
+
+	a.now = func() time.Time { return testNow.Add(a.cfg.RefreshTTL) }
+	_, _, err = refreshRequest(a, cookieByName(rec, refreshCookie))
+	assert.ErrorIs(t, err, ErrRefreshExpired)
+}
+
+func Test_authMiddle_Logout(t *testing.T) {
+	a := newTestAuth("testkey")
+	rec := httptest.NewRecorder()
+	_, err := a.Register(echo.New().NewContext(httptest.NewRequest(http.MethodPost, "/", nil), rec), entities.User{ID: 7})
+	require.NoError(t, err)
+	refresh := cookieByName(rec, refreshCookie)
+	access := cookieByName(rec, accessCookie)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/auth/logout", nil)
+	req.AddCookie(refresh)
+	req.AddCookie(access)
+	rec = httptest.NewRecorder()
+	require.NoError(t, a.Logout(echo.New().NewContext(req, rec)))
+	assert.Equal(t, -1, cookieByName(rec, accessCookie).MaxAge)
+	assert.Equal(t, -1, cookieByName(rec, refreshCookie).MaxAge)
+
+	_, _, err = refreshRequest(a, refresh)
+	assert.ErrorIs(t, err, ErrRefreshReused)
+	_, err = authRequest(a, access)
+	assert.ErrorIs(t, err, ErrTokenRevoked)
+}
+
+func authRequest(a *authMiddle, access *http.Cookie) (entities.User, error) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(access)
+	return a.Auth(echo.New().NewContext(req, httptest.NewRecorder()))
+}
+
+func Test_authMiddle_LogoutAll(t *testing.T) {
+	a := newTestAuth("testkey")
+	register := func(userID int) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		_, err := a.Register(echo.New().NewContext(httptest.NewRequest(http.MethodPost, "/", nil), rec), entities.User{ID: userID})
+		require.NoError(t, err)
+		return rec
+	}
+	a.now = func() time.Time { return testNow.Add(-time.Second) }
+	laptop, other := register(7), register(8)
+	// токен выпущен и сразу же, в ту же секунду, выходим со всех устройств
+	a.now = func() time.Time { return testNow.Add(300 * time.Millisecond) }
+	phone := register(7)
+	a.now = func() time.Time { return testNow.Add(300*time.Millisecond + time.Microsecond) }
+	req := httptest.NewRequest(http.MethodPost, "/api/auth/logout-all", nil)
+	req.AddCookie(cookieByName(phone, accessCookie))
+	require.NoError(t, a.LogoutAll(echo.New().NewContext(req, httptest.NewRecorder())))
+
+	_, err := authRequest(a, cookieByName(phone, accessCookie))
+	assert.ErrorIs(t, err, ErrTokenRevoked)
+	_, err = authRequest(a, cookieByName(laptop, accessCookie))
+	assert.ErrorIs(t, err, ErrTokenRevoked)
+	_, _, err = refreshRequest(a, cookieByName(laptop, refreshCookie))
+	assert.ErrorIs(t, err, ErrRefreshReused)
+	// токены других пользователей не трогаем
+	user, err := authRequest(a, cookieByName(other, accessCookie))
+	require.NoError(t, err)
+	assert.Equal(t, entities.User{ID: 8}, user)
+
+	// токены, выпущенные после выхода в ту же секунду, работают
+	a.now = func() time.Time { return testNow.Add(700 * time.Millisecond) }
+	user, err = authRequest(a, cookieByName(register(7), accessCookie))
+	require.NoError(t, err)
+	assert.Equal(t, entities.User{ID: 7}, user)
+}
+
+func Test_authMiddle_RevokeAccess(t *testing.T) {
+	a := newTestAuth("testkey")
+	a.now = func() time.Time { return testNow.Add(-time.Second) }
+	rec := httptest.NewRecorder()
+	_, err := a.Register(echo.New().NewContext(httptest.NewRequest(http.MethodPost, "/", nil), rec), entities.User{ID: 7})
+	require.NoError(t, err)
+
+	a.now = func() time.Time { return testNow }
+	require.NoError(t, a.RevokeAccess(echo.New().NewContext(httptest.NewRequest(http.MethodPut, "/", nil), httptest.NewRecorder()), 7))
+	_, err = authRequest(a, cookieByName(rec, accessCookie))
+	assert.ErrorIs(t, err, ErrTokenRevoked)
+	// refresh выдаёт новый токен уже с текущей ролью
+	_, _, err = refreshRequest(a, cookieByName(rec, refreshCookie))
+	assert.NoError(t, err)
+}
+
+func Test_authMiddle_BearerToken(t *testing.T) {
+	a := newTestAuth("testkey")
+	rec := httptest.NewRecorder()
+	tokens, err := a.Register(echo.New().NewContext(httptest.NewRequest(http.MethodPost, "/", nil), rec), entities.User{ID: 7})
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer", tokens.TokenType)
+	assert.Equal(t, int(a.cfg.AccessTTL.Seconds()), tokens.ExpiresIn)
+	assert.Equal(t, cookieByName(rec, accessCookie).Value, tokens.AccessToken)
+	assert.Equal(t, cookieByName(rec, refreshCookie).Value, tokens.RefreshToken)
+
+	auth := func(header string, cookie *http.Cookie) (entities.User, error) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if header != "" {
+			req.Header.Set(echo.HeaderAuthorization, header)
+		}
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		return a.Auth(echo.New().NewContext(req, httptest.NewRecorder()))
+	}
+	user, err := auth("Bearer "+tokens.AccessToken, nil)
+	require.NoError(t, err)
+	assert.Equal(t, entities.User{ID: 7}, user)
+	_, err = auth("bearer "+tokens.AccessToken, nil)
+	assert.NoError(t, err)
+	_, err = auth("Basic "+tokens.AccessToken, nil)
+	assert.ErrorIs(t, err, ErrNoToken)
+
+	// источники проверяются в порядке из конфига
+	broken := &http.Cookie{Name: accessCookie, Value: "broken.token"}
+	_, err = auth("Bearer "+tokens.AccessToken, broken)
+	assert.NoError(t, err)
+	a.cfg.TokenSources = []string{"cookie", "header"}
+	_, err = auth("Bearer "+tokens.AccessToken, broken)
+	assert.Error(t, err)
+	a.cfg.TokenSources = []string{"cookie"}
+	_, err = auth("Bearer "+tokens.AccessToken, nil)
+	assert.ErrorIs(t, err, ErrNoToken)
+}
+
+func Test_authMiddle_RefreshFromBody(t *testing.T) {
+	a := newTestAuth("testkey")
+	tokens, err := a.Register(echo.New().NewContext(httptest.NewRequest(http.MethodPost, "/", nil), httptest.NewRecorder()), entities.User{ID: 7})
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/auth/refresh", strings.NewReader(`{"refresh_token":"`+tokens.RefreshToken+`"}`))
+	session, err := a.Refresh(echo.New().NewContext(req, httptest.NewRecorder()))
+	require.NoError(t, err)
+	assert.Equal(t, 7, session.User.ID)
+	assert.NotEqual(t, tokens.RefreshToken, session.RefreshToken)
+	assert.NotEmpty(t, session.AccessToken)
+}
+
+func Test_authMiddle_CookieAttributes(t *testing.T) {
+	a := newTestAuth("testkey")
+	rec := httptest.NewRecorder()
+	_, err := a.Register(echo.New().NewContext(httptest.NewRequest(http.MethodPost, "/", nil), rec), entities.User{ID: 7})
+	require.NoError(t, err)
+
+	access := cookieByName(rec, accessCookie)
+	assert.Equal(t, "/", access.Path)
+	assert.True(t, access.Secure)
+	assert.True(t, access.HttpOnly)
+	assert.Equal(t, http.SameSiteStrictMode, access.SameSite)
+	refresh := cookieByName(rec, refreshCookie)
+	assert.Equal(t, refreshCookiePath, refresh.Path)
+	assert.True(t, refresh.Secure)
+	assert.Equal(t, http.SameSiteStrictMode, refresh.SameSite)
+
+	a.cfg.Cookie = config.Cookie{Path: "/api", SameSite: http.SameSiteLaxMode}
+	rec = httptest.NewRecorder()
+	_, err = a.Register(echo.New().NewContext(httptest.NewRequest(http.MethodPost, "/", nil), rec), entities.User{ID: 7})
+	require.NoError(t, err)
+	access = cookieByName(rec, accessCookie)
+	assert.Equal(t, "/api", access.Path)
+	assert.False(t, access.Secure)
+	assert.False(t, access.HttpOnly)
+	// refresh токен скриптам недоступен при любом конфиге
+	assert.True(t, cookieByName(rec, refreshCookie).HttpOnly)
+}
@@ -0,0 +1,28 @@
+package entities
+
+import "time"
+
+// RefreshToken is a stored refresh token, only sha256 hash of the token is kept.
+// Tokens issued one from another by rotation share FamilyID
+type RefreshToken struct {
+	ID        int
+	Hash      string
+	UserID    int
+	FamilyID  string
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	RevokedAt *time.Time
+}
+
+// APIKey is a stored personal API key, only sha256 hash of the key is kept.
+// Prefix is the beginning of the key shown in the list of keys
+type APIKey struct {
+	ID        int
+	Hash      string
+	Prefix    string
+	UserID    int
+	Name      string
+	Scopes    []string
+	ExpiresAt *time.Time
+	CreatedAt time.Time
+}
@@ -0,0 +1,211 @@
+package authmiddleware
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+
+	"github.com/SapolovichSV/backprogeng/internal/config"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var ErrNoSigningKey = errors.New("no jwt signing key is configured: set JWT_SIGNING_KEYS or SECRET")
+
+// devSecret подписывает токены в разработке, если ключи не заданы
+const devSecret = "simple"
+
+// verifyKey is a public key which verifies tokens with its kid
+type verifyKey struct {
+	method jwt.SigningMethod
+	public crypto.PublicKey
+}
+
+// KeySet signs new tokens with one key and verifies tokens with the key
+// chosen by kid from the token header. Tokens without kid are HS256 tokens
+// signed with the secret, once signing keys are loaded the secret verifies
+// only tokens of the old format, see legacyKeyFunc
+type KeySet struct {
+	signKID    string
+	signMethod jwt.SigningMethod
+	// signKey is crypto.Signer of the signing key or the secret for HS256
+	signKey interface{}
+	keys    map[string]verifyKey
+	secret  []byte
+}
+
+// LoadKeys reads PEM files of cfg.SigningKeys. Without signing keys tokens are
+// signed with cfg.Secret, in development a built-in secret is used if it is empty
+// and in production ErrNoSigningKey is returned
+func LoadKeys(cfg config.Auth, production bool) (*KeySet, error) {
+	ks := &KeySet{keys: map[string]verifyKey{}}
+	if cfg.Secret != "" {
+		ks.secret = []byte(cfg.Secret)
+	}
+	for kid, path := range cfg.SigningKeys {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read jwt key %s: %w", kid, err)
+		}
+		private, public, method, err := parsePEMKey(data)
+		if err != nil {
+			return nil, fmt.Errorf("parse jwt key %s: %w", kid, err)
+		}
+		ks.keys[kid] = verifyKey{method: method, public: public}
+		if kid != cfg.SigningKID {
+			continue
+		}
+		if private == nil {
+			return nil, fmt.Errorf("jwt key %s signs tokens but has no private key", kid)
+		}
+		ks.signKID, ks.signMethod, ks.signKey = kid, method, private
+	}
+	if len(cfg.SigningKeys) > 0 && ks.signKey == nil {
+		return nil, fmt.Errorf("jwt signing key %q is not among the keys", cfg.SigningKID)
+	}
+	if ks.signKey != nil {
+		return ks, nil
+	}
+	if ks.secret == nil {
+		if production {
+			return nil, ErrNoSigningKey
+		}
+		ks.secret = []byte(devSecret)
+	}
+	ks.signMethod, ks.signKey = jwt.SigningMethodHS256, ks.secret
+	return ks, nil
+}
+
+// NewHMACKeySet returns KeySet which signs and verifies HS256 tokens with the secret
+func NewHMACKeySet(secret string) *KeySet {
+	return &KeySet{
+		signMethod: jwt.SigningMethodHS256,
+		signKey:    []byte(secret),
+		keys:       map[string]verifyKey{},
+		secret:     []byte(secret),
+	}
+}
+
+// parsePEMKey понимает закрытые RSA(PKCS1, PKCS8) и Ed25519(PKCS8) ключи,
+// а также открытые ключи, которые только проверяют подпись старых токенов
+func parsePEMKey(data []byte) (crypto.Signer, crypto.PublicKey, jwt.SigningMethod, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, nil, nil, errors.New("no PEM block")
+	}
+	var key interface{}
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		key, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, nil, nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		return k, k.Public(), jwt.SigningMethodRS256, nil
+	case ed25519.PrivateKey:
+		return k, k.Public(), jwt.SigningMethodEdDSA, nil
+	case *rsa.PublicKey:
+		return nil, k, jwt.SigningMethodRS256, nil
+	case ed25519.PublicKey:
+		return nil, k, jwt.SigningMethodEdDSA, nil
+	}
+	return nil, nil, nil, fmt.Errorf("unsupported key type %T", key)
+}
+
+// Sign signs the claims with the signing key and puts its kid into the header
+func (ks *KeySet) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(ks.signMethod, claims)
+	if ks.signKID != "" {
+		token.Header["kid"] = ks.signKID
+	}
+	return token.SignedString(ks.signKey)
+}
+
+// keyFunc is jwt.Keyfunc which chooses the key by kid and checks the algorithm,
+// tokens without kid are accepted only while the secret signs new tokens
+func (ks *KeySet) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		// с ключами секрет не подписывает новые токены и не должен их проверять
+		if ks.signKID != "" {
+			return nil, fmt.Errorf("token without kid, tokens are signed with kid %q", ks.signKID)
+		}
+		return ks.legacyKeyFunc(token)
+	}
+	key, ok := ks.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown kid %q", kid)
+	}
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method %v for kid %q", token.Header["alg"], kid)
+	}
+	return key.public, nil
+}
+
+// legacyKeyFunc is jwt.Keyfunc for tokens of the old format, they have no kid
+// and are signed with the secret
+func (ks *KeySet) legacyKeyFunc(token *jwt.Token) (interface{}, error) {
+	if _, ok := token.Header["kid"]; ok {
+		return nil, errors.New("token of the old format has kid")
+	}
+	if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok || ks.secret == nil {
+		return nil, fmt.Errorf("unexpected signing method %v without kid", token.Header["alg"])
+	}
+	return ks.secret, nil
+}
+
+// JWK is a public key in JSON Web Key format
+type JWK struct {
+	Kty string `json:"kty" example:"RSA"`
+	Kid string `json:"kid" example:"2025-01"`
+	Use string `json:"use" example:"sig"`
+	Alg string `json:"alg" example:"RS256"`
+	// N and E are set for RSA keys
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// Crv and X are set for Ed25519 keys
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKS is a set of public keys which verify tokens
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns public keys in kid order, the secret isn't published
+func (ks *KeySet) JWKS() JWKS {
+	res := JWKS{Keys: []JWK{}}
+	for kid, key := range ks.keys {
+		jwk := JWK{Kid: kid, Use: "sig", Alg: key.method.Alg()}
+		switch k := key.public.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(k.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(k.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(k)
+		}
+		res.Keys = append(res.Keys, jwk)
+	}
+	sort.Slice(res.Keys, func(i, j int) bool { return res.Keys[i].Kid < res.Keys[j].Kid })
+	return res
+}
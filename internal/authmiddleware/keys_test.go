@@ -0,0 +1,210 @@
+package authmiddleware
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/SapolovichSV/backprogeng/internal/config"
+	"github.com/SapolovichSV/backprogeng/internal/user/entities"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeKeys пишет во временную папку RSA и Ed25519 ключи и открытую часть RSA ключа
+func writeKeys(t *testing.T) map[string]string {
+	dir := t.TempDir()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	edDER, err := x509.MarshalPKCS8PrivateKey(edKey)
+	require.NoError(t, err)
+	pubDER, err := x509.MarshalPKIXPublicKey(rsaKey.Public())
+	require.NoError(t, err)
+
+	paths := map[string]string{}
+	for name, block := range map[string]*pem.Block{
+		"rsa":     {Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)},
+		"ed":      {Type: "PRIVATE KEY", Bytes: edDER},
+		"rsa-pub": {Type: "PUBLIC KEY", Bytes: pubDER},
+	} {
+		path := filepath.Join(dir, name+".pem")
+		require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(block), 0o600))
+		paths[name] = path
+	}
+	return paths
+}
+
+func accessFor(t *testing.T, a *authMiddle, userID int) *http.Cookie {
+	rec := httptest.NewRecorder()
+	_, err := a.Register(echo.New().NewContext(httptest.NewRequest(http.MethodPost, "/", nil), rec), entities.User{ID: userID})
+	require.NoError(t, err)
+	return cookieByName(rec, accessCookie)
+}
+
+func TestLoadKeys_rotation(t *testing.T) {
+	paths := writeKeys(t)
+
+	a := newTestAuth("")
+	var err error
+	a.keys, err = LoadKeys(config.Auth{
+		SigningKeys: map[string]string{"ed": paths["ed"]},
+		SigningKID:  "ed",
+	}, true)
+	require.NoError(t, err)
+	edToken := accessFor(t, a, 7)
+	token, _, err := jwt.NewParser().ParseUnverified(edToken.Value, &jwtClaims{})
+	require.NoError(t, err)
+	assert.Equal(t, "EdDSA", token.Method.Alg())
+	assert.Equal(t, "ed", token.Header["kid"])
+
+	// новым ключом подписываем, старый остаётся для проверки
+	a.keys, err = LoadKeys(config.Auth{
+		SigningKeys: map[string]string{"ed": paths["ed"], "rsa": paths["rsa"]},
+		SigningKID:  "rsa",
+	}, true)
+	require.NoError(t, err)
+	rsaToken := accessFor(t, a, 8)
+	token, _, err = jwt.NewParser().ParseUnverified(rsaToken.Value, &jwtClaims{})
+	require.NoError(t, err)
+	assert.Equal(t, "RS256", token.Method.Alg())
+
+	for _, tt := range []struct {
+		cookie *http.Cookie
+		want   entities.User
+	}{{edToken, entities.User{ID: 7}}, {rsaToken, entities.User{ID: 8}}} {
+		user, err := authRequest(a, tt.cookie)
+		require.NoError(t, err)
+		assert.Equal(t, tt.want, user)
+	}
+
+	// без секрета HS256 токены не принимаются
+	_, err = authRequest(a, accessFor(t, newTestAuth("testkey"), 7))
+	assert.Error(t, err)
+
+	jwks := a.keys.JWKS()
+	require.Len(t, jwks.Keys, 2)
+	assert.Equal(t, JWK{Kty: "OKP", Kid: "ed", Use: "sig", Alg: "EdDSA", Crv: "Ed25519", X: jwks.Keys[0].X}, jwks.Keys[0])
+	assert.Equal(t, "RSA", jwks.Keys[1].Kty)
+	assert.Equal(t, "AQAB", jwks.Keys[1].E)
+	assert.NotEmpty(t, jwks.Keys[1].N)
+}
+
+func TestLoadKeys_secretAfterRotation(t *testing.T) {
+	paths := writeKeys(t)
+
+	a := newTestAuth("")
+	var err error
+	a.keys, err = LoadKeys(config.Auth{
+		SigningKeys: map[string]string{"ed": paths["ed"]},
+		SigningKID:  "ed",
+		Secret:      "testkey",
+	}, true)
+	require.NoError(t, err)
+
+	// секретом нельзя подписать токен нового формата, в том числе с ролью админа
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, jwtClaims{
+		Role: RoleAdmin,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   "7",
+			Issuer:    a.cfg.Issuer,
+			Audience:  jwt.ClaimStrings{a.cfg.Audience},
+			IssuedAt:  jwt.NewNumericDate(testNow),
+			ExpiresAt: jwt.NewNumericDate(testNow.Add(time.Hour)),
+		},
+	})
+	token, err := forged.SignedString([]byte("testkey"))
+	require.NoError(t, err)
+	_, err = a.getClaims(token)
+	assert.Error(t, err)
+	_, err = authRequest(a, &http.Cookie{Name: accessCookie, Value: token})
+	assert.Error(t, err)
+
+	// токен старого формата принимается только до конца льготного периода
+	legacy := jwt.NewWithClaims(jwt.SigningMethodHS256, legacyClaims{
+		Id: 7,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(testNow.Add(time.Hour)),
+		},
+	})
+	token, err = legacy.SignedString([]byte("testkey"))
+	require.NoError(t, err)
+	claims, err := a.getClaims(token)
+	require.NoError(t, err)
+	assert.Equal(t, "7", claims.Subject)
+	assert.Equal(t, DefaultRole, claims.Role)
+
+	a.cfg.LegacyTokensUntil = time.Time{}
+	_, err = a.getClaims(token)
+	assert.Error(t, err)
+}
+
+func TestLoadKeys_errors(t *testing.T) {
+	paths := writeKeys(t)
+	tests := []struct {
+		name       string
+		cfg        config.Auth
+		production bool
+		wantErr    bool
+	}{
+		{name: "production_without_keys", production: true, wantErr: true},
+		{name: "production_with_secret", cfg: config.Auth{Secret: "secret"}, production: true},
+		{name: "development_without_keys"},
+		{
+			name:    "public_key_signs",
+			cfg:     config.Auth{SigningKeys: map[string]string{"pub": paths["rsa-pub"]}, SigningKID: "pub"},
+			wantErr: true,
+		},
+		{
+			name: "public_key_verifies",
+			cfg: config.Auth{
+				SigningKeys: map[string]string{"pub": paths["rsa-pub"], "ed": paths["ed"]},
+				SigningKID:  "ed",
+			},
+		},
+		{
+			name:    "missing_file",
+			cfg:     config.Auth{SigningKeys: map[string]string{"ed": paths["ed"] + ".missing"}, SigningKID: "ed"},
+			wantErr: true,
+		},
+		{
+			name:    "unknown_signing_kid",
+			cfg:     config.Auth{SigningKeys: map[string]string{"ed": paths["ed"]}, SigningKID: "rsa"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadKeys(tt.cfg, tt.production)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("LoadKeys() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+	_, err := LoadKeys(config.Auth{}, true)
+	assert.ErrorIs(t, err, ErrNoSigningKey)
+}
+
+func Test_authMiddle_JWKS(t *testing.T) {
+	paths := writeKeys(t)
+	keys, err := LoadKeys(config.Auth{SigningKeys: map[string]string{"ed": paths["ed"]}, SigningKID: "ed"}, false)
+	require.NoError(t, err)
+	a := newTestAuth("")
+	a.keys = keys
+	rec := httptest.NewRecorder()
+	require.NoError(t, a.JWKS(echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil), rec)))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"kid":"ed"`)
+	assert.NotContains(t, rec.Body.String(), "simple")
+}
@@ -0,0 +1,121 @@
+package authmiddleware
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/SapolovichSV/backprogeng/internal/errlib"
+	"github.com/labstack/echo/v4"
+)
+
+// Principal is the authenticated caller of the request.
+// APIKeyID and Scopes are set only when the caller used an API key
+type Principal struct {
+	UserID   int
+	Role     string
+	APIKeyID int
+	Scopes   []string
+}
+
+// allows reports if scopes of the API key permit the request method,
+// callers with tokens aren't limited by scopes
+func (p Principal) allows(method string) bool {
+	if p.APIKeyID == 0 {
+		return true
+	}
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return slices.Contains(p.Scopes, ScopeRead) || slices.Contains(p.Scopes, ScopeWrite)
+	default:
+		return slices.Contains(p.Scopes, ScopeWrite)
+	}
+}
+
+const principalKey = "authmiddleware.principal"
+
+var ErrNoPrincipal = errors.New("request is not authenticated")
+
+// SetPrincipal stores the principal in the echo context
+func SetPrincipal(c echo.Context, p Principal) {
+	c.Set(principalKey, p)
+}
+
+// PrincipalFrom returns the principal put into the context by Middleware,
+// errlib.UnauthorizedErr is returned if the route isn't behind Middleware
+func PrincipalFrom(c echo.Context) (Principal, error) {
+	p, ok := c.Get(principalKey).(Principal)
+	if !ok {
+		return Principal{}, errlib.UnauthorizedErr{Err: ErrNoPrincipal}
+	}
+	return p, nil
+}
+
+// Middleware validates the token or API key of the request and puts the principal
+// into the echo context, requests without valid token get 401,
+// requests beyond scopes of the API key get 403.
+// It can be attached to routes and route groups
+func (a *authMiddle) Middleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		p, err := a.authenticate(c)
+		if err != nil {
+			return errlib.UnauthorizedErr{Err: err}
+		}
+		if !p.allows(c.Request().Method) {
+			return errlib.ForbiddenErr{Err: errors.New("api key has no scope for " + c.Request().Method + " requests")}
+		}
+		SetPrincipal(c, p)
+		return next(c)
+	}
+}
+
+// DenyAPIKeys allows the route only to callers with tokens, so a leaked key
+// can't be used to manage keys. It must be attached after Middleware
+func DenyAPIKeys(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		p, err := PrincipalFrom(c)
+		if err != nil {
+			return err
+		}
+		if p.APIKeyID != 0 {
+			return errlib.ForbiddenErr{Err: errors.New("api keys can't be used here, log in instead")}
+		}
+		return next(c)
+	}
+}
+
+// RequireRole allows the route only to principals with one of the roles,
+// others get 403. It must be attached after Middleware
+func RequireRole(roles ...string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			p, err := PrincipalFrom(c)
+			if err != nil {
+				return err
+			}
+			if !slices.Contains(roles, p.Role) {
+				return errlib.ForbiddenErr{Err: errors.New(strings.Join(roles, " or ") + " role is required")}
+			}
+			return next(c)
+		}
+	}
+}
+
+// RequireSelfOrRole allows the route only to the user whose id is in the path param
+// and to principals with one of the roles, others get 403. It must be attached after Middleware
+func RequireSelfOrRole(param string, roles ...string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			p, err := PrincipalFrom(c)
+			if err != nil {
+				return err
+			}
+			if c.Param(param) != strconv.Itoa(p.UserID) && !slices.Contains(roles, p.Role) {
+				return errlib.ForbiddenErr{Err: errors.New("only the user itself or " + strings.Join(roles, " or ") + " can access it")}
+			}
+			return next(c)
+		}
+	}
+}
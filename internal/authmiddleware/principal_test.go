@@ -0,0 +1,91 @@
+package authmiddleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/SapolovichSV/backprogeng/internal/errlib"
+	"github.com/SapolovichSV/backprogeng/internal/user/entities"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_authMiddle_Middleware(t *testing.T) {
+	a := newTestAuth("testkey")
+	rec := httptest.NewRecorder()
+	_, err := a.Register(echo.New().NewContext(httptest.NewRequest(http.MethodPost, "/", nil), rec), entities.User{ID: 7})
+	require.NoError(t, err)
+	access := cookieByName(rec, accessCookie)
+
+	var got Principal
+	handler := a.Middleware(func(c echo.Context) error {
+		p, err := PrincipalFrom(c)
+		got = p
+		return err
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/api/drink", nil)
+	req.AddCookie(access)
+	require.NoError(t, handler(echo.New().NewContext(req, httptest.NewRecorder())))
+	assert.Equal(t, Principal{UserID: 7, Role: DefaultRole}, got)
+
+	err = handler(echo.New().NewContext(httptest.NewRequest(http.MethodPost, "/api/drink", nil), httptest.NewRecorder()))
+	assert.True(t, errlib.CheckErrUnauthorized(err))
+}
+
+func TestPrincipalFrom_withoutMiddleware(t *testing.T) {
+	c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/", nil), httptest.NewRecorder())
+	_, err := PrincipalFrom(c)
+	assert.ErrorIs(t, err, ErrNoPrincipal)
+	assert.True(t, errlib.CheckErrUnauthorized(err))
+}
+
+func Test_RequireRole(t *testing.T) {
+	handler := RequireRole(RoleAdmin)(func(c echo.Context) error {
+		return c.NoContent(http.StatusNoContent)
+	})
+	tests := []struct {
+		name      string
+		principal *Principal
+		check     func(error) bool
+	}{
+		{name: "admin", principal: &Principal{UserID: 1, Role: RoleAdmin}, check: func(err error) bool { return err == nil }},
+		{name: "user", principal: &Principal{UserID: 2, Role: RoleUser}, check: errlib.CheckErrForbidden},
+		{name: "no_principal", check: errlib.CheckErrUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := echo.New().NewContext(httptest.NewRequest(http.MethodPost, "/api/drink", nil), httptest.NewRecorder())
+			if tt.principal != nil {
+				SetPrincipal(c, *tt.principal)
+			}
+			assert.True(t, tt.check(handler(c)))
+		})
+	}
+}
+
+func Test_RequireSelfOrRole(t *testing.T) {
+	handler := RequireSelfOrRole("id", RoleAdmin)(func(c echo.Context) error {
+		return c.NoContent(http.StatusNoContent)
+	})
+	tests := []struct {
+		name      string
+		principal Principal
+		check     func(error) bool
+	}{
+		{name: "self", principal: Principal{UserID: 5, Role: RoleUser}, check: func(err error) bool { return err == nil }},
+		{name: "admin", principal: Principal{UserID: 1, Role: RoleAdmin}, check: func(err error) bool { return err == nil }},
+		{name: "other_user", principal: Principal{UserID: 2, Role: RoleUser}, check: errlib.CheckErrForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/api/user/5", nil), httptest.NewRecorder())
+			c.SetParamNames("id")
+			c.SetParamValues("5")
+			SetPrincipal(c, tt.principal)
+			assert.True(t, tt.check(handler(c)))
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package queries
+
+import (
+	"github.com/SapolovichSV/backprogeng/internal/authmiddleware/entities"
+	"github.com/SapolovichSV/backprogeng/internal/errlib"
+)
+
+const API_KEYS_TABLE_NAME = "api_keys"
+
+func (q *Query) CreateAPIKey(k entities.APIKey) (entities.APIKey, error) {
+	sql := `INSERT INTO api_keys
+	(key_hash, prefix, user_id, name, scopes, expires_at)
+	VALUES ($1, $2, $3, $4, $5, $6)
+	RETURNING id, created_at;`
+	err := q.db.QueryRow(q.ctx, sql, k.Hash, k.Prefix, k.UserID, k.Name, k.Scopes, k.ExpiresAt).
+		Scan(&k.ID, &k.CreatedAt)
+	if err != nil {
+		return entities.APIKey{}, errlib.WrapError(err, API_KEYS_TABLE_NAME, "api key "+k.Name)
+	}
+	return k, nil
+}
+func (q *Query) APIKeyByHash(hash string) (entities.APIKey, error) {
+	sql := `SELECT id, key_hash, prefix, user_id, name, scopes, expires_at, created_at
+	FROM api_keys
+	WHERE key_hash = $1;`
+	var k entities.APIKey
+	err := q.db.QueryRow(q.ctx, sql, hash).
+		Scan(&k.ID, &k.Hash, &k.Prefix, &k.UserID, &k.Name, &k.Scopes, &k.ExpiresAt, &k.CreatedAt)
+	if err != nil {
+		return entities.APIKey{}, errlib.WrapError(err, API_KEYS_TABLE_NAME, "api key")
+	}
+	return k, nil
+}
+func (q *Query) APIKeysByUserID(userID int) ([]entities.APIKey, error) {
+	sql := `SELECT id, key_hash, prefix, user_id, name, scopes, expires_at, created_at
+	FROM api_keys
+	WHERE user_id = $1
+	ORDER BY id;`
+	rows, err := q.db.Query(q.ctx, sql, userID)
+	if err != nil {
+		return nil, errlib.WrapError(err, API_KEYS_TABLE_NAME, "api keys of user")
+	}
+	defer rows.Close()
+	keys := []entities.APIKey{}
+	for rows.Next() {
+		var k entities.APIKey
+		if err := rows.Scan(&k.ID, &k.Hash, &k.Prefix, &k.UserID, &k.Name, &k.Scopes, &k.ExpiresAt, &k.CreatedAt); err != nil {
+			return nil, errlib.WrapError(err, API_KEYS_TABLE_NAME, "api keys of user")
+		}
+		keys = append(keys, k)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errlib.WrapError(err, API_KEYS_TABLE_NAME, "api keys of user")
+	}
+	return keys, nil
+}
+
+// DeleteAPIKey deletes the key only if it belongs to the user,
+// errlib.NotFoundErr is returned otherwise
+func (q *Query) DeleteAPIKey(userID int, id int) error {
+	sql := `DELETE FROM api_keys
+	WHERE id = $1 AND user_id = $2;`
+	res, err := q.db.Exec(q.ctx, sql, id, userID)
+	if err != nil {
+		return errlib.WrapError(err, API_KEYS_TABLE_NAME, "api key can't be deleted")
+	}
+	if res.RowsAffected() == 0 {
+		return errlib.NotFoundErr{Where: API_KEYS_TABLE_NAME, What: "api key"}
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package queries
+
+import (
+	"context"
+
+	"github.com/SapolovichSV/backprogeng/internal/authmiddleware/entities"
+	"github.com/SapolovichSV/backprogeng/internal/dblib"
+	"github.com/SapolovichSV/backprogeng/internal/errlib"
+)
+
+type Query struct {
+	ctx context.Context
+	db  dblib.DBTX
+}
+
+const TABLE_NAME = "refresh_tokens"
+
+func New(ctx context.Context, db dblib.DBTX) *Query {
+	return &Query{
+		ctx: ctx,
+		db:  db,
+	}
+}
+func (q *Query) CreateRefreshToken(t entities.RefreshToken) error {
+	sql := `INSERT INTO refresh_tokens
+	(token_hash, user_id, family_id, expires_at)
+	VALUES ($1, $2, $3, $4);`
+	if _, err := q.db.Exec(q.ctx, sql, t.Hash, t.UserID, t.FamilyID, t.ExpiresAt); err != nil {
+		return errlib.WrapError(err, TABLE_NAME, "refresh token can't be created")
+	}
+	return nil
+}
+
+// RefreshTokenByHashForUpdate locks the token till the end of transaction
+func (q *Query) RefreshTokenByHashForUpdate(hash string) (entities.RefreshToken, error) {
+	sql := `SELECT id, token_hash, user_id, family_id, expires_at, used_at, revoked_at
+	FROM refresh_tokens
+	WHERE token_hash = $1
+	FOR UPDATE;`
+	var t entities.RefreshToken
+	err := q.db.QueryRow(q.ctx, sql, hash).
+		Scan(&t.ID, &t.Hash, &t.UserID, &t.FamilyID, &t.ExpiresAt, &t.UsedAt, &t.RevokedAt)
+	if err != nil {
+		return entities.RefreshToken{}, errlib.WrapError(err, TABLE_NAME, "refresh token")
+	}
+	return t, nil
+}
+func (q *Query) MarkRefreshTokenUsed(id int) error {
+	sql := `UPDATE refresh_tokens
+	SET used_at = now()
+	WHERE id = $1;`
+	if _, err := q.db.Exec(q.ctx, sql, id); err != nil {
+		return errlib.WrapError(err, TABLE_NAME, "refresh token can't be used")
+	}
+	return nil
+}
+
+// RevokeRefreshFamily revokes all not yet revoked tokens of the family
+func (q *Query) RevokeRefreshFamily(familyID string) error {
+	sql := `UPDATE refresh_tokens
+	SET revoked_at = now()
+	WHERE family_id = $1 AND revoked_at IS NULL;`
+	if _, err := q.db.Exec(q.ctx, sql, familyID); err != nil {
+		return errlib.WrapError(err, TABLE_NAME, "refresh tokens can't be revoked")
+	}
+	return nil
+}
+
+// RevokeRefreshFamilyByHash revokes the family of the token with the hash
+func (q *Query) RevokeRefreshFamilyByHash(hash string) error {
+	sql := `UPDATE refresh_tokens
+	SET revoked_at = now()
+	WHERE family_id = (SELECT family_id FROM refresh_tokens WHERE token_hash = $1)
+	AND revoked_at IS NULL;`
+	if _, err := q.db.Exec(q.ctx, sql, hash); err != nil {
+		return errlib.WrapError(err, TABLE_NAME, "refresh tokens can't be revoked")
+	}
+	return nil
+}
+
+// RevokeRefreshTokensOfUser revokes refresh tokens of all user sessions
+func (q *Query) RevokeRefreshTokensOfUser(userID int) error {
+	sql := `UPDATE refresh_tokens
+	SET revoked_at = now()
+	WHERE user_id = $1 AND revoked_at IS NULL;`
+	if _, err := q.db.Exec(q.ctx, sql, userID); err != nil {
+		return errlib.WrapError(err, TABLE_NAME, "refresh tokens can't be revoked")
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package queries
+
+import (
+	"time"
+
+	"github.com/SapolovichSV/backprogeng/internal/errlib"
+)
+
+const REVOKED_TABLE_NAME = "revoked_tokens"
+
+// RevokeToken stores jti of the token and removes the rows which are expired anyway
+func (q *Query) RevokeToken(jti string, expiresAt time.Time) error {
+	sql := `DELETE FROM revoked_tokens
+	WHERE expires_at < now();`
+	if _, err := q.db.Exec(q.ctx, sql); err != nil {
+		return errlib.WrapError(err, REVOKED_TABLE_NAME, "expired tokens can't be removed")
+	}
+	sql = `INSERT INTO revoked_tokens (jti, expires_at)
+	VALUES ($1, $2)
+	ON CONFLICT (jti) DO NOTHING;`
+	if _, err := q.db.Exec(q.ctx, sql, jti, expiresAt); err != nil {
+		return errlib.WrapError(err, REVOKED_TABLE_NAME, "token can't be revoked")
+	}
+	return nil
+}
+
+// RevokeUserTokens revokes all tokens of the user issued before the microsecond of before
+func (q *Query) RevokeUserTokens(userID int, before time.Time) error {
+	sql := `INSERT INTO user_revocations (user_id, revoked_before)
+	VALUES ($1, $2)
+	ON CONFLICT (user_id) DO UPDATE
+	SET revoked_before = GREATEST(user_revocations.revoked_before, EXCLUDED.revoked_before);`
+	// postgres округляет до микросекунд, а iat токенов обрезан, обрезаем и границу
+	if _, err := q.db.Exec(q.ctx, sql, userID, before.Truncate(time.Microsecond)); err != nil {
+		return errlib.WrapError(err, "user_revocations", "user tokens can't be revoked")
+	}
+	return nil
+}
+func (q *Query) IsTokenRevoked(jti string, userID int, issuedAt time.Time) (bool, error) {
+	sql := `SELECT EXISTS (SELECT 1 FROM revoked_tokens WHERE jti = $1)
+	OR EXISTS (SELECT 1 FROM user_revocations WHERE user_id = $2 AND revoked_before > $3);`
+	var revoked bool
+	if err := q.db.QueryRow(q.ctx, sql, jti, userID, issuedAt).Scan(&revoked); err != nil {
+		return false, errlib.WrapError(err, REVOKED_TABLE_NAME, "token revocation")
+	}
+	return revoked, nil
+}
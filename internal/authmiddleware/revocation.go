@@ -0,0 +1,93 @@
+package authmiddleware
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/SapolovichSV/backprogeng/internal/authmiddleware/queries"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// RevocationStore keeps access tokens revoked before they expire,
+// implemented by MemoryRevocationStore and SQLRevocationStore
+type RevocationStore interface {
+	// Revoke revokes one token, it has to be kept only till expiresAt
+	Revoke(ctx context.Context, jti string, expiresAt time.Time) error
+	// RevokeUser revokes all tokens of the user issued before the microsecond of before,
+	// iat of access tokens has the same precision
+	RevokeUser(ctx context.Context, userID int, before time.Time) error
+	IsRevoked(ctx context.Context, jti string, userID int, issuedAt time.Time) (bool, error)
+}
+
+// MemoryRevocationStore keeps revocations in memory of one process,
+// they are lost on restart
+type MemoryRevocationStore struct {
+	mu     sync.Mutex
+	tokens map[string]time.Time
+	users  map[int]time.Time
+	now    func() time.Time
+}
+
+func NewMemoryRevocationStore() *MemoryRevocationStore {
+	return &MemoryRevocationStore{
+		tokens: map[string]time.Time{},
+		users:  map[int]time.Time{},
+		now:    time.Now,
+	}
+}
+func (s *MemoryRevocationStore) Revoke(ctx context.Context, jti string, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// заодно выкидываем токены, которые истекли и так
+	now := s.now()
+	for token, exp := range s.tokens {
+		if exp.Before(now) {
+			delete(s.tokens, token)
+		}
+	}
+	s.tokens[jti] = expiresAt
+	return nil
+}
+func (s *MemoryRevocationStore) RevokeUser(ctx context.Context, userID int, before time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	before = before.Truncate(time.Microsecond)
+	if before.After(s.users[userID]) {
+		s.users[userID] = before
+	}
+	return nil
+}
+func (s *MemoryRevocationStore) IsRevoked(ctx context.Context, jti string, userID int, issuedAt time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.tokens[jti]; ok {
+		return true, nil
+	}
+	before, ok := s.users[userID]
+	return ok && issuedAt.Before(before), nil
+}
+
+// DB:
+// revoked_tokens
+// jti | expires_at
+// user_revocations
+// user_id | revoked_before
+type SQLRevocationStore struct {
+	db *pgxpool.Pool
+}
+
+func NewSQLRevocationStore(db *pgxpool.Pool) *SQLRevocationStore {
+	return &SQLRevocationStore{
+		db: db,
+	}
+}
+func (s *SQLRevocationStore) Revoke(ctx context.Context, jti string, expiresAt time.Time) error {
+	return queries.New(ctx, s.db).RevokeToken(jti, expiresAt)
+}
+func (s *SQLRevocationStore) RevokeUser(ctx context.Context, userID int, before time.Time) error {
+	return queries.New(ctx, s.db).RevokeUserTokens(userID, before)
+}
+func (s *SQLRevocationStore) IsRevoked(ctx context.Context, jti string, userID int, issuedAt time.Time) (bool, error) {
+	return queries.New(ctx, s.db).IsTokenRevoked(jti, userID, issuedAt)
+}
@@ -0,0 +1,76 @@
+package authmiddleware
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testRevocationStore проверяет одинаковое поведение обеих реализаций
+func testRevocationStore(t *testing.T, s RevocationStore) {
+	ctx := context.Background()
+	now := time.Now()
+
+	revoked, err := s.IsRevoked(ctx, "jti", 1, now)
+	require.NoError(t, err)
+	assert.False(t, revoked)
+
+	require.NoError(t, s.Revoke(ctx, "jti", now.Add(time.Hour)))
+	revoked, err = s.IsRevoked(ctx, "jti", 1, now)
+	require.NoError(t, err)
+	assert.True(t, revoked)
+
+	require.NoError(t, s.RevokeUser(ctx, 1, now))
+	revoked, err = s.IsRevoked(ctx, "other", 1, now.Add(-time.Minute))
+	require.NoError(t, err)
+	assert.True(t, revoked)
+	revoked, err = s.IsRevoked(ctx, "other", 1, now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.False(t, revoked)
+	revoked, err = s.IsRevoked(ctx, "other", 2, now.Add(-time.Minute))
+	require.NoError(t, err)
+	assert.False(t, revoked)
+
+	// граница точнее секунды: токен, выпущенный в ту же секунду до отзыва,
+	// отозван, а выпущенный после отзыва работает
+	before := now.Add(time.Hour).Truncate(time.Second).Add(300*time.Millisecond + 500*time.Nanosecond)
+	require.NoError(t, s.RevokeUser(ctx, 1, before))
+	revoked, err = s.IsRevoked(ctx, "other", 1, before.Add(-time.Microsecond).Truncate(time.Microsecond))
+	require.NoError(t, err)
+	assert.True(t, revoked)
+	revoked, err = s.IsRevoked(ctx, "other", 1, before.Truncate(time.Microsecond))
+	require.NoError(t, err)
+	assert.False(t, revoked)
+
+	// более ранний отзыв не отменяет более поздний
+	require.NoError(t, s.RevokeUser(ctx, 1, now.Add(-time.Hour)))
+	revoked, err = s.IsRevoked(ctx, "other", 1, now.Add(-time.Minute))
+	require.NoError(t, err)
+	assert.True(t, revoked)
+}
+
+func TestMemoryRevocationStore(t *testing.T) {
+	testRevocationStore(t, NewMemoryRevocationStore())
+}
+
+func TestSQLRevocationStore(t *testing.T) {
+	ctx := context.Background()
+	db, err := pgxpool.New(ctx, "host=localhost user=username password=password dbname=dbname sslmode=disable")
+	if err != nil {
+		t.Fatalf("Failed to connect to the database: %v", err)
+	}
+	defer db.Close()
+	_, err = db.Exec(ctx, QUERY_CREATE_TABLES)
+	defer db.Exec(ctx, QUERY_DROP_TABLES)
+	if err != nil {
+		t.Fatalf("Failed to create tables: %v", err)
+	}
+	if _, err := db.Exec(ctx, `INSERT INTO users (username, password) VALUES ('OtherUser', '');`); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	testRevocationStore(t, NewSQLRevocationStore(db))
+}
@@ -0,0 +1,83 @@
+package authmiddleware
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/SapolovichSV/backprogeng/internal/authmiddleware/entities"
+	"github.com/SapolovichSV/backprogeng/internal/authmiddleware/queries"
+	"github.com/SapolovichSV/backprogeng/internal/dblib"
+	"github.com/SapolovichSV/backprogeng/internal/errlib"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	ErrRefreshUnknown = errors.New("refresh token is unknown")
+	ErrRefreshExpired = errors.New("refresh token is expired")
+	ErrRefreshReused  = errors.New("refresh token is already used, all tokens of its family are revoked")
+)
+
+// DB:
+// refresh_tokens
+// id | token_hash | user_id | family_id | expires_at | created_at | used_at | revoked_at
+type SQLRefreshStore struct {
+	db *pgxpool.Pool
+}
+
+func NewSQLRefreshStore(db *pgxpool.Pool) *SQLRefreshStore {
+	return &SQLRefreshStore{
+		db: db,
+	}
+}
+func (s *SQLRefreshStore) Create(ctx context.Context, t entities.RefreshToken) error {
+	return queries.New(ctx, s.db).CreateRefreshToken(t)
+}
+
+// Rotate marks the token with the hash used and stores next in its family
+// in one transaction. Reuse of a used or revoked token revokes the whole family
+func (s *SQLRefreshStore) Rotate(ctx context.Context, hash string, next entities.RefreshToken, now time.Time) (entities.RefreshToken, error) {
+	var old entities.RefreshToken
+	reused := false
+	err := dblib.WithTx(ctx, s.db, func(tx dblib.DBTX) error {
+		q := queries.New(ctx, tx)
+		var err error
+		old, err = q.RefreshTokenByHashForUpdate(hash)
+		if errlib.CheckErrNotFound(err) {
+			return ErrRefreshUnknown
+		} else if err != nil {
+			return err
+		}
+		// отзыв семьи должен сохраниться, поэтому транзакцию не откатываем
+		if old.UsedAt != nil || old.RevokedAt != nil {
+			reused = true
+			return q.RevokeRefreshFamily(old.FamilyID)
+		}
+		if !now.Before(old.ExpiresAt) {
+			return ErrRefreshExpired
+		}
+		if err := q.MarkRefreshTokenUsed(old.ID); err != nil {
+			return err
+		}
+		next.UserID = old.UserID
+		next.FamilyID = old.FamilyID
+		return q.CreateRefreshToken(next)
+	})
+	if err != nil {
+		return entities.RefreshToken{}, err
+	}
+	if reused {
+		return entities.RefreshToken{}, ErrRefreshReused
+	}
+	return old, nil
+}
+
+// RevokeFamily revokes the family of the token with the hash
+func (s *SQLRefreshStore) RevokeFamily(ctx context.Context, hash string) error {
+	return queries.New(ctx, s.db).RevokeRefreshFamilyByHash(hash)
+}
+
+// RevokeUser revokes refresh tokens of all sessions of the user
+func (s *SQLRefreshStore) RevokeUser(ctx context.Context, userID int) error {
+	return queries.New(ctx, s.db).RevokeRefreshTokensOfUser(userID)
+}
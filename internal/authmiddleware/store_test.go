@@ -0,0 +1,106 @@
+package authmiddleware
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/SapolovichSV/backprogeng/internal/authmiddleware/entities"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const QUERY_CREATE_TABLES = `CREATE TABLE users (
+    id SERIAL PRIMARY KEY,
+    username VARCHAR(255) NOT NULL,
+    password VARCHAR(255),
+    role VARCHAR(16) NOT NULL DEFAULT 'user'
+);
+CREATE TABLE refresh_tokens (
+    id SERIAL PRIMARY KEY,
+    token_hash CHAR(64) NOT NULL UNIQUE,
+    user_id INT NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    family_id VARCHAR(64) NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    used_at TIMESTAMPTZ,
+    revoked_at TIMESTAMPTZ
+);
+CREATE TABLE revoked_tokens (
+    jti VARCHAR(64) PRIMARY KEY,
+    expires_at TIMESTAMPTZ NOT NULL
+);
+CREATE TABLE user_revocations (
+    user_id INT PRIMARY KEY,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    revoked_before TIMESTAMPTZ NOT NULL
+);
+CREATE TABLE api_keys (
+    id SERIAL PRIMARY KEY,
+    key_hash CHAR(64) NOT NULL UNIQUE,
+    prefix VARCHAR(16) NOT NULL,
+    user_id INT NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(64) NOT NULL,
+    scopes TEXT[] NOT NULL,
+    expires_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (user_id, name)
+);
+INSERT INTO users (username, password) VALUES ('TestUser', '');`
+const QUERY_DROP_TABLES = `DROP TABLE api_keys CASCADE;
+DROP TABLE user_revocations CASCADE;
+DROP TABLE revoked_tokens CASCADE;
+DROP TABLE refresh_tokens CASCADE;
+DROP TABLE users CASCADE;`
+
+func TestSQLRefreshStore_Rotate(t *testing.T) {
+	ctx := context.Background()
+	db, err := pgxpool.New(ctx, "host=localhost user=username password=password dbname=dbname sslmode=disable")
+	if err != nil {
+		t.Fatalf("Failed to connect to the database: %v", err)
+	}
+	defer db.Close()
+	_, err = db.Exec(ctx, QUERY_CREATE_TABLES)
+	defer db.Exec(ctx, QUERY_DROP_TABLES)
+	if err != nil {
+		t.Fatalf("Failed to create tables: %v", err)
+	}
+	s := NewSQLRefreshStore(db)
+	now := time.Now()
+
+	require.NoError(t, s.Create(ctx, entities.RefreshToken{
+		Hash: hashToken("first"), UserID: 1, FamilyID: "family", ExpiresAt: now.Add(time.Hour),
+	}))
+	old, err := s.Rotate(ctx, hashToken("first"), entities.RefreshToken{
+		Hash: hashToken("second"), ExpiresAt: now.Add(time.Hour),
+	}, now)
+	require.NoError(t, err)
+	assert.Equal(t, 1, old.UserID)
+	assert.Equal(t, "family", old.FamilyID)
+
+	_, err = s.Rotate(ctx, hashToken("first"), entities.RefreshToken{
+		Hash: hashToken("third"), ExpiresAt: now.Add(time.Hour),
+	}, now)
+	assert.ErrorIs(t, err, ErrRefreshReused)
+	// семья отозвана, значит и второй токен больше не работает
+	_, err = s.Rotate(ctx, hashToken("second"), entities.RefreshToken{
+		Hash: hashToken("third"), ExpiresAt: now.Add(time.Hour),
+	}, now)
+	assert.ErrorIs(t, err, ErrRefreshReused)
+
+	_, err = s.Rotate(ctx, hashToken("unknown"), entities.RefreshToken{
+		Hash: hashToken("third"), ExpiresAt: now.Add(time.Hour),
+	}, now)
+	assert.ErrorIs(t, err, ErrRefreshUnknown)
+
+	require.NoError(t, s.Create(ctx, entities.RefreshToken{
+		Hash: hashToken("expired"), UserID: 1, FamilyID: "other", ExpiresAt: now,
+	}))
+	_, err = s.Rotate(ctx, hashToken("expired"), entities.RefreshToken{
+		Hash: hashToken("third"), ExpiresAt: now.Add(time.Hour),
+	}, now)
+	assert.ErrorIs(t, err, ErrRefreshExpired)
+}
@@ -2,14 +2,79 @@ package config
 
 import (
 	"fmt"
+	"net/http"
 	"os"
+	"regexp"
 	"strconv"
+	"strings"
+	"time"
 )
 
 type Config struct {
+	// Env is development or production, in production nothing insecure is defaulted.
+	// Unset APP_ENV means production, the dev key and plain http cookies need APP_ENV=development
+	Env      string
 	Port     string
 	DbAddr   string
 	LogLevel int
+	// PasswordHash is the algorithm of new password hashes: bcrypt or argon2id
+	PasswordHash string
+	// LoginMaxFailures failed logins in a row block the client for LoginLockout
+	LoginMaxFailures int
+	LoginLockout     time.Duration
+	Auth             Auth
+	Validation       Validation
+}
+
+// Validation configures rules of user input checked by validate package
+type Validation struct {
+	UsernameMinLength int
+	UsernameMaxLength int
+	// UsernameCharset is the content of regexp character class, e.g. A-Za-z0-9_
+	UsernameCharset   string
+	PasswordMinLength int
+	PasswordMaxLength int
+	// PasswordRequire are classes of characters password must contain:
+	// lower, upper, letter, digit and symbol
+	PasswordRequire []string
+	// BreachedPasswordsFile is a wordlist of leaked passwords, one per line,
+	// empty path turns the check off
+	BreachedPasswordsFile string
+}
+
+// Auth configures tokens issued and accepted by authmiddleware
+type Auth struct {
+	Issuer   string
+	Audience string
+	// LegacyTokensUntil is the fixed end of the grace period while tokens
+	// of the old format without iss and aud are still accepted,
+	// zero time accepts no legacy tokens
+	LegacyTokensUntil time.Time
+	// AccessTTL is the lifetime of access tokens, RefreshTTL of refresh tokens
+	AccessTTL  time.Duration
+	RefreshTTL time.Duration
+	// RevocationStore keeps revoked tokens: postgres or memory
+	RevocationStore string
+	// Secret signs HS256 tokens if there are no SigningKeys,
+	// with SigningKeys it only verifies tokens of the old format until LegacyTokensUntil.
+	// Config is logged on start, so the secret is hidden from json
+	Secret string `json:"-"`
+	// SigningKeys are paths to PEM files with RSA or Ed25519 keys by their kid
+	SigningKeys map[string]string
+	// SigningKID is kid of the key which signs new tokens, other keys only verify
+	SigningKID string
+	// TokenSources are places of the access token in the request in order of precedence:
+	// header(Authorization: Bearer) and cookie
+	TokenSources []string
+	Cookie       Cookie
+}
+
+// Cookie configures attributes of token cookies
+type Cookie struct {
+	Path     string
+	Secure   bool
+	HttpOnly bool
+	SameSite http.SameSite
 }
 
 func ListConfig() Config {
@@ -49,11 +114,244 @@ func ListConfig() Config {
 		panic("Incorrect log level from env")
 	}
 	dbAddr := parseDbAddr()
+	passwordHash := os.Getenv("PASSWORD_HASH")
+	if passwordHash == "" {
+		passwordHash = "bcrypt"
+	}
+	loginMaxFailures, loginLockout := parseLoginLimits()
+	// без явного development включаем всё безопасное: ключи обязательны, куки Secure
+	env := os.Getenv("APP_ENV")
+	if env == "" {
+		env = "production"
+	}
+	if env != "development" && env != "production" {
+		panic("Incorrect app env from env")
+	}
 	return Config{
-		Port:     port,
-		DbAddr:   dbAddr,
-		LogLevel: logLevelInt,
+		Env:              env,
+		Port:             port,
+		DbAddr:           dbAddr,
+		LogLevel:         logLevelInt,
+		PasswordHash:     passwordHash,
+		LoginMaxFailures: loginMaxFailures,
+		LoginLockout:     loginLockout,
+		Auth:             parseAuth(env == "production"),
+		Validation:       parseValidation(),
+	}
+}
+func parseValidation() Validation {
+	charset := os.Getenv("USERNAME_CHARSET")
+	if charset == "" {
+		charset = "A-Za-z0-9_.-"
+	}
+	if _, err := regexp.Compile("^[" + charset + "]+$"); err != nil {
+		panic("Incorrect username charset from env")
+	}
+	require := os.Getenv("PASSWORD_REQUIRE")
+	if require == "" {
+		require = "letter,digit"
+	}
+	var classes []string
+	for _, class := range strings.Split(require, ",") {
+		class = strings.TrimSpace(class)
+		switch class {
+		case "none":
+			continue
+		case "lower", "upper", "letter", "digit", "symbol":
+			classes = append(classes, class)
+		default:
+			panic("Incorrect password require from env")
+		}
+	}
+	v := Validation{
+		UsernameMinLength:     parseInt("USERNAME_MIN_LENGTH", 4),
+		UsernameMaxLength:     parseInt("USERNAME_MAX_LENGTH", 32),
+		UsernameCharset:       charset,
+		PasswordMinLength:     parseInt("PASSWORD_MIN_LENGTH", 8),
+		PasswordMaxLength:     parseInt("PASSWORD_MAX_LENGTH", 72),
+		PasswordRequire:       classes,
+		BreachedPasswordsFile: os.Getenv("BREACHED_PASSWORDS_FILE"),
+	}
+	if v.UsernameMinLength < 1 || v.UsernameMaxLength < v.UsernameMinLength || v.UsernameMaxLength > 255 {
+		panic("Incorrect username length limits from env")
+	}
+	if v.PasswordMinLength < 1 || v.PasswordMaxLength < v.PasswordMinLength {
+		panic("Incorrect password length limits from env")
+	}
+	return v
+}
+func parseInt(env string, def int) int {
+	value := os.Getenv(env)
+	if value == "" {
+		return def
+	}
+	i, err := strconv.Atoi(value)
+	if err != nil {
+		panic("Incorrect " + env + " from env")
+	}
+	return i
+}
+func parseLoginLimits() (int, time.Duration) {
+	maxFailures := os.Getenv("LOGIN_MAX_FAILURES")
+	if maxFailures == "" {
+		maxFailures = "5"
+	}
+	lockout := os.Getenv("LOGIN_LOCKOUT")
+	if lockout == "" {
+		lockout = "15m"
+	}
+	maxFailuresInt, err := strconv.Atoi(maxFailures)
+	if err != nil || maxFailuresInt < 1 {
+		panic("Incorrect login max failures from env")
+	}
+	lockoutDuration, err := time.ParseDuration(lockout)
+	if err != nil || lockoutDuration <= 0 {
+		panic("Incorrect login lockout from env")
+	}
+	return maxFailuresInt, lockoutDuration
+}
+func parseAuth(production bool) Auth {
+	issuer := os.Getenv("JWT_ISSUER")
+	if issuer == "" {
+		issuer = "backprogeng"
+	}
+	audience := os.Getenv("JWT_AUDIENCE")
+	if audience == "" {
+		audience = "backprogeng-api"
+	}
+	signingKeys, signingKID := parseSigningKeys()
+	legacyUntil, err := parseLegacyUntil()
+	if err != nil {
+		panic(err.Error())
+	}
+	revocationStore := os.Getenv("REVOCATION_STORE")
+	if revocationStore == "" {
+		revocationStore = "postgres"
+	}
+	if revocationStore != "postgres" && revocationStore != "memory" {
+		panic("Incorrect revocation store from env")
+	}
+	return Auth{
+		Issuer:            issuer,
+		Audience:          audience,
+		LegacyTokensUntil: legacyUntil,
+		AccessTTL:         parseTTL("ACCESS_TOKEN_TTL", "15m"),
+		RefreshTTL:        parseTTL("REFRESH_TOKEN_TTL", "720h"),
+		RevocationStore:   revocationStore,
+		Secret:            os.Getenv("SECRET"),
+		SigningKeys:       signingKeys,
+		SigningKID:        signingKID,
+		TokenSources:      parseTokenSources(),
+		Cookie:            parseCookie(production),
+	}
+}
+
+// parseLegacyUntil читает JWT_LEGACY_UNTIL в RFC3339. Граница фиксированная,
+// а не отсчёт от запуска, иначе каждый деплой продлевал бы приём старых токенов.
+// Без неё старые токены не принимаются, новому деплою она не нужна
+func parseLegacyUntil() (time.Time, error) {
+	env := os.Getenv("JWT_LEGACY_UNTIL")
+	if env == "" {
+		return time.Time{}, nil
+	}
+	until, err := time.Parse(time.RFC3339, env)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("incorrect JWT_LEGACY_UNTIL from env: %w", err)
+	}
+	return until, nil
+}
+func parseTokenSources() []string {
+	env := os.Getenv("TOKEN_SOURCES")
+	if env == "" {
+		env = "header,cookie"
+	}
+	var sources []string
+	for _, source := range strings.Split(env, ",") {
+		source = strings.TrimSpace(source)
+		if source != "header" && source != "cookie" {
+			panic("Incorrect token sources from env")
+		}
+		sources = append(sources, source)
+	}
+	return sources
+}
+
+// parseCookie по умолчанию ставит Secure только в production,
+// чтобы локально куки работали по http
+func parseCookie(production bool) Cookie {
+	path := os.Getenv("COOKIE_PATH")
+	if path == "" {
+		path = "/"
+	}
+	secure := parseBool("COOKIE_SECURE", production)
+	httpOnly := parseBool("COOKIE_HTTP_ONLY", true)
+	var sameSite http.SameSite
+	switch strings.ToLower(os.Getenv("COOKIE_SAME_SITE")) {
+	case "", "lax":
+		sameSite = http.SameSiteLaxMode
+	case "strict":
+		sameSite = http.SameSiteStrictMode
+	case "none":
+		sameSite = http.SameSiteNoneMode
+	default:
+		panic("Incorrect cookie same site from env")
+	}
+	if sameSite == http.SameSiteNoneMode && !secure {
+		panic("Cookie same site none requires secure cookie")
+	}
+	return Cookie{
+		Path:     path,
+		Secure:   secure,
+		HttpOnly: httpOnly,
+		SameSite: sameSite,
+	}
+}
+func parseBool(env string, def bool) bool {
+	value := os.Getenv(env)
+	if value == "" {
+		return def
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		panic("Incorrect " + env + " from env")
+	}
+	return b
+}
+
+// parseSigningKeys читает JWT_SIGNING_KEYS вида kid1=/keys/a.pem,kid2=/keys/b.pem
+// и JWT_SIGNING_KID, который можно не задавать, если ключ один
+func parseSigningKeys() (map[string]string, string) {
+	keys := map[string]string{}
+	if env := os.Getenv("JWT_SIGNING_KEYS"); env != "" {
+		for _, pair := range strings.Split(env, ",") {
+			kid, path, ok := strings.Cut(strings.TrimSpace(pair), "=")
+			if !ok || kid == "" || path == "" {
+				panic("Incorrect jwt signing keys from env")
+			}
+			keys[kid] = path
+		}
+	}
+	kid := os.Getenv("JWT_SIGNING_KID")
+	if kid == "" && len(keys) == 1 {
+		for k := range keys {
+			kid = k
+		}
+	}
+	if _, ok := keys[kid]; len(keys) > 0 && !ok {
+		panic("Incorrect jwt signing kid from env")
+	}
+	return keys, kid
+}
+func parseTTL(env string, def string) time.Duration {
+	ttl := os.Getenv(env)
+	if ttl == "" {
+		ttl = def
+	}
+	ttlDuration, err := time.ParseDuration(ttl)
+	if err != nil || ttlDuration <= 0 {
+		panic("Incorrect " + env + " from env")
 	}
+	return ttlDuration
 }
 func parseDbAddr() string {
 	dbHost := os.Getenv("DB_HOST")
@@ -0,0 +1,40 @@
+package dblib
+
+import (
+	"context"
+
+	"github.com/SapolovichSV/backprogeng/internal/errlib"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// DBTX is implemented by both *pgxpool.Pool and pgx.Tx,
+// so the same queries can run inside or outside of a transaction
+type DBTX interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// TxBeginner starts transactions, *pgxpool.Pool implements it
+type TxBeginner interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+// WithTx runs fn as a single unit of work: the transaction is committed
+// if fn returns nil and rolled back if fn returns an error or panics
+func WithTx(ctx context.Context, db TxBeginner, fn func(tx DBTX) error) error {
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return errlib.WrapError(err, "transaction", "transaction can't be started")
+	}
+	// после Commit откат ничего не делает
+	defer tx.Rollback(ctx)
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return errlib.WrapError(err, "transaction", "transaction can't be committed")
+	}
+	return nil
+}
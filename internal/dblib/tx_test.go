@@ -0,0 +1,50 @@
+package dblib
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func TestWithTx(t *testing.T) {
+	db, err := pgxpool.New(context.TODO(), "host=localhost user=username password=password dbname=dbname sslmode=disable")
+	if err != nil {
+		t.Fatalf("Failed to connect to the database: %v", err)
+	}
+	defer db.Close()
+	_, err = db.Exec(context.TODO(), `CREATE TABLE tx_test (id INT PRIMARY KEY);`)
+	defer db.Exec(context.TODO(), `DROP TABLE tx_test;`)
+	if err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	ctx := context.Background()
+
+	err = WithTx(ctx, db, func(tx DBTX) error {
+		_, err := tx.Exec(ctx, `INSERT INTO tx_test (id) VALUES (1);`)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Failed to commit transaction: %v", err)
+	}
+
+	errFailed := errors.New("failed")
+	err = WithTx(ctx, db, func(tx DBTX) error {
+		if _, err := tx.Exec(ctx, `INSERT INTO tx_test (id) VALUES (2);`); err != nil {
+			return err
+		}
+		return errFailed
+	})
+	if err != errFailed {
+		t.Fatalf("Expected error of fn, got %v", err)
+	}
+
+	var count int
+	if err := db.QueryRow(ctx, `SELECT count(*) FROM tx_test;`).Scan(&count); err != nil {
+		t.Fatalf("Failed to count rows: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("Rolled back row was saved: got %d rows, want 1", count)
+	}
+}
@@ -3,10 +3,15 @@ package controller
 import (
 	"context"
 	"fmt"
+	"io"
 	"net/http"
 	"strconv"
+	"strings"
 
+	"github.com/SapolovichSV/backprogeng/internal/authmiddleware"
 	"github.com/SapolovichSV/backprogeng/internal/drink/entities"
+	"github.com/SapolovichSV/backprogeng/internal/errlib"
+	"github.com/SapolovichSV/backprogeng/internal/pagination"
 	"github.com/labstack/echo/v4"
 )
 
@@ -14,99 +19,200 @@ type storage interface {
 	CreateDrink(context.Context, entities.Drink) (entities.Drink, error)
 	UpdateDrink(context.Context, entities.Drink) (entities.Drink, error)
 	DeleteDrink(ctx context.Context, name string) error
-	DrinksByTags(ctx context.Context, tag []string) ([]entities.Drink, error)
-	AllDrinks(ctx context.Context, id int) ([]entities.Drink, error)
+	DrinksByTags(ctx context.Context, tag []string, page pagination.Request) (pagination.Page[entities.Drink], error)
+	DrinksByTagQuery(ctx context.Context, tq entities.TagQuery, page pagination.Request) (pagination.Page[entities.Drink], error)
+	AllDrinks(ctx context.Context, page pagination.Request) (pagination.Page[entities.Drink], error)
 	DrinkByName(ctx context.Context, name string) (entities.Drink, error)
+	DrinkByID(ctx context.Context, id int) (entities.Drink, error)
+	ReplaceDrink(ctx context.Context, id int, dCont entities.Drink) (entities.Drink, error)
+	SearchDrinks(ctx context.Context, text string, limit int) ([]entities.Drink, error)
 }
 
-var ErrNotFound = fmt.Errorf("not found")
+// inputValidator is implemented by validate.Validator
+type inputValidator interface {
+	Drink(drink entities.Drink) error
+}
 
 type httpHandler struct {
-	st   storage
-	echo *echo.Echo
-	ctx  context.Context
+	st        storage
+	echo      *echo.Echo
+	ctx       context.Context
+	validator inputValidator
 }
 
-func New(st storage, ctx context.Context) *httpHandler {
+func New(st storage, validator inputValidator, ctx context.Context) *httpHandler {
 	echo := echo.New()
 	return &httpHandler{
-		st:   st,
-		echo: echo,
-		ctx:  ctx,
+		st:        st,
+		echo:      echo,
+		ctx:       ctx,
+		validator: validator,
 	}
 }
 
-// BuildRouter is a method that creates a new router group in the echo instance
-// Example usage:
-// h.BuildRouter("/api")
-// This will create a new router group in the echo instance with the prefix /api
-
-// AddRoutes is a method that adds the routes to the router
+// AddRoutes is a method that adds the routes to the group
 // Example usage:
-// h.AddRoutes(router)
-// This will add the routes to the router
+// h.AddRoutes(server.Group("/api"), auth.Middleware)
+// This will add the routes to the group,
+// catalog changes are available only to admins
 // The routes are:
-// POST /{{pathRoutesName}}/drink
+// POST /{{group prefix}}/drink
 // and e.t.c
-func (h *httpHandler) AddRoutes(pathRoutesName string, router *echo.Router) {
-	router.Add("POST", "/"+pathRoutesName+"/drink", h.createDrink)
-	//router.POST("/drink", h.createDrink)
-	router.Add("PUT", "/"+pathRoutesName+"/drink", h.updateDrink)
-	//router.PUT("/drink", h.updateDrink)
-	router.Add("DELETE", "/"+pathRoutesName+"/drink/:name", h.deleteDrink)
-	//router.DELETE("/drink/:name", h.deleteDrink)
-	router.Add("GET", "/"+pathRoutesName+"/drink/tag/:tag", h.drinksByTags)
-	//router.GET("/drink/tag/:tag", h.drinksByTags)
-	router.Add("GET", "/"+pathRoutesName+"/drink/id/:id", h.allDrinks)
-
-	//router.GET("/drink/id/:id", h.allDrinks)
-	router.Add("GET", "/"+pathRoutesName+"/drink/name/:name", h.drinkByName)
-	//router.GET("/drink/name/:name", h.drinkByName)
+func (h *httpHandler) AddRoutes(group *echo.Group, authenticate echo.MiddlewareFunc) {
+	drinks := group.Group("/drink")
+	drinks.GET("/tag/:tag", h.drinksByTags)
+	drinks.GET("/tags", h.drinksByTagQuery)
+	drinks.GET("/search", h.searchDrinks)
+	drinks.GET("", h.allDrinks)
+	drinks.GET("/name/:name", h.drinkByName)
+
+	// middleware вешаем на маршруты, а не на вторую группу /drink:
+	// группа с middleware отвечала бы 401 вместо 404 на любой неизвестный путь
+	admin := authmiddleware.RequireRole(authmiddleware.RoleAdmin)
+	drinks.POST("", h.createDrink, authenticate, admin)
+	drinks.PUT("", h.updateDrink, authenticate, admin)
+	drinks.PUT("/:id", h.replaceDrink, authenticate, admin)
+	drinks.PATCH("/:id", h.patchDrink, authenticate, admin)
+	drinks.DELETE("/:name", h.deleteDrink, authenticate, admin)
 }
 
 // createDrink godoc
 //
 //		@Summary Creates a drink
-//		@Description Creates a drink with the specified name and tags
+//		@Description Creates a drink with the specified name, tags and recipe
 //		@Tags drink
 //		@Accept json
 //		@Produce json
 //		@Success 201 {object} entities.Drink
-//	 	@Failure 500 {string} string
-//		@Param drink body entities.Drink true "Drink what we add with optional tags,if tags not: set tags will be empty, name is required,"
+//		@Failure 400 {object} errlib.Response
+//		@Failure 401 {object} errlib.Response "Not authenticated"
+//		@Failure 403 {object} errlib.Response "Not admin"
+//		@Failure 409 {object} errlib.Response "Name is taken by another drink"
+//	 	@Failure 500 {object} errlib.Response
+//		@Param drink body entities.Drink true "Drink what we add with optional tags and recipe,if tags not: set tags will be empty, name is required,"
 //		@Router /drink [post]
 func (h *httpHandler) createDrink(c echo.Context) error {
 
 	var drink entities.Drink
 	if err := c.Bind(&drink); err != nil {
-		return c.JSON(400, err.Error())
+		return err
+	}
+	if err := h.validator.Drink(drink); err != nil {
+		return err
 	}
 	d, err := h.st.CreateDrink(h.ctx, drink)
 	if err != nil {
-		return c.JSON(500, err.Error())
+		return err
 	}
 	return c.JSON(http.StatusCreated, d)
 }
 
 // updateDrink godoc
 //
-//		@Summary Updates drink tags
-//		@Description Updates drink tags with the specified name(old tags will be deleted)
+//		@Summary Updates drink
+//		@Description Updates drink tags and recipe with the specified name(old tags and ingredients will be deleted)
 //		@Tags drink
 //		@Accept json
 //		@Produce json
 //		@Success 200 {object} entities.Drink
-//		@Failure 500 {string} string
+//		@Failure 400 {object} errlib.Response
+//		@Failure 401 {object} errlib.Response "Not authenticated"
+//		@Failure 403 {object} errlib.Response "Not admin"
+//		@Failure 404 {object} errlib.Response
+//		@Failure 500 {object} errlib.Response
 //		@Param drink body entities.Drink true "Drink what we update with optional tags,if tags not: set tags will be empty, name is required,"
 //	 @Router /drink [put]
 func (h *httpHandler) updateDrink(c echo.Context) error {
 	var drink entities.Drink
 	if err := c.Bind(&drink); err != nil {
-		return c.JSON(400, err.Error())
+		return err
+	}
+	if err := h.validator.Drink(drink); err != nil {
+		return err
 	}
 	d, err := h.st.UpdateDrink(h.ctx, drink)
 	if err != nil {
-		return c.JSON(500, err.Error())
+		return err
+	}
+	return c.JSON(200, d)
+}
+
+// replaceDrink godoc
+//
+//	@Summary Replaces drink
+//	@Description Replaces all fields of the drink with the specified id, name can be changed too.
+//	@Description Tags and ingredients which aren't passed will be removed
+//	@Tags drink
+//	@Accept json
+//	@Produce json
+//	@Success 200 {object} entities.Drink
+//	@Failure 400 {object} errlib.Response
+//	@Failure 401 {object} errlib.Response "Not authenticated"
+//	@Failure 403 {object} errlib.Response "Not admin"
+//	@Failure 404 {object} errlib.Response "Not found"
+//	@Failure 409 {object} errlib.Response "Name is taken by another drink"
+//	@Failure 500 {object} errlib.Response
+//	@Param id path int true "Drink id"
+//	@Param drink body entities.Drink true "New drink, name is required, id is ignored"
+//	@Router /drink/{id} [put]
+func (h *httpHandler) replaceDrink(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return errlib.InvalidInputErr{What: "id", Reason: "must be a number"}
+	}
+	var drink entities.Drink
+	if err := c.Bind(&drink); err != nil {
+		return err
+	}
+	return h.replace(c, id, drink)
+}
+
+// patchDrink godoc
+//
+//	@Summary Partially updates drink
+//	@Description Updates drink with the specified id by JSON merge patch (RFC 7386):
+//	@Description only passed fields are changed, null resets the field, tags and ingredients are replaced entirely
+//	@Tags drink
+//	@Accept json
+//	@Produce json
+//	@Success 200 {object} entities.Drink
+//	@Failure 400 {object} errlib.Response
+//	@Failure 401 {object} errlib.Response "Not authenticated"
+//	@Failure 403 {object} errlib.Response "Not admin"
+//	@Failure 404 {object} errlib.Response "Not found"
+//	@Failure 409 {object} errlib.Response "Name is taken by another drink"
+//	@Failure 500 {object} errlib.Response
+//	@Param id path int true "Drink id"
+//	@Param patch body entities.Drink true "Only fields to change, e.g. new name and tags"
+//	@Router /drink/{id} [patch]
+func (h *httpHandler) patchDrink(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return errlib.InvalidInputErr{What: "id", Reason: "must be a number"}
+	}
+	patch, err := io.ReadAll(c.Request().Body)
+	if err != nil {
+		return errlib.InvalidInputErr{What: "patch", Reason: "is invalid: " + err.Error()}
+	}
+	old, err := h.st.DrinkByID(h.ctx, id)
+	if err != nil {
+		return err
+	}
+	drink, err := old.MergePatch(patch)
+	if err != nil {
+		return errlib.InvalidInputErr{What: "patch", Reason: "is invalid: " + err.Error()}
+	}
+	return h.replace(c, id, drink)
+}
+
+// replace проверяет напиток и сохраняет его под данным id
+func (h *httpHandler) replace(c echo.Context, id int, drink entities.Drink) error {
+	if err := h.validator.Drink(drink); err != nil {
+		return err
+	}
+	d, err := h.st.ReplaceDrink(h.ctx, id, drink)
+	if err != nil {
+		return err
 	}
 	return c.JSON(200, d)
 }
@@ -119,17 +225,17 @@ func (h *httpHandler) updateDrink(c echo.Context) error {
 //	 @Accept plain
 //		@Produce json
 //		@Success 200 {string} deleted
-//		@Failure 404 {string} string
-//		@Failure 500 {string} string
+//		@Failure 401 {object} errlib.Response "Not authenticated"
+//		@Failure 403 {object} errlib.Response "Not admin"
+//		@Failure 404 {object} errlib.Response
+//		@Failure 500 {object} errlib.Response
 //		@Param name	path string	true "Name of the drink to delete"
 //		@Router /drink/{name} [delete]
 func (h *httpHandler) deleteDrink(c echo.Context) error {
 	name := c.Param("name")
 	err := h.st.DeleteDrink(h.ctx, name)
-	if err == ErrNotFound {
-		return c.JSON(404, echo.ErrNotFound.Error())
-	} else if err != nil {
-		return c.JSON(500, err.Error())
+	if err != nil {
+		return err
 	}
 	return c.JSON(200, "deleted")
 }
@@ -142,45 +248,108 @@ func (h *httpHandler) deleteDrink(c echo.Context) error {
 //
 // @Accept plain
 // @Produce json
-// @Success 200 {array} entities.Drink
-// @Failure 404 {string} string "Not found"
-// @Failure 500 {string} string "Internal server error"
+// @Success 200 {object} pagination.Page[entities.Drink]
+// @Failure 400 {object} errlib.Response "Bad limit or cursor"
+// @Failure 404 {object} errlib.Response "Not found"
+// @Failure 500 {object} errlib.Response "Internal server error"
 // @Param tag path string true "tasty sweet spicy"
+// @Param limit query int false "page size, 20 by default, max 100"
+// @Param cursor query string false "next_cursor from the previous page"
 // @Router /drink/tag/{tag} [get]
 func (h *httpHandler) drinksByTags(c echo.Context) error {
 	tag := c.Param("tag")
-	d, err := h.st.DrinksByTags(h.ctx, []string{tag})
-	if err == ErrNotFound {
-		return c.JSON(404, echo.ErrNotFound.Error())
-	} else if err != nil {
-		return c.JSON(500, err.Error())
+	page, err := pagination.Parse(c.QueryParam("limit"), c.QueryParam("cursor"))
+	if err != nil {
+		return err
+	}
+	d, err := h.st.DrinksByTags(h.ctx, []string{tag}, page)
+	if err != nil {
+		return err
+	}
+	return c.JSON(200, d)
+}
+
+// drinksByTagQuery godoc
+// @Summary Search drinks by several tags
+// @Description Search drinks by tags with modes: drink must have all tags from "all",
+// @Description at least one tag from "any" and no tags from "none".
+// @Description Every param can be repeated or contain comma separated tags, matching is exact and case-insensitive
+// @Description Example: /drink/tags?all=Sweet,Girly&none=Soft
+//
+//	@Tags drink
+//
+// @Accept plain
+// @Produce json
+// @Success 200 {object} pagination.Page[entities.Drink]
+// @Failure 400 {object} errlib.Response "No tags in query or bad limit or cursor"
+// @Failure 404 {object} errlib.Response "Not found"
+// @Failure 500 {object} errlib.Response "Internal server error"
+// @Param all query string false "drink has all of these tags"
+// @Param any query string false "drink has at least one of these tags"
+// @Param none query string false "drink has none of these tags"
+// @Param limit query int false "page size, 20 by default, max 100"
+// @Param cursor query string false "next_cursor from the previous page"
+// @Router /drink/tags [get]
+func (h *httpHandler) drinksByTagQuery(c echo.Context) error {
+	params := c.QueryParams()
+	tq := entities.TagQuery{
+		All:  splitTagsParam(params["all"]),
+		Any:  splitTagsParam(params["any"]),
+		None: splitTagsParam(params["none"]),
+	}
+	if tq.IsEmpty() {
+		return errlib.InvalidInputErr{What: "tags", Reason: "must be set in at least one of all, any, none"}
+	}
+	page, err := pagination.Parse(c.QueryParam("limit"), c.QueryParam("cursor"))
+	if err != nil {
+		return err
+	}
+	d, err := h.st.DrinksByTagQuery(h.ctx, tq, page)
+	if err != nil {
+		return err
 	}
 	return c.JSON(200, d)
 }
 
+// splitTagsParam собирает теги из повторяющегося query параметра,
+// каждое значение которого может содержать несколько тегов через запятую
+func splitTagsParam(values []string) []string {
+	var tags []string
+	for _, v := range values {
+		for _, tag := range strings.Split(v, ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				tags = append(tags, tag)
+			}
+		}
+	}
+	return tags
+}
+
 // allDrinks godoc
 // @Summary Get all drinks
-// @Description Get all drinks with offset = id
+// @Description Get all drinks page by page in id order.
+// @Description To get the next page pass next_cursor of the current page as cursor,
+// @Description there are no more drinks when next_cursor is absent
 //
 //	@Tags drink
 //
 // @Accept plain
 // @Produce json
-// @Success 200 {array} entities.Drink
-// @Failure 500 {string} string "Internal server error"
-// @Param id path int true "id"
-// @Router /drink/id/{id} [get]
+// @Success 200 {object} pagination.Page[entities.Drink]
+// @Failure 400 {object} errlib.Response "Bad limit or cursor"
+// @Failure 500 {object} errlib.Response "Internal server error"
+// @Param limit query int false "page size, 20 by default, max 100"
+// @Param cursor query string false "next_cursor from the previous page"
+// @Router /drink [get]
 func (h *httpHandler) allDrinks(c echo.Context) error {
-	param := c.Param("id")
-	id, err := strconv.Atoi(param)
+	page, err := pagination.Parse(c.QueryParam("limit"), c.QueryParam("cursor"))
 	if err != nil {
-		fmt.Println(err)
-		return c.JSON(500, err.Error())
+		return err
 	}
-	d, err := h.st.AllDrinks(h.ctx, id)
+	d, err := h.st.AllDrinks(h.ctx, page)
 	if err != nil {
 		fmt.Println(err.Error() + "at storage")
-		return c.JSON(500, err.Error())
+		return err
 	}
 	return c.JSON(200, d)
 }
@@ -194,17 +363,46 @@ func (h *httpHandler) allDrinks(c echo.Context) error {
 // @Accept plain
 // @Produce json
 // @Success 200 {object} entities.Drink
-// @Failure 404 {string} string "Not found"
-// @Failure 500 {string} string "Internal server error"
+// @Failure 404 {object} errlib.Response "Not found"
+// @Failure 500 {object} errlib.Response "Internal server error"
 // @Param name path string true "Name of the drink"
 // @Router /drink/name/{name} [get]
 func (h *httpHandler) drinkByName(c echo.Context) error {
 	name := c.Param("name")
 	d, err := h.st.DrinkByName(h.ctx, name)
-	if err == ErrNotFound {
-		return c.JSON(404, echo.ErrNotFound.Error())
-	} else if err != nil {
-		return c.JSON(500, err.Error())
+	if err != nil {
+		return err
+	}
+	return c.JSON(200, d)
+}
+
+// searchDrinks godoc
+// @Summary Search drinks by name
+// @Description Search drinks by name and tags with prefix, typo-tolerant and full-text matching,
+// @Description drinks are ordered by relevance: "blu fary" finds "Blue Fairy"
+//
+//	@Tags drink
+//
+// @Accept plain
+// @Produce json
+// @Success 200 {array} entities.Drink
+// @Failure 400 {object} errlib.Response "Empty query or bad limit"
+// @Failure 500 {object} errlib.Response "Internal server error"
+// @Param q query string true "search text"
+// @Param limit query int false "max count of drinks, 20 by default, max 100"
+// @Router /drink/search [get]
+func (h *httpHandler) searchDrinks(c echo.Context) error {
+	text := strings.TrimSpace(c.QueryParam("q"))
+	if text == "" {
+		return errlib.InvalidInputErr{What: "q", Reason: "must be set"}
+	}
+	page, err := pagination.Parse(c.QueryParam("limit"), "")
+	if err != nil {
+		return err
+	}
+	d, err := h.st.SearchDrinks(h.ctx, text, page.Limit)
+	if err != nil {
+		return err
 	}
 	return c.JSON(200, d)
 }
@@ -1,13 +1,21 @@
 package controller
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
 
+	"github.com/SapolovichSV/backprogeng/internal/authmiddleware"
+	"github.com/SapolovichSV/backprogeng/internal/config"
 	"github.com/SapolovichSV/backprogeng/internal/drink/entities"
+	"github.com/SapolovichSV/backprogeng/internal/errlib"
+	httpinfra "github.com/SapolovichSV/backprogeng/internal/http_infra"
+	"github.com/SapolovichSV/backprogeng/internal/pagination"
+	"github.com/SapolovichSV/backprogeng/internal/validate"
 	mocks "github.com/SapolovichSV/backprogeng/mocks/drink"
 	"github.com/labstack/echo/v4"
 	"github.com/stretchr/testify/assert"
@@ -52,12 +60,60 @@ func Test_httpHandler_createDrink(t *testing.T) {
 			hasRespBody:  true,
 			expectedCode: http.StatusCreated,
 		},
+		{
+			name:   "recipe",
+			method: http.MethodPost,
+			path:   "/drink",
+			reqBody: entities.Drink{
+				Name:        "Bad Touch",
+				Price:       250,
+				Alcoholic:   true,
+				Preparation: entities.PreparationIced,
+				Ingredients: []entities.Ingredient{{Name: "Karmotrine", Amount: 4}},
+			},
+			respBody: entities.Drink{
+				ID:          3,
+				Name:        "Bad Touch",
+				Price:       250,
+				Alcoholic:   true,
+				Preparation: entities.PreparationIced,
+				Ingredients: []entities.Ingredient{{Name: "Karmotrine", Amount: 4}},
+			},
+			hasRespBody:  true,
+			expectedCode: http.StatusCreated,
+		},
+		{
+			name:         "bad_preparation",
+			method:       http.MethodPost,
+			path:         "/drink",
+			reqBody:      entities.Drink{Name: "test04", Preparation: "shaken"},
+			hasRespBody:  false,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "every_field_invalid",
+			method:       http.MethodPost,
+			path:         "/drink",
+			reqBody:      entities.Drink{Name: " ", Price: -1, Tags: []string{"sour", "Sour"}},
+			hasRespBody:  false,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "name_taken",
+			method:       http.MethodPost,
+			path:         "/drink",
+			reqBody:      entities.Drink{Name: "bad touch"},
+			hasRespBody:  false,
+			expectedCode: http.StatusConflict,
+		},
 	}
 
 	mockStorage.EXPECT().CreateDrink(gomock.Any(), ts[0].reqBody).Return(ts[0].respBody, nil)
 	mockStorage.EXPECT().CreateDrink(gomock.Any(), ts[1].reqBody).Return(ts[1].respBody, nil)
+	mockStorage.EXPECT().CreateDrink(gomock.Any(), ts[2].reqBody).Return(ts[2].respBody, nil)
+	mockStorage.EXPECT().CreateDrink(gomock.Any(), ts[5].reqBody).Return(entities.Drink{}, errlib.ConflictErr{Where: "drinks", What: "drink name"})
 
-	h := &httpHandler{mockStorage, nil, nil}
+	h := &httpHandler{mockStorage, nil, nil, newTestValidator(t)}
 
 	for _, v := range ts {
 
@@ -70,12 +126,11 @@ func Test_httpHandler_createDrink(t *testing.T) {
 		c := e.NewContext(req, rec)
 		c.SetPath(v.path)
 
-		if assert.NoError(t, h.createDrink(c)) {
-			assert.Equal(t, v.expectedCode, rec.Code)
-			if v.hasRespBody {
-				resData, _ := json.Marshal(&v.respBody)
-				assert.JSONEq(t, string(resData), rec.Body.String())
-			}
+		serve(h.createDrink, c)
+		assert.Equal(t, v.expectedCode, rec.Code)
+		if v.hasRespBody {
+			resData, _ := json.Marshal(&v.respBody)
+			assert.JSONEq(t, string(resData), rec.Body.String())
 		}
 	}
 }
@@ -123,7 +178,7 @@ func Test_httpHandler_updateDrink(t *testing.T) {
 	mockStorage.EXPECT().UpdateDrink(gomock.Any(), ts[0].reqBody).Return(ts[0].respBody, nil)
 	mockStorage.EXPECT().UpdateDrink(gomock.Any(), ts[1].reqBody).Return(ts[1].respBody, nil)
 
-	h := &httpHandler{mockStorage, nil, nil}
+	h := &httpHandler{mockStorage, nil, nil, newTestValidator(t)}
 
 	for _, v := range ts {
 
@@ -136,16 +191,163 @@ func Test_httpHandler_updateDrink(t *testing.T) {
 		c := e.NewContext(req, rec)
 		c.SetPath(v.path)
 
-		if assert.NoError(t, h.updateDrink(c)) {
-			assert.Equal(t, v.expectedCode, rec.Code)
-			if v.hasRespBody {
-				resData, _ := json.Marshal(&v.respBody)
-				assert.JSONEq(t, string(resData), rec.Body.String())
-			}
+		serve(h.updateDrink, c)
+		assert.Equal(t, v.expectedCode, rec.Code)
+		if v.hasRespBody {
+			resData, _ := json.Marshal(&v.respBody)
+			assert.JSONEq(t, string(resData), rec.Body.String())
+		}
+	}
+}
+
+func Test_httpHandler_replaceDrink(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mocks.NewMockDrinkModel(ctrl)
+	type TestCase struct {
+		name         string
+		id           string
+		reqBody      entities.Drink
+		respBody     entities.Drink
+		hasRespBody  bool
+		expectedCode int
+	}
+	ts := []TestCase{
+		{
+			name:         "rename",
+			id:           "1",
+			reqBody:      entities.Drink{Name: "Blue Fairy", Tags: []string{"sweet"}},
+			respBody:     entities.Drink{ID: 1, Name: "Blue Fairy", Tags: []string{"sweet"}},
+			hasRespBody:  true,
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "not_found",
+			id:           "2",
+			reqBody:      entities.Drink{Name: "Missing"},
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "name_taken",
+			id:           "3",
+			reqBody:      entities.Drink{Name: "Bad Touch"},
+			expectedCode: http.StatusConflict,
+		},
+		{
+			name:         "bad_id",
+			id:           "abc",
+			reqBody:      entities.Drink{Name: "test"},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "no_name",
+			id:           "4",
+			reqBody:      entities.Drink{Tags: []string{"sweet"}},
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	mockStorage.EXPECT().ReplaceDrink(gomock.Any(), 1, ts[0].reqBody).Return(ts[0].respBody, nil)
+	mockStorage.EXPECT().ReplaceDrink(gomock.Any(), 2, ts[1].reqBody).Return(entities.Drink{}, errlib.NotFoundErr{Where: "drinks", What: "drink"})
+	mockStorage.EXPECT().ReplaceDrink(gomock.Any(), 3, ts[2].reqBody).Return(entities.Drink{}, errlib.ConflictErr{Where: "drinks", What: "drink name"})
+
+	h := &httpHandler{mockStorage, nil, nil, newTestValidator(t)}
+
+	for _, v := range ts {
+		e := echo.New()
+		reqBody, _ := json.Marshal(v.reqBody)
+		req := httptest.NewRequest(http.MethodPut, "/drink/"+v.id, strings.NewReader(string(reqBody)))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+
+		c := e.NewContext(req, rec)
+		c.SetPath("/drink/:id")
+		c.SetParamNames("id")
+		c.SetParamValues(v.id)
+
+		serve(h.replaceDrink, c)
+		assert.Equal(t, v.expectedCode, rec.Code, v.name)
+		if v.hasRespBody {
+			resData, _ := json.Marshal(&v.respBody)
+			assert.JSONEq(t, string(resData), rec.Body.String())
 		}
 	}
 }
 
+func Test_httpHandler_patchDrink(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mocks.NewMockDrinkModel(ctrl)
+	old := entities.Drink{
+		ID:          1,
+		Name:        "Blue Fary",
+		Tags:        []string{"sweet", "soft"},
+		Price:       170,
+		Alcoholic:   true,
+		Preparation: entities.PreparationAged,
+		Ingredients: []entities.Ingredient{{Name: "Adelhyde", Amount: 4}},
+	}
+	type TestCase struct {
+		name         string
+		id           string
+		patch        string
+		expectedCode int
+	}
+	ts := []TestCase{
+		{
+			name:         "rename_and_retag",
+			id:           "1",
+			patch:        `{"name": "Blue Fairy", "tags": ["sweet", "girly"], "description": null}`,
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "not_found",
+			id:           "2",
+			patch:        `{"name": "Missing"}`,
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "not_object",
+			id:           "1",
+			patch:        `["name"]`,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "reset_name",
+			id:           "1",
+			patch:        `{"name": null}`,
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	patched := old
+	patched.Name = "Blue Fairy"
+	patched.Tags = []string{"sweet", "girly"}
+
+	mockStorage.EXPECT().DrinkByID(gomock.Any(), 1).Return(old, nil).Times(3)
+	mockStorage.EXPECT().DrinkByID(gomock.Any(), 2).Return(entities.Drink{}, errlib.NotFoundErr{Where: "drinks", What: "drink"})
+	mockStorage.EXPECT().ReplaceDrink(gomock.Any(), 1, patched).Return(patched, nil)
+
+	h := &httpHandler{mockStorage, nil, nil, newTestValidator(t)}
+
+	for _, v := range ts {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPatch, "/drink/"+v.id, strings.NewReader(v.patch))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+
+		c := e.NewContext(req, rec)
+		c.SetPath("/drink/:id")
+		c.SetParamNames("id")
+		c.SetParamValues(v.id)
+
+		serve(h.patchDrink, c)
+		assert.Equal(t, v.expectedCode, rec.Code, v.name)
+	}
+}
+
 func Test_httpHandler_deleteDrink(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -192,7 +394,7 @@ func Test_httpHandler_deleteDrink(t *testing.T) {
 	mockStorage.EXPECT().DeleteDrink(gomock.Any(), "test01").Return(nil)
 	mockStorage.EXPECT().DeleteDrink(gomock.Any(), "test02").Return(nil)
 
-	h := &httpHandler{mockStorage, nil, nil}
+	h := &httpHandler{mockStorage, nil, nil, newTestValidator(t)}
 
 	for _, v := range ts {
 
@@ -207,9 +409,8 @@ func Test_httpHandler_deleteDrink(t *testing.T) {
 			c.SetParamValues(v.value)
 		}
 
-		if assert.NoError(t, h.deleteDrink(c)) {
-			assert.Equal(t, v.expectedCode, rec.Code)
-		}
+		serve(h.deleteDrink, c)
+		assert.Equal(t, v.expectedCode, rec.Code)
 	}
 }
 
@@ -226,7 +427,7 @@ func Test_httpHandler_drinksByTags(t *testing.T) {
 		name         string
 		method       string
 		path         string
-		respBody     []entities.Drink
+		respBody     pagination.Page[entities.Drink]
 		hasRespBody  bool
 		expectedCode int
 		params       []params
@@ -236,7 +437,7 @@ func Test_httpHandler_drinksByTags(t *testing.T) {
 			name:         "test01",
 			method:       http.MethodGet,
 			path:         "/drink/tag/:tag",
-			respBody:     []entities.Drink{{Name: "test01"}},
+			respBody:     pagination.Page[entities.Drink]{Items: []entities.Drink{{Name: "test01"}}},
 			hasRespBody:  true,
 			expectedCode: http.StatusOK,
 			params: []params{
@@ -250,7 +451,7 @@ func Test_httpHandler_drinksByTags(t *testing.T) {
 			name:         "test02",
 			method:       http.MethodGet,
 			path:         "/drink/tag/:tag",
-			respBody:     []entities.Drink{{Name: "test02", Tags: []string{"spicy", "non-alcohol"}}},
+			respBody:     pagination.Page[entities.Drink]{Items: []entities.Drink{{Name: "test02", Tags: []string{"spicy", "non-alcohol"}}}},
 			hasRespBody:  true,
 			expectedCode: http.StatusOK,
 			params: []params{
@@ -262,10 +463,10 @@ func Test_httpHandler_drinksByTags(t *testing.T) {
 		},
 	}
 
-	mockStorage.EXPECT().DrinksByTags(gomock.Any(), []string{"spicy"}).Return(ts[0].respBody, nil)
-	mockStorage.EXPECT().DrinksByTags(gomock.Any(), []string{"non-alcohol"}).Return(ts[1].respBody, nil)
+	mockStorage.EXPECT().DrinksByTags(gomock.Any(), []string{"spicy"}, pagination.Request{Limit: pagination.DefaultLimit}).Return(ts[0].respBody, nil)
+	mockStorage.EXPECT().DrinksByTags(gomock.Any(), []string{"non-alcohol"}, pagination.Request{Limit: pagination.DefaultLimit}).Return(ts[1].respBody, nil)
 
-	h := &httpHandler{mockStorage, nil, nil}
+	h := &httpHandler{mockStorage, nil, nil, newTestValidator(t)}
 
 	for _, v := range ts {
 
@@ -280,89 +481,159 @@ func Test_httpHandler_drinksByTags(t *testing.T) {
 			c.SetParamValues(v.value)
 		}
 
-		if assert.NoError(t, h.drinksByTags(c)) {
-			assert.Equal(t, v.expectedCode, rec.Code)
-			if v.hasRespBody {
-				resData, _ := json.Marshal(&v.respBody)
-				assert.JSONEq(t, string(resData), rec.Body.String())
-			}
+		serve(h.drinksByTags, c)
+		assert.Equal(t, v.expectedCode, rec.Code)
+		if v.hasRespBody {
+			resData, _ := json.Marshal(&v.respBody)
+			assert.JSONEq(t, string(resData), rec.Body.String())
 		}
 	}
 }
 
-func Test_httpHandler_allDrinks(t *testing.T) {
+func Test_httpHandler_drinksByTagQuery(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
 	mockStorage := mocks.NewMockDrinkModel(ctrl)
-	type params struct {
-		name  string
-		value string
-	}
 	type TestCase struct {
 		name         string
 		method       string
 		path         string
-		respBody     []entities.Drink
+		query        string
+		respBody     pagination.Page[entities.Drink]
 		hasRespBody  bool
 		expectedCode int
-		params       []params
 	}
 	ts := []TestCase{
 		{
-			name:         "test01",
+			name:         "all_and_none",
 			method:       http.MethodGet,
-			path:         "/drink/id/:id",
-			respBody:     []entities.Drink{{Name: "test01"}},
+			path:         "/drink/tags",
+			query:        "?all=Sweet,Girly&none=Soft",
+			respBody:     pagination.Page[entities.Drink]{Items: []entities.Drink{{Name: "Moonblast", Tags: []string{"Girly", "Happy", "Sweet"}}}},
 			hasRespBody:  true,
 			expectedCode: http.StatusOK,
-			params: []params{
-				{
-					name:  "id",
-					value: "1",
-				},
-			},
 		},
 		{
-			name:         "test02",
+			name:         "repeated_any",
 			method:       http.MethodGet,
-			path:         "/drink/id/:id",
-			respBody:     []entities.Drink{{Name: "test02", Tags: []string{"spicy", "non-alcohol"}}},
+			path:         "/drink/tags",
+			query:        "?any=Sour&any=Bitter",
+			respBody:     pagination.Page[entities.Drink]{Items: []entities.Drink{{Name: "Gut Punch", Tags: []string{"Bitter", "Manly", "Strong"}}}},
 			hasRespBody:  true,
 			expectedCode: http.StatusOK,
-			params: []params{
-				{
-					name:  "id",
-					value: "2",
-				},
-			},
+		},
+		{
+			name:         "no_tags",
+			method:       http.MethodGet,
+			path:         "/drink/tags",
+			query:        "?all=,",
+			hasRespBody:  false,
+			expectedCode: http.StatusBadRequest,
 		},
 	}
 
-	mockStorage.EXPECT().AllDrinks(gomock.Any(), 1).Return(ts[0].respBody, nil)
-	mockStorage.EXPECT().AllDrinks(gomock.Any(), 2).Return(ts[1].respBody, nil)
+	mockStorage.EXPECT().DrinksByTagQuery(gomock.Any(), entities.TagQuery{
+		All:  []string{"Sweet", "Girly"},
+		None: []string{"Soft"},
+	}, pagination.Request{Limit: pagination.DefaultLimit}).Return(ts[0].respBody, nil)
+	mockStorage.EXPECT().DrinksByTagQuery(gomock.Any(), entities.TagQuery{
+		Any: []string{"Sour", "Bitter"},
+	}, pagination.Request{Limit: pagination.DefaultLimit}).Return(ts[1].respBody, nil)
 
-	h := &httpHandler{mockStorage, nil, nil}
+	h := &httpHandler{mockStorage, nil, nil, newTestValidator(t)}
 
 	for _, v := range ts {
 
 		e := echo.New()
-		req := httptest.NewRequest(v.method, v.path, nil)
+		req := httptest.NewRequest(v.method, v.path+v.query, nil)
 		rec := httptest.NewRecorder()
 
 		c := e.NewContext(req, rec)
 		c.SetPath(v.path)
-		for _, v := range v.params {
-			c.SetParamNames(v.name)
-			c.SetParamValues(v.value)
+
+		serve(h.drinksByTagQuery, c)
+		assert.Equal(t, v.expectedCode, rec.Code)
+		if v.hasRespBody {
+			resData, _ := json.Marshal(&v.respBody)
+			assert.JSONEq(t, string(resData), rec.Body.String())
 		}
+	}
+}
 
-		if assert.NoError(t, h.allDrinks(c)) {
-			assert.Equal(t, v.expectedCode, rec.Code)
-			if v.hasRespBody {
-				resData, _ := json.Marshal(&v.respBody)
-				assert.JSONEq(t, string(resData), rec.Body.String())
-			}
+func Test_httpHandler_allDrinks(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mocks.NewMockDrinkModel(ctrl)
+	type TestCase struct {
+		name         string
+		method       string
+		path         string
+		query        string
+		respBody     pagination.Page[entities.Drink]
+		hasRespBody  bool
+		expectedCode int
+	}
+	ts := []TestCase{
+		{
+			name:   "first_page",
+			method: http.MethodGet,
+			path:   "/drink",
+			query:  "?limit=1",
+			respBody: pagination.Page[entities.Drink]{
+				Items:      []entities.Drink{{ID: 1, Name: "test01"}},
+				NextCursor: pagination.EncodeCursor(1),
+			},
+			hasRespBody:  true,
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:   "next_page",
+			method: http.MethodGet,
+			path:   "/drink",
+			query:  "?limit=1&cursor=" + pagination.EncodeCursor(1),
+			respBody: pagination.Page[entities.Drink]{
+				Items: []entities.Drink{{ID: 2, Name: "test02", Tags: []string{"spicy", "non-alcohol"}}},
+			},
+			hasRespBody:  true,
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "bad_limit",
+			method:       http.MethodGet,
+			path:         "/drink",
+			query:        "?limit=1000",
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "bad_cursor",
+			method:       http.MethodGet,
+			path:         "/drink",
+			query:        "?cursor=broken",
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	mockStorage.EXPECT().AllDrinks(gomock.Any(), pagination.Request{Limit: 1}).Return(ts[0].respBody, nil)
+	mockStorage.EXPECT().AllDrinks(gomock.Any(), pagination.Request{Limit: 1, AfterID: 1}).Return(ts[1].respBody, nil)
+
+	h := &httpHandler{mockStorage, nil, nil, newTestValidator(t)}
+
+	for _, v := range ts {
+
+		e := echo.New()
+		req := httptest.NewRequest(v.method, v.path+v.query, nil)
+		rec := httptest.NewRecorder()
+
+		c := e.NewContext(req, rec)
+		c.SetPath(v.path)
+
+		serve(h.allDrinks, c)
+		assert.Equal(t, v.expectedCode, rec.Code)
+		if v.hasRespBody {
+			resData, _ := json.Marshal(&v.respBody)
+			assert.JSONEq(t, string(resData), rec.Body.String())
 		}
 	}
 }
@@ -422,7 +693,7 @@ func Test_httpHandler_drinkByName(t *testing.T) {
 	mockStorage.EXPECT().DrinkByName(gomock.Any(), "test01").Return(ts[0].respBody, nil)
 	mockStorage.EXPECT().DrinkByName(gomock.Any(), "test02").Return(ts[1].respBody, nil)
 
-	h := &httpHandler{mockStorage, nil, nil}
+	h := &httpHandler{mockStorage, nil, nil, newTestValidator(t)}
 
 	for _, v := range ts {
 
@@ -437,13 +708,173 @@ func Test_httpHandler_drinkByName(t *testing.T) {
 			c.SetParamValues(v.value)
 		}
 
-		if assert.NoError(t, h.drinkByName(c)) {
-			assert.Equal(t, v.expectedCode, rec.Code)
-			if v.hasRespBody {
+		serve(h.drinkByName, c)
+		assert.Equal(t, v.expectedCode, rec.Code)
+		if v.hasRespBody {
 
-				resData, _ := json.Marshal(&v.respBody)
-				assert.JSONEq(t, string(resData), rec.Body.String())
+			resData, _ := json.Marshal(&v.respBody)
+			assert.JSONEq(t, string(resData), rec.Body.String())
+		}
+	}
+}
+
+func Test_httpHandler_searchDrinks(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mocks.NewMockDrinkModel(ctrl)
+	type TestCase struct {
+		name         string
+		method       string
+		path         string
+		query        string
+		respBody     []entities.Drink
+		hasRespBody  bool
+		expectedCode int
+	}
+	ts := []TestCase{
+		{
+			name:         "typo",
+			method:       http.MethodGet,
+			path:         "/drink/search",
+			query:        "?q=blu+fary",
+			respBody:     []entities.Drink{{ID: 5, Name: "Blue Fairy", Tags: []string{"Girly", "Soft", "Sweet"}}},
+			hasRespBody:  true,
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "empty_query",
+			method:       http.MethodGet,
+			path:         "/drink/search",
+			query:        "?q=+",
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "bad_limit",
+			method:       http.MethodGet,
+			path:         "/drink/search",
+			query:        "?q=blue&limit=0",
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	mockStorage.EXPECT().SearchDrinks(gomock.Any(), "blu fary", pagination.DefaultLimit).Return(ts[0].respBody, nil)
+
+	h := &httpHandler{mockStorage, nil, nil, newTestValidator(t)}
+
+	for _, v := range ts {
+
+		e := echo.New()
+		req := httptest.NewRequest(v.method, v.path+v.query, nil)
+		rec := httptest.NewRecorder()
+
+		c := e.NewContext(req, rec)
+		c.SetPath(v.path)
+
+		serve(h.searchDrinks, c)
+		assert.Equal(t, v.expectedCode, rec.Code)
+		if v.hasRespBody {
+			resData, _ := json.Marshal(&v.respBody)
+			assert.JSONEq(t, string(resData), rec.Body.String())
+		}
+	}
+}
+
+// serve вызывает обработчик так же, как echo:
+// возвращённая ошибка превращается в ответ через httpinfra.ErrorHandler
+func serve(handler echo.HandlerFunc, c echo.Context) {
+	if err := handler(c); err != nil {
+		httpinfra.ErrorHandler(err, c)
+	}
+}
+
+func Test_httpHandler_AddRoutes_writesOnlyForAdmins(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mocks.NewMockDrinkModel(ctrl)
+	mockStorage.EXPECT().AllDrinks(gomock.Any(), gomock.Any()).
+		Return(pagination.Page[entities.Drink]{Items: []entities.Drink{}}, nil)
+
+	e := echo.New()
+	e.HTTPErrorHandler = httpinfra.ErrorHandler
+	// вместо настоящей проверки токена берём роль из заголовка
+	authenticate := func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			role := c.Request().Header.Get("X-Test-Role")
+			if role == "" {
+				return errlib.UnauthorizedErr{Err: errors.New("no token")}
 			}
+			authmiddleware.SetPrincipal(c, authmiddleware.Principal{UserID: 1, Role: role})
+			return next(c)
 		}
 	}
+	New(mockStorage, newTestValidator(t), context.Background()).AddRoutes(e.Group("/api"), authenticate)
+
+	for _, route := range []struct{ method, path string }{
+		{http.MethodPost, "/api/drink"},
+		{http.MethodPut, "/api/drink"},
+		{http.MethodPut, "/api/drink/1"},
+		{http.MethodPatch, "/api/drink/1"},
+		{http.MethodDelete, "/api/drink/Coke"},
+	} {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(route.method, route.path, strings.NewReader(`{}`)))
+		assert.Equal(t, http.StatusUnauthorized, rec.Code, route.method+" "+route.path)
+
+		req := httptest.NewRequest(route.method, route.path, strings.NewReader(`{}`))
+		req.Header.Set("X-Test-Role", authmiddleware.RoleUser)
+		rec = httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusForbidden, rec.Code, route.method+" "+route.path)
+	}
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/drink", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	// неизвестные пути отвечают 404 или 405 и не требуют токена
+	for _, path := range []string{"/api/drink/xyz", "/api/drink/1/unknown"} {
+		rec = httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Contains(t, []int{http.StatusNotFound, http.StatusMethodNotAllowed}, rec.Code, path)
+	}
+}
+
+func newTestValidator(t *testing.T) *validate.Validator {
+	v, err := validate.New(config.Validation{
+		UsernameMinLength: 4,
+		UsernameMaxLength: 32,
+		UsernameCharset:   "A-Za-z0-9_.-",
+		PasswordMinLength: 8,
+		PasswordMaxLength: 72,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func Test_httpHandler_createDrink_validationErrors(t *testing.T) {
+	e := echo.New()
+	e.HTTPErrorHandler = httpinfra.ErrorHandler
+	body := `{"name": "", "price": -5, "preparation": "shaken", "ingredients": [{"name": "Karmotrine", "amount": 0}]}`
+	req := httptest.NewRequest(http.MethodPost, "/drink", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	h := &httpHandler{nil, nil, nil, newTestValidator(t)}
+	e.HTTPErrorHandler(h.createDrink(c), c)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	var resp errlib.Response
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, errlib.CodeValidationFailed, resp.Code)
+	assert.Equal(t, []errlib.FieldError{
+		{Field: "name", Rule: validate.RuleRequired, Message: "is required"},
+		{Field: "price", Rule: validate.RuleMin, Message: "must not be negative"},
+		{Field: "preparation", Rule: validate.RuleOneOf, Message: "must be one of mixed, aged, iced, blended"},
+		{Field: "ingredients[0].amount", Rule: validate.RulePositive, Message: "must be positive"},
+	}, resp.Errors)
 }
@@ -1,7 +1,55 @@
 package entities
 
 type Drink struct {
-	ID   int      `json:"id,omitempty" example:"12"`
-	Name string   `json:"name" example:"Coca Cola"`
-	Tags []string `json:"tags" example:"[\"soda\",\"cola\"]"`
+	ID          int          `json:"id,omitempty" example:"12"`
+	Name        string       `json:"name" example:"Coca Cola"`
+	Tags        []string     `json:"tags" example:"[\"soda\",\"cola\"]"`
+	Description string       `json:"description" example:"Sweet soda with a lot of sugar"`
+	Price       int          `json:"price" example:"150"`
+	Alcoholic   bool         `json:"alcoholic" example:"false"`
+	Preparation Preparation  `json:"preparation" example:"iced" enums:"mixed,aged,iced,blended"`
+	Ingredients []Ingredient `json:"ingredients"`
+	// RatingAverage and RatingCount are computed from ratings, they are ignored in requests
+	RatingAverage float64 `json:"rating_average" example:"4.5"`
+	RatingCount   int     `json:"rating_count" example:"12"`
+}
+
+// Ingredient is a line of the drink recipe
+type Ingredient struct {
+	Name   string  `json:"name" example:"Adelhyde"`
+	Amount float64 `json:"amount" example:"2"`
+	Unit   string  `json:"unit" example:"oz"`
+}
+
+// Preparation is how the drink is prepared after mixing ingredients
+type Preparation string
+
+const (
+	PreparationMixed   Preparation = "mixed"
+	PreparationAged    Preparation = "aged"
+	PreparationIced    Preparation = "iced"
+	PreparationBlended Preparation = "blended"
+)
+
+// Valid reports if p is one of known preparations,
+// empty preparation is valid and means PreparationMixed
+func (p Preparation) Valid() bool {
+	switch p {
+	case "", PreparationMixed, PreparationAged, PreparationIced, PreparationBlended:
+		return true
+	}
+	return false
+}
+
+// TagQuery is a search of drinks by tags: drink must have all tags from All,
+// at least one tag from Any (if Any isn't empty) and no tags from None.
+// Tags are compared exactly but case-insensitive
+type TagQuery struct {
+	All  []string `json:"all" example:"Sweet,Girly"`
+	Any  []string `json:"any" example:"Happy"`
+	None []string `json:"none" example:"Soft"`
+}
+
+func (q TagQuery) IsEmpty() bool {
+	return len(q.All) == 0 && len(q.Any) == 0 && len(q.None) == 0
 }
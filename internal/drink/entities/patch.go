@@ -0,0 +1,59 @@
+package entities
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+var ErrBadPatch = errors.New("merge patch must be a json object")
+
+// MergePatch applies JSON merge patch (RFC 7386) to the drink:
+// fields absent in the patch stay the same, null resets the field
+// and arrays such as tags are replaced entirely. Drink ID can't be patched
+func (d Drink) MergePatch(patch []byte) (Drink, error) {
+	var p any
+	if err := json.Unmarshal(patch, &p); err != nil {
+		return Drink{}, err
+	}
+	if _, ok := p.(map[string]any); !ok {
+		return Drink{}, ErrBadPatch
+	}
+	orig, err := json.Marshal(d)
+	if err != nil {
+		return Drink{}, err
+	}
+	var doc any
+	if err := json.Unmarshal(orig, &doc); err != nil {
+		return Drink{}, err
+	}
+	merged, err := json.Marshal(mergePatch(doc, p))
+	if err != nil {
+		return Drink{}, err
+	}
+	var res Drink
+	if err := json.Unmarshal(merged, &res); err != nil {
+		return Drink{}, err
+	}
+	res.ID = d.ID
+	return res, nil
+}
+
+// mergePatch рекурсивно накладывает patch на target по правилам RFC 7386
+func mergePatch(target, patch any) any {
+	p, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+	t, ok := target.(map[string]any)
+	if !ok {
+		t = map[string]any{}
+	}
+	for k, v := range p {
+		if v == nil {
+			delete(t, k)
+			continue
+		}
+		t[k] = mergePatch(t[k], v)
+	}
+	return t
+}
@@ -5,22 +5,29 @@ import (
 	"fmt"
 
 	"github.com/Masterminds/squirrel"
+	"github.com/SapolovichSV/backprogeng/internal/dblib"
 	"github.com/SapolovichSV/backprogeng/internal/drink/entities"
 	"github.com/SapolovichSV/backprogeng/internal/drink/model/queries"
+	"github.com/SapolovichSV/backprogeng/internal/errlib"
+	"github.com/SapolovichSV/backprogeng/internal/pagination"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 // DB:
 // drinks
-// id | name | tags
-var ErrNotFound = fmt.Errorf("not found")
+// id | name | description | price | alcoholic | preparation
+// tags
+// id | name
+// drink_tags
+// drink_id | tag_id
+// ingredients
+// id | name
+// drink_ingredients
+// drink_id | ingredient_id | position | amount | unit
+var ErrNotFound = errlib.NotFoundErr{Where: queries.TABLE_NAME, What: "drink"}
+var ErrNameTaken = errlib.ConflictErr{Where: queries.TABLE_NAME, What: "drink name"}
 var sq = squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar)
 
-// Drink is a struct that represents a drink
-type Drink struct {
-	name string
-	tags tags
-}
 type SQLDrinkModel struct {
 	db *pgxpool.Pool
 }
@@ -28,9 +35,13 @@ type DrinkModel interface {
 	CreateDrink(ctx context.Context, dCont entities.Drink) (entities.Drink, error)
 	UpdateDrink(ctx context.Context, dCont entities.Drink) (entities.Drink, error)
 	DeleteDrink(ctx context.Context, name string) error
-	DrinksByTags(ctx context.Context, tagsCont []string) ([]entities.Drink, error)
-	AllDrinks(ctx context.Context, id int) ([]entities.Drink, error)
+	DrinksByTags(ctx context.Context, tagsCont []string, page pagination.Request) (pagination.Page[entities.Drink], error)
+	DrinksByTagQuery(ctx context.Context, tq entities.TagQuery, page pagination.Request) (pagination.Page[entities.Drink], error)
+	AllDrinks(ctx context.Context, page pagination.Request) (pagination.Page[entities.Drink], error)
 	DrinkByName(ctx context.Context, name string) (entities.Drink, error)
+	DrinkByID(ctx context.Context, id int) (entities.Drink, error)
+	ReplaceDrink(ctx context.Context, id int, dCont entities.Drink) (entities.Drink, error)
+	SearchDrinks(ctx context.Context, text string, limit int) ([]entities.Drink, error)
 }
 
 func New(db *pgxpool.Pool) *SQLDrinkModel {
@@ -38,28 +49,71 @@ func New(db *pgxpool.Pool) *SQLDrinkModel {
 		db: db,
 	}
 }
-func (m *SQLDrinkModel) CreateDrink(ctx context.Context, dCont entities.Drink) (entities.Drink, error) {
-	q := queries.New(ctx, m.db)
-	if _, err := q.CreateDrink(dCont.Name); err != nil {
-		fmt.Println(dCont)
-		return entities.Drink{}, err
-	}
 
-	resDrink, err := q.SetTagsToDrink(dCont.Name, queries.ToTags(dCont.Tags))
-	if err != nil {
-		return entities.Drink{}, err
-	}
-	return resDrink, nil
+// CreateDrink creates drink with its tags and recipe in one transaction
+func (m *SQLDrinkModel) CreateDrink(ctx context.Context, dCont entities.Drink) (res entities.Drink, err error) {
+	dCont = withDefaults(dCont)
+	err = dblib.WithTx(ctx, m.db, func(tx dblib.DBTX) error {
+		q := queries.New(ctx, tx)
+		created, err := q.CreateDrink(dCont)
+		if err != nil {
+			return err
+		}
+		if err := q.SetTagsToDrink(created.ID, dCont.Tags); err != nil {
+			return err
+		}
+		if err := q.SetIngredientsToDrink(created.ID, dCont.Ingredients); err != nil {
+			return err
+		}
+		res, err = q.DrinkByID(created.ID)
+		return err
+	})
+	if errlib.CheckErrConflict(err) {
+		return entities.Drink{}, ErrNameTaken
+	} else if err != nil {
+		return entities.Drink{}, wrapifErrorInModel("create drink", err)
+	}
+	return res, nil
 }
+
+// UpdateDrink finds drink by name and overwrites all other its fields:
+// tags and ingredients which aren't passed will be removed
 func (m *SQLDrinkModel) UpdateDrink(ctx context.Context, dCont entities.Drink) (entities.Drink, error) {
-	d := fromControllerToModel(dCont)
-	sql, args, err := sq.Update("drinks").Set("tags", d.tags).Where(squirrel.Eq{"name": d.name}).ToSql()
-	if err != nil {
-		return fromModelToController(Drink{}), err
+	old, err := queries.New(ctx, m.db).DrinkByName(dCont.Name)
+	if errlib.CheckErrNotFound(err) {
+		return entities.Drink{}, ErrNotFound
+	} else if err != nil {
+		return entities.Drink{}, wrapifErrorInModel("update drink", err)
 	}
-	_, err = m.db.Exec(ctx, sql, args...)
+	return m.ReplaceDrink(ctx, old.ID, dCont)
+}
 
-	return fromModelToController(d), wrapifErrorInModel("update drink", err)
+// ReplaceDrink overwrites all fields of the drink with the given id in one transaction,
+// name can be changed to any name which isn't taken by another drink
+func (m *SQLDrinkModel) ReplaceDrink(ctx context.Context, id int, dCont entities.Drink) (res entities.Drink, err error) {
+	dCont = withDefaults(dCont)
+	err = dblib.WithTx(ctx, m.db, func(tx dblib.DBTX) error {
+		q := queries.New(ctx, tx)
+		if err := q.UpdateDrinkFields(id, dCont); err != nil {
+			return err
+		}
+		if err := q.SetTagsToDrink(id, dCont.Tags); err != nil {
+			return err
+		}
+		if err := q.SetIngredientsToDrink(id, dCont.Ingredients); err != nil {
+			return err
+		}
+		res, err = q.DrinkByID(id)
+		return err
+	})
+	if errlib.CheckErrNotFound(err) {
+		return entities.Drink{}, ErrNotFound
+	} else if errlib.CheckErrConflict(err) {
+		return entities.Drink{}, ErrNameTaken
+	} else if err != nil {
+		return entities.Drink{}, wrapifErrorInModel("replace drink", err)
+	}
+	return res, nil
 }
 func (m *SQLDrinkModel) DeleteDrink(ctx context.Context, name string) error {
 	sql, args, err := sq.Delete("drinks").Where(squirrel.Eq{"name": name}).ToSql()
@@ -76,59 +130,68 @@ func (m *SQLDrinkModel) DeleteDrink(ctx context.Context, name string) error {
 
 	return nil
 }
-func (m *SQLDrinkModel) DrinksByTags(ctx context.Context, tagsCont []string) ([]entities.Drink, error) {
-	tags := fromControllerToModelTags(tagsCont)
-	likeConditions := make([]squirrel.Sqlizer, len(tags))
-	for i, tag := range tags {
-		likeConditions[i] = squirrel.Like{"tags": "%" + tag.Name + "%"}
-	}
-	sql, args, err := sq.Select("name", "tags").From("drinks").Where(squirrel.Or(likeConditions)).ToSql()
+
+// DrinksByTags returns drinks which have at least one of the tags
+func (m *SQLDrinkModel) DrinksByTags(ctx context.Context, tagsCont []string, page pagination.Request) (pagination.Page[entities.Drink], error) {
+	return m.DrinksByTagQuery(ctx, entities.TagQuery{Any: tagsCont}, page)
+}
+func (m *SQLDrinkModel) DrinksByTagQuery(ctx context.Context, tq entities.TagQuery, page pagination.Request) (pagination.Page[entities.Drink], error) {
+	drinks, err := queries.New(ctx, m.db).DrinksByTagQuery(tq, page)
 	if err != nil {
-		return nil, wrapifErrorInModel("drink by tags", err)
+		return pagination.Page[entities.Drink]{}, wrapifErrorInModel("drink by tags", err)
 	}
+	return pagination.NewPage(drinks, page, drinkID), nil
+}
 
-	rows, err := m.db.Query(ctx, sql, args...)
+// AllDrinks returns drinks page by page in id order
+func (m *SQLDrinkModel) AllDrinks(ctx context.Context, page pagination.Request) (pagination.Page[entities.Drink], error) {
+	drinks, err := queries.New(ctx, m.db).AllDrinks(page)
 	if err != nil {
-		return nil, wrapifErrorInModel("drink by tags", err)
+		return pagination.Page[entities.Drink]{}, wrapifErrorInModel("all drinks", err)
 	}
-	var drinks []entities.Drink
-	for rows.Next() {
-		var d Drink
-		rows.Scan(&d.name, &d.tags)
-		drinks = append(drinks, fromModelToController(d))
+	return pagination.NewPage(drinks, page, drinkID), nil
+}
+func (m *SQLDrinkModel) DrinkByName(ctx context.Context, name string) (entities.Drink, error) {
+	d, err := queries.New(ctx, m.db).DrinkByName(name)
+	if errlib.CheckErrNotFound(err) {
+		return entities.Drink{}, ErrNotFound
 	}
-	err = rows.Err()
-	if len(drinks) == 0 {
-		return nil, ErrNotFound
+	return d, wrapifErrorInModel("drink by name", err)
+}
+func (m *SQLDrinkModel) DrinkByID(ctx context.Context, id int) (entities.Drink, error) {
+	d, err := queries.New(ctx, m.db).DrinkByID(id)
+	if errlib.CheckErrNotFound(err) {
+		return entities.Drink{}, ErrNotFound
 	}
-	return drinks, wrapifErrorInModel("drink by tags", err)
+	return d, wrapifErrorInModel("drink by id", err)
 }
-func (m *SQLDrinkModel) AllDrinks(ctx context.Context, id int) ([]entities.Drink, error) {
-	sql := "SELECT name,tags FROM drinks WHERE id >= $1;"
-	rows, err := m.db.Query(ctx, sql, id)
+
+// SearchDrinks finds drinks by prefix, typo-tolerant and full-text matching
+// of their names and tags, the most relevant drinks go first
+func (m *SQLDrinkModel) SearchDrinks(ctx context.Context, text string, limit int) ([]entities.Drink, error) {
+	drinks, err := queries.New(ctx, m.db).SearchDrinks(text, limit)
 	if err != nil {
-		return nil, wrapifErrorInModel("all drinks", err)
+		return nil, wrapifErrorInModel("search drinks", err)
 	}
-	var drinks []entities.Drink
-	for rows.Next() {
-		var d Drink
-		rows.Scan(&d.name, &d.tags)
-		drinks = append(drinks, fromModelToController(d))
+	if drinks == nil {
+		drinks = []entities.Drink{}
 	}
-	err = rows.Err()
-	if len(drinks) == 0 {
-		return nil, ErrNotFound
+	return drinks, nil
+}
+
+// withDefaults заполняет необязательные поля напитка значениями по умолчанию
+func withDefaults(d entities.Drink) entities.Drink {
+	if d.Preparation == "" {
+		d.Preparation = entities.PreparationMixed
 	}
-	return drinks, wrapifErrorInModel("all drinks", err)
+	return d
 }
-func (m *SQLDrinkModel) DrinkByName(ctx context.Context, name string) (entities.Drink, error) {
-	sql, args, err := sq.Select("name", "tags").From("drinks").Where(squirrel.Eq{"name": name}).ToSql()
+func drinkID(d entities.Drink) int {
+	return d.ID
+}
+func wrapifErrorInModel(msg string, err error) error {
 	if err != nil {
-		return entities.Drink{}, err
+		return fmt.Errorf("%s : %w", msg, err)
 	}
-	row := m.db.QueryRow(ctx, sql, args...)
-	var d Drink
-	err = row.Scan(&d.name, &d.tags)
-
-	return fromModelToController(d), wrapifErrorInModel("drink by name", err)
+	return nil
 }
@@ -6,29 +6,91 @@ import (
 	"testing"
 
 	"github.com/SapolovichSV/backprogeng/internal/drink/entities"
+	"github.com/SapolovichSV/backprogeng/internal/drink/model/queries"
+	"github.com/SapolovichSV/backprogeng/internal/errlib"
+	"github.com/SapolovichSV/backprogeng/internal/pagination"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/stretchr/testify/assert"
 )
 
-const QUERY_CREATE_TABLES = `CREATE TABLE drinks (
+const QUERY_CREATE_TABLES = `CREATE EXTENSION IF NOT EXISTS pg_trgm;
+CREATE TABLE drinks (
     id SERIAL PRIMARY KEY,
     name VARCHAR(255) NOT NULL,
-    tags TEXT
+    description TEXT NOT NULL DEFAULT '',
+    price INT NOT NULL DEFAULT 0 CHECK (price >= 0),
+    alcoholic BOOLEAN NOT NULL DEFAULT true,
+    preparation VARCHAR(16) NOT NULL DEFAULT 'mixed'
+        CHECK (preparation IN ('mixed', 'aged', 'iced', 'blended'))
+);
+CREATE UNIQUE INDEX drinks_name_lower_key ON drinks (lower(name));
+CREATE TABLE tags (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL
+);
+CREATE UNIQUE INDEX tags_name_lower_idx ON tags (lower(name));
+CREATE TABLE drink_tags (
+    drink_id INT NOT NULL,
+    FOREIGN KEY (drink_id) REFERENCES drinks(id) ON DELETE CASCADE,
+    tag_id INT NOT NULL,
+    FOREIGN KEY (tag_id) REFERENCES tags(id) ON DELETE CASCADE,
+    PRIMARY KEY (drink_id, tag_id)
+);
+CREATE TABLE ingredients (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL
+);
+CREATE UNIQUE INDEX ingredients_name_lower_idx ON ingredients (lower(name));
+CREATE TABLE drink_ingredients (
+    drink_id INT NOT NULL,
+    FOREIGN KEY (drink_id) REFERENCES drinks(id) ON DELETE CASCADE,
+    ingredient_id INT NOT NULL,
+    FOREIGN KEY (ingredient_id) REFERENCES ingredients(id) ON DELETE RESTRICT,
+    position INT NOT NULL,
+    amount NUMERIC(10, 2) NOT NULL CHECK (amount > 0),
+    unit VARCHAR(32) NOT NULL DEFAULT '',
+    PRIMARY KEY (drink_id, ingredient_id)
 );
 CREATE TABLE users (
     id SERIAL PRIMARY KEY,
     username VARCHAR(255) NOT NULL,
-    password VARCHAR(255)
+    password VARCHAR(255),
+    role VARCHAR(16) NOT NULL DEFAULT 'user'
 );
 CREATE TABLE favs (
     user_id INT NOT NULL,
     FOREIGN KEY (user_id) REFERENCES users(id)  ON DELETE CASCADE,
     drink_id INT NOT NULL,
-   FOREIGN KEY (drink_id) REFERENCES drinks(id) ON DELETE CASCADE
+   FOREIGN KEY (drink_id) REFERENCES drinks(id) ON DELETE CASCADE,
+    PRIMARY KEY (user_id, drink_id)
+);
+CREATE TABLE ratings (
+    user_id INT NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    drink_id INT NOT NULL,
+    FOREIGN KEY (drink_id) REFERENCES drinks(id) ON DELETE CASCADE,
+    score SMALLINT NOT NULL CHECK (score BETWEEN 1 AND 5),
+    review VARCHAR(1000) NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (user_id, drink_id)
 );`
-const QUERY_DROP_TABLES = `DROP TABLE drinks CASCADE;
+const QUERY_DROP_TABLES = `DROP TABLE drink_ingredients CASCADE;
+DROP TABLE ingredients CASCADE;
+DROP TABLE drink_tags CASCADE;
+DROP TABLE tags CASCADE;
+DROP TABLE drinks CASCADE;
 DROP TABLE users CASCADE;
-DROP TABLE favs CASCADE;`
+DROP TABLE favs CASCADE;
+DROP TABLE ratings CASCADE;`
+
+// drinkFromDB достаёт напиток вместе с тегами без ID,
+// чтобы сравнивать его с тем, что передавали в модель
+func drinkFromDB(ctx context.Context, db *pgxpool.Pool, name string) (entities.Drink, error) {
+	d, err := queries.New(ctx, db).DrinkByName(name)
+	d.ID = 0
+	return d, err
+}
 
 // Сначала нужно поднять тестовую бд
 // потом запускать
@@ -56,8 +118,16 @@ func TestSQLDrinkModel_CreateDrink(t *testing.T) {
 
 	ctx := context.Background()
 	drink := entities.Drink{
-		Name: "Test Drink",
-		Tags: []string{"tag1", "tag2"},
+		Name:        "Test Drink",
+		Tags:        []string{"tag1", "tag2"},
+		Description: "Test description",
+		Price:       250,
+		Alcoholic:   true,
+		Preparation: entities.PreparationIced,
+		Ingredients: []entities.Ingredient{
+			{Name: "Karmotrine", Amount: 4},
+			{Name: "Bronson Extract", Amount: 2, Unit: "oz"},
+		},
 	}
 
 	_, err = model.CreateDrink(ctx, drink)
@@ -65,16 +135,30 @@ func TestSQLDrinkModel_CreateDrink(t *testing.T) {
 		t.Fatalf("Failed to create drink: %v", err)
 	}
 
-	var createdDrink Drink
-
-	err = db.QueryRow(ctx, "SELECT name, tags FROM drinks WHERE name = $1", drink.Name).Scan(&createdDrink.name, &createdDrink.tags)
+	resDrink, err := drinkFromDB(ctx, db, drink.Name)
 	if err != nil {
 		t.Fatalf("Failed to retrieve created drink: %v", err)
 	}
-	resDrink := fromModelToController(createdDrink)
 	if !reflect.DeepEqual(drink, resDrink) {
 		t.Errorf("Created drink does not match: got %v, want %v", resDrink, drink)
 	}
+
+	_, err = model.CreateDrink(ctx, entities.Drink{Name: "TEST DRINK"})
+	if err != ErrNameTaken {
+		t.Errorf("Expected name conflict, got %v", err)
+	}
+
+	// повторные и пустые строки рецепта не теряются молча, напиток не создаётся
+	for _, ingredients := range [][]entities.Ingredient{
+		{{Name: "Karmotrine", Amount: 1}, {Name: "karmotrine ", Amount: 2}},
+		{{Name: "Karmotrine", Amount: 1}, {Name: " ", Amount: 2}},
+	} {
+		_, err = model.CreateDrink(ctx, entities.Drink{Name: "Broken Recipe", Ingredients: ingredients})
+		var invalid errlib.InvalidInputErr
+		assert.ErrorAs(t, err, &invalid)
+		_, err = model.DrinkByName(ctx, "Broken Recipe")
+		assert.True(t, errlib.CheckErrNotFound(err))
+	}
 }
 
 func TestSQLDrinkModel_UpdateDrink(t *testing.T) {
@@ -95,8 +179,10 @@ func TestSQLDrinkModel_UpdateDrink(t *testing.T) {
 
 	ctx := context.Background()
 	drink := entities.Drink{
-		Name: "Test Drink",
-		Tags: []string{"tag1", "tag2"},
+		Name:        "Test Drink",
+		Tags:        []string{"tag1", "tag2"},
+		Preparation: entities.PreparationMixed,
+		Ingredients: []entities.Ingredient{},
 	}
 
 	_, err = model.CreateDrink(ctx, drink)
@@ -105,24 +191,76 @@ func TestSQLDrinkModel_UpdateDrink(t *testing.T) {
 	}
 
 	drink.Tags = []string{"tag3", "tag4"}
+	drink.Price = 100
+	drink.Preparation = entities.PreparationBlended
+	drink.Ingredients = []entities.Ingredient{{Name: "Adelhyde", Amount: 1.5}}
 
 	_, err = model.UpdateDrink(ctx, drink)
 	if err != nil {
 		t.Fatalf("Failed to update drink: %v", err)
 	}
 
-	var updatedDrink Drink
-
-	err = db.QueryRow(ctx, "SELECT name, tags FROM drinks WHERE name = $1", drink.Name).Scan(&updatedDrink.name, &updatedDrink.tags)
+	resDrink, err := drinkFromDB(ctx, db, drink.Name)
 	if err != nil {
 		t.Fatalf("Failed to retrieve updated drink: %v", err)
 	}
-	resDrink := fromModelToController(updatedDrink)
 	if !reflect.DeepEqual(drink, resDrink) {
 		t.Errorf("Updated drink does not match: got %v, want %v", resDrink, drink)
 	}
 }
 
+func TestSQLDrinkModel_ReplaceDrink(t *testing.T) {
+	db, err := pgxpool.New(context.TODO(), "host=localhost user=username password=password dbname=dbname sslmode=disable")
+	if err != nil {
+		t.Fatalf("Failed to connect to the database: %v", err)
+	}
+	defer db.Close()
+	_, err = db.Exec(context.TODO(), QUERY_CREATE_TABLES)
+	defer db.Exec(context.TODO(), QUERY_DROP_TABLES)
+	if err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	model := &SQLDrinkModel{db: db}
+
+	ctx := context.Background()
+	drink := entities.Drink{
+		Name:        "Test Drink",
+		Tags:        []string{"tag1", "tag2"},
+		Preparation: entities.PreparationMixed,
+		Ingredients: []entities.Ingredient{},
+	}
+	created, err := model.CreateDrink(ctx, drink)
+	if err != nil {
+		t.Fatalf("Failed to create drink: %v", err)
+	}
+	other, err := model.CreateDrink(ctx, entities.Drink{Name: "Other Drink"})
+	if err != nil {
+		t.Fatalf("Failed to create drink: %v", err)
+	}
+
+	drink.Name = "Renamed Drink"
+	drink.Tags = []string{"tag3"}
+	drink.ID = created.ID
+	replaced, err := model.ReplaceDrink(ctx, created.ID, drink)
+	if err != nil {
+		t.Fatalf("Failed to replace drink: %v", err)
+	}
+	if !reflect.DeepEqual(drink, replaced) {
+		t.Errorf("Replaced drink does not match: got %v, want %v", replaced, drink)
+	}
+	if _, err := drinkFromDB(ctx, db, "Test Drink"); err == nil {
+		t.Errorf("Drink is still found by old name")
+	}
+
+	drink.Name = "other drink"
+	if _, err := model.ReplaceDrink(ctx, created.ID, drink); err != ErrNameTaken {
+		t.Errorf("Expected name conflict, got %v", err)
+	}
+	if _, err := model.ReplaceDrink(ctx, other.ID+100, entities.Drink{Name: "Missing"}); err != ErrNotFound {
+		t.Errorf("Expected not found, got %v", err)
+	}
+}
+
 func TestSQLDrinkModel_DeleteDrink(t *testing.T) {
 	db, err := pgxpool.New(context.TODO(), "host=localhost user=username password=password dbname=dbname sslmode=disable")
 	if err != nil {
@@ -141,8 +279,10 @@ func TestSQLDrinkModel_DeleteDrink(t *testing.T) {
 
 	ctx := context.Background()
 	drink := entities.Drink{
-		Name: "Test Drink",
-		Tags: []string{"tag1", "tag2"},
+		Name:        "Test Drink",
+		Tags:        []string{"tag1", "tag2"},
+		Preparation: entities.PreparationMixed,
+		Ingredients: []entities.Ingredient{},
 	}
 
 	_, err = model.CreateDrink(ctx, drink)
@@ -155,9 +295,7 @@ func TestSQLDrinkModel_DeleteDrink(t *testing.T) {
 		t.Fatalf("Failed to delete drink: %v", err)
 	}
 
-	var deletedDrink Drink
-
-	err = db.QueryRow(ctx, "SELECT name, tags FROM drinks WHERE name = $1", drink.Name).Scan(&deletedDrink.name, &deletedDrink.tags)
+	_, err = drinkFromDB(ctx, db, drink.Name)
 	if err == nil {
 		t.Fatalf("Deleted drink was found: %v", err)
 	}
@@ -182,27 +320,34 @@ func TestSQLDrinkModel_DrinksByTags(t *testing.T) {
 
 	ctx := context.Background()
 	drink1 := entities.Drink{
-		Name: "Test Drink1",
-		Tags: []string{"tag1", "tag2"},
+		Name:        "Test Drink1",
+		Tags:        []string{"tag1", "tag2"},
+		Preparation: entities.PreparationMixed,
+		Ingredients: []entities.Ingredient{},
 	}
 	drink2 := entities.Drink{
-		Name: "Test Drink2",
-		Tags: []string{"tag2", "tag3"},
+		Name:        "Test Drink2",
+		Tags:        []string{"tag2", "tag3"},
+		Preparation: entities.PreparationMixed,
+		Ingredients: []entities.Ingredient{},
 	}
 
-	_, err = model.CreateDrink(ctx, drink1)
+	created1, err := model.CreateDrink(ctx, drink1)
 	if err != nil {
 		t.Fatalf("Failed to create drink1: %v", err)
 	}
-	_, err = model.CreateDrink(ctx, drink2)
+	drink1.ID = created1.ID
+	created2, err := model.CreateDrink(ctx, drink2)
 	if err != nil {
 		t.Fatalf("Failed to create drink2: %v", err)
 	}
+	drink2.ID = created2.ID
 
-	drinks, err := model.DrinksByTags(ctx, []string{"tag2"})
+	page, err := model.DrinksByTags(ctx, []string{"tag2"}, pagination.Request{Limit: pagination.DefaultLimit})
 	if err != nil {
 		t.Fatalf("Failed to get drinks by tags: %v", err)
 	}
+	drinks := page.Items
 	if len(drinks) != 2 {
 		t.Fatalf("Wrong number of drinks: got %d, want %d", len(drinks), 2)
 	}
@@ -226,30 +371,49 @@ func TestSQLDrinkModel_AllDrinks(t *testing.T) {
 
 	ctx := context.Background()
 	drink1 := entities.Drink{
-		Name: "Test Drink1",
-		Tags: []string{"tag1", "tag2"},
+		Name:        "Test Drink1",
+		Tags:        []string{"tag1", "tag2"},
+		Preparation: entities.PreparationMixed,
+		Ingredients: []entities.Ingredient{},
 	}
 	drink2 := entities.Drink{
-		Name: "Test Drink2",
-		Tags: []string{"tag2", "tag3"},
+		Name:        "Test Drink2",
+		Tags:        []string{"tag2", "tag3"},
+		Preparation: entities.PreparationMixed,
+		Ingredients: []entities.Ingredient{},
 	}
 
-	_, err = model.CreateDrink(ctx, drink1)
+	created1, err := model.CreateDrink(ctx, drink1)
 	if err != nil {
 		t.Fatalf("Failed to create drink1: %v", err)
 	}
-	_, err = model.CreateDrink(ctx, drink2)
+	drink1.ID = created1.ID
+	created2, err := model.CreateDrink(ctx, drink2)
 	if err != nil {
 		t.Fatalf("Failed to create drink2: %v", err)
 	}
+	drink2.ID = created2.ID
 
-	drinks, err := model.AllDrinks(ctx, 0)
+	// по одному напитку на страницу, чтобы проверить курсор
+	page, err := model.AllDrinks(ctx, pagination.Request{Limit: 1})
 	if err != nil {
 		t.Fatalf("Failed to get all drinks: %v", err)
 	}
-	if len(drinks) != 2 {
-		t.Fatalf("Wrong number of drinks: got %d, want %d", len(drinks), 2)
+	if len(page.Items) != 1 || page.NextCursor == "" {
+		t.Fatalf("Wrong first page: got %v", page)
 	}
+	next, err := pagination.Parse("1", page.NextCursor)
+	if err != nil {
+		t.Fatalf("Failed to parse next cursor: %v", err)
+	}
+	lastPage, err := model.AllDrinks(ctx, next)
+	if err != nil {
+		t.Fatalf("Failed to get all drinks: %v", err)
+	}
+	if len(lastPage.Items) != 1 || lastPage.NextCursor != "" {
+		t.Fatalf("Wrong last page: got %v", lastPage)
+	}
+	drinks := append(page.Items, lastPage.Items...)
 	if !reflect.DeepEqual(drinks[0], drink1) || !reflect.DeepEqual(drinks[1], drink2) {
 		t.Errorf("Drinks do not match: got %v, want %v", drinks, []entities.Drink{drink1, drink2})
 	}
@@ -279,13 +443,15 @@ func TestSQLDrinkModel_DrinkByName(t *testing.T) {
 		{
 			name: "SimpleTest",
 			want: entities.Drink{
-				Name: "SimpleDrink",
-				Tags: []string{"tag1", "tag2"},
+				Name:        "SimpleDrink",
+				Tags:        []string{"tag1", "tag2"},
+				Preparation: entities.PreparationMixed,
+				Ingredients: []entities.Ingredient{},
 			},
 			wantErr:                      false,
 			mustCreateDrinkBeforeTesting: true,
 			drinks: []entities.Drink{
-				{Name: "SimpleDrink", Tags: []string{"tag1", "tag2"}},
+				{Name: "SimpleDrink", Tags: []string{"tag1", "tag2"}, Preparation: entities.PreparationMixed},
 			},
 		},
 	}
@@ -303,9 +469,127 @@ func TestSQLDrinkModel_DrinkByName(t *testing.T) {
 				} else {
 					drink, err := model.DrinkByName(ctx, tt.want.Name)
 					assert.NoError(t, err)
+					drink.ID = 0
 					assert.Equal(t, tt.want, drink)
 				}
 			}
 		})
 	}
 }
+
+func TestSQLDrinkModel_DrinksByTagQuery(t *testing.T) {
+	db, err := pgxpool.New(context.TODO(), "host=localhost user=username password=password dbname=dbname sslmode=disable")
+	if err != nil {
+		t.Fatalf("Failed to connect to the database: %v", err)
+	}
+	defer db.Close()
+	_, err = db.Exec(context.TODO(), QUERY_CREATE_TABLES)
+	defer db.Exec(context.TODO(), QUERY_DROP_TABLES)
+	if err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	model := &SQLDrinkModel{db: db}
+
+	ctx := context.Background()
+	for _, drink := range []entities.Drink{
+		{Name: "Blue Fairy", Tags: []string{"Girly", "Soft", "Sweet"}},
+		{Name: "Moonblast", Tags: []string{"Girly", "Happy", "Sweet"}},
+		{Name: "Bad Touch", Tags: []string{"Classy", "Sour", "Vintage"}},
+		{Name: "Sourish", Tags: []string{"Sourish"}},
+	} {
+		if _, err := model.CreateDrink(ctx, drink); err != nil {
+			t.Fatalf("Failed to create drink: %v", err)
+		}
+	}
+	tests := []struct {
+		name      string
+		query     entities.TagQuery
+		wantNames []string
+	}{
+		{
+			name:      "AllAndNone",
+			query:     entities.TagQuery{All: []string{"Sweet", "Girly"}, None: []string{"Soft"}},
+			wantNames: []string{"Moonblast"},
+		},
+		{
+			name:      "ExactCaseInsensitive",
+			query:     entities.TagQuery{Any: []string{"sour"}},
+			wantNames: []string{"Bad Touch"},
+		},
+		{
+			name:      "AnyOf",
+			query:     entities.TagQuery{Any: []string{"Soft", "Vintage"}},
+			wantNames: []string{"Blue Fairy", "Bad Touch"},
+		},
+		{
+			name:      "NothingMatches",
+			query:     entities.TagQuery{All: []string{"Sweet", "Sour"}},
+			wantNames: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := model.DrinksByTagQuery(ctx, tt.query, pagination.Request{Limit: pagination.DefaultLimit})
+			assert.NoError(t, err)
+			var names []string
+			for _, d := range page.Items {
+				names = append(names, d.Name)
+			}
+			assert.Equal(t, tt.wantNames, names)
+		})
+	}
+}
+
+func TestSQLDrinkModel_SearchDrinks(t *testing.T) {
+	db, err := pgxpool.New(context.TODO(), "host=localhost user=username password=password dbname=dbname sslmode=disable")
+	if err != nil {
+		t.Fatalf("Failed to connect to the database: %v", err)
+	}
+	defer db.Close()
+	_, err = db.Exec(context.TODO(), QUERY_CREATE_TABLES)
+	defer db.Exec(context.TODO(), QUERY_DROP_TABLES)
+	if err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	model := &SQLDrinkModel{db: db}
+
+	ctx := context.Background()
+	for _, drink := range []entities.Drink{
+		{Name: "Blue Fairy", Tags: []string{"Girly", "Soft", "Sweet"}},
+		{Name: "Bloom Light", Tags: []string{"Bland", "Promo", "Spicy"}},
+		{Name: "Moonblast", Tags: []string{"Girly", "Happy", "Sweet"}},
+		{Name: "Gut Punch", Tags: []string{"Bitter", "Manly", "Strong"}},
+	} {
+		if _, err := model.CreateDrink(ctx, drink); err != nil {
+			t.Fatalf("Failed to create drink: %v", err)
+		}
+	}
+	tests := []struct {
+		name      string
+		text      string
+		wantFirst string
+	}{
+		{name: "Typo", text: "blu fary", wantFirst: "Blue Fairy"},
+		{name: "Prefix", text: "Bloo", wantFirst: "Bloom Light"},
+		{name: "FullText", text: "punch", wantFirst: "Gut Punch"},
+		{name: "ByTag", text: "happy", wantFirst: "Moonblast"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			drinks, err := model.SearchDrinks(ctx, tt.text, pagination.DefaultLimit)
+			assert.NoError(t, err)
+			if assert.NotEmpty(t, drinks) {
+				assert.Equal(t, tt.wantFirst, drinks[0].Name)
+			}
+		})
+	}
+}
+
+// ошибки модели остаются типизированными, иначе ErrorHandler отдаст 500
+func Test_wrapifErrorInModel(t *testing.T) {
+	assert.NoError(t, wrapifErrorInModel("create drink", nil))
+	err := wrapifErrorInModel("create drink", errlib.ConflictErr{Where: "drinks", What: "drink name"})
+	assert.True(t, errlib.CheckErrConflict(err))
+	err = wrapifErrorInModel("drink by id", errlib.NotFoundErr{Where: "drinks", What: "drink"})
+	assert.True(t, errlib.CheckErrNotFound(err))
+}
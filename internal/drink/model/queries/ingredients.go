@@ -0,0 +1,54 @@
+package queries
+
+import (
+	"github.com/SapolovichSV/backprogeng/internal/drink/entities"
+	"github.com/SapolovichSV/backprogeng/internal/errlib"
+)
+
+const INGREDIENTS_TABLE_NAME = "ingredients"
+
+// SetIngredientsToDrink replaces drink recipe with the given ingredient lines
+// keeping their order, missing ingredients are added to the catalog,
+// ingredient names are unique case-insensitive. Repeated or blank lines
+// are an error, validate.Validator rejects them before
+func (q *Query) SetIngredientsToDrink(drinkID int, ingredients []entities.Ingredient) error {
+	sql := `DELETE FROM drink_ingredients
+	WHERE drink_id = $1;`
+	if _, err := q.db.Exec(q.ctx, sql, drinkID); err != nil {
+		return errlib.WrapError(err, "drink_ingredients", "old recipe can't be removed from drink")
+	}
+	if len(ingredients) == 0 {
+		return nil
+	}
+	names := make([]string, len(ingredients))
+	amounts := make([]float64, len(ingredients))
+	units := make([]string, len(ingredients))
+	for i, ingredient := range ingredients {
+		names[i] = ingredient.Name
+		amounts[i] = ingredient.Amount
+		units[i] = ingredient.Unit
+	}
+	sql = `INSERT INTO ingredients (name)
+	SELECT DISTINCT btrim(name)
+	FROM unnest($1::text[]) AS name
+	WHERE btrim(name) <> ''
+	ON CONFLICT ((lower(name))) DO NOTHING;`
+	if _, err := q.db.Exec(q.ctx, sql, names); err != nil {
+		return errlib.WrapError(err, INGREDIENTS_TABLE_NAME, "ingredients can't be created")
+	}
+	sql = `INSERT INTO drink_ingredients (drink_id, ingredient_id, position, amount, unit)
+	SELECT $1, ingredients.id, line.position, line.amount, btrim(line.unit)
+	FROM unnest($2::text[], $3::numeric[], $4::text[]) WITH ORDINALITY AS line(name, amount, unit, position)
+	JOIN ingredients ON lower(ingredients.name) = lower(btrim(line.name));`
+	res, err := q.db.Exec(q.ctx, sql, drinkID, names, amounts, units)
+	if errlib.CheckErrConflictInDB(err) {
+		return errlib.InvalidInputErr{What: "ingredients", Reason: "must not repeat"}
+	} else if err != nil {
+		return errlib.WrapError(err, "drink_ingredients", "recipe can't be set to drink")
+	}
+	// строки с пустым именем не нашли ингредиента, молча их не теряем
+	if int(res.RowsAffected()) != len(ingredients) {
+		return errlib.InvalidInputErr{What: "ingredients", Reason: "names must not be blank"}
+	}
+	return nil
+}
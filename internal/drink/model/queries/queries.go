@@ -3,74 +3,187 @@ package queries
 import (
 	"context"
 
+	"github.com/SapolovichSV/backprogeng/internal/dblib"
 	"github.com/SapolovichSV/backprogeng/internal/drink/entities"
 	"github.com/SapolovichSV/backprogeng/internal/errlib"
-	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/SapolovichSV/backprogeng/internal/pagination"
+	"github.com/jackc/pgx/v5"
 )
 
 type Query struct {
 	ctx context.Context
-	db  *pgxpool.Pool
+	db  dblib.DBTX
 }
 
 const TABLE_NAME = "drinks"
 
-func New(ctx context.Context, db *pgxpool.Pool) *Query {
+// SelectDrinks выбирает напиток вместе с его тегами из drink_tags,
+// рецептом из drink_ingredients и средней оценкой из ratings, строки читаются через ScanDrink
+const SelectDrinks = `SELECT drinks.id, drinks.name,
+	drinks.description, drinks.price, drinks.alcoholic, drinks.preparation,
+	ARRAY(SELECT tags.name
+		FROM drink_tags
+		JOIN tags ON tags.id = drink_tags.tag_id
+		WHERE drink_tags.drink_id = drinks.id
+		ORDER BY tags.name) AS tags,
+	COALESCE((SELECT json_agg(json_build_object(
+			'name', ingredients.name,
+			'amount', drink_ingredients.amount,
+			'unit', drink_ingredients.unit
+		) ORDER BY drink_ingredients.position)
+		FROM drink_ingredients
+		JOIN ingredients ON ingredients.id = drink_ingredients.ingredient_id
+		WHERE drink_ingredients.drink_id = drinks.id), '[]'::json) AS ingredients,
+	COALESCE((SELECT round(avg(ratings.score), 2)
+		FROM ratings
+		WHERE ratings.drink_id = drinks.id), 0)::float8 AS rating_average,
+	(SELECT count(*)
+		FROM ratings
+		WHERE ratings.drink_id = drinks.id) AS rating_count
+	FROM drinks`
+
+func New(ctx context.Context, db dblib.DBTX) *Query {
 	return &Query{
 		ctx: ctx,
 		db:  db,
 	}
 }
 func (q *Query) DrinkByName(name string) (entities.Drink, error) {
-	sql := `SELECT id,name,tags FROM drinks
-	WHERE name = $1`
-	var drink entities.Drink
-	err := q.db.QueryRow(q.ctx, sql, name).Scan(&drink.ID, &drink.Name, &drink.Tags)
+	sql := SelectDrinks + `
+	WHERE drinks.name = $1`
+	drink, err := ScanDrink(q.db.QueryRow(q.ctx, sql, name))
+	if err != nil {
+		return entities.Drink{}, errlib.WrapError(err, TABLE_NAME, "drink")
+	}
+	return drink, nil
+}
+func (q *Query) DrinkByID(id int) (entities.Drink, error) {
+	sql := SelectDrinks + `
+	WHERE drinks.id = $1`
+	drink, err := ScanDrink(q.db.QueryRow(q.ctx, sql, id))
 	if err != nil {
 		return entities.Drink{}, errlib.WrapError(err, TABLE_NAME, "drink")
 	}
 	return drink, nil
 }
-func (q *Query) CreateDrink(drinkName string) (entities.Drink, error) {
 
-	sql := `INSERT INTO drinks
-	(name)
-	VALUES($1);`
-	_, err := q.db.Exec(q.ctx, sql, drinkName)
+// DrinksByIDs returns drinks with the given ids in id order
+func (q *Query) DrinksByIDs(ids []int) ([]entities.Drink, error) {
+	sql := SelectDrinks + `
+	WHERE drinks.id = ANY($1)
+	ORDER BY drinks.id`
+	rows, err := q.db.Query(q.ctx, sql, ids)
 	if err != nil {
-		return entities.Drink{}, errlib.WrapError(err, "drinks", "drink can't be created")
+		return nil, errlib.WrapError(err, TABLE_NAME, "drinks")
 	}
-	var resultDrink entities.Drink
-	sql = `SELECT id,name
-	FROM drinks
-	WHERE name=$1;`
-	err = q.db.QueryRow(q.ctx, sql, drinkName).Scan(&resultDrink.ID, &resultDrink.Name)
+	return ScanDrinks(rows)
+}
+
+// AllDrinks returns up to page.FetchLimit() drinks after page.AfterID in id order
+func (q *Query) AllDrinks(page pagination.Request) ([]entities.Drink, error) {
+	sql := SelectDrinks + `
+	WHERE drinks.id > $1
+	ORDER BY drinks.id
+	LIMIT $2`
+	rows, err := q.db.Query(q.ctx, sql, page.AfterID, page.FetchLimit())
 	if err != nil {
-		return entities.Drink{}, errlib.WrapError(err, "drinks", "drink was created but not found")
+		return nil, errlib.WrapError(err, TABLE_NAME, "drinks")
 	}
-	return resultDrink, nil
+	return ScanDrinks(rows)
 }
-func (q *Query) SetTagsToDrink(drinkname string, tags tags) (entities.Drink, error) {
 
-	sql := `UPDATE drinks
-	SET tags = $1
-	WHERE name = $2;`
-	_, err := q.db.Exec(q.ctx, sql, tags, drinkname)
+// DrinksByTagQuery returns drinks matching the tag query page by page,
+// tags are compared exactly but case-insensitive
+func (q *Query) DrinksByTagQuery(tq entities.TagQuery, page pagination.Request) ([]entities.Drink, error) {
+	sql := SelectDrinks + `
+	WHERE (cardinality($1::text[]) = 0 OR (SELECT count(*)
+		FROM drink_tags
+		JOIN tags ON tags.id = drink_tags.tag_id
+		WHERE drink_tags.drink_id = drinks.id
+		AND lower(tags.name) = ANY($1)) = cardinality($1::text[]))
+	AND (cardinality($2::text[]) = 0 OR EXISTS (SELECT 1
+		FROM drink_tags
+		JOIN tags ON tags.id = drink_tags.tag_id
+		WHERE drink_tags.drink_id = drinks.id
+		AND lower(tags.name) = ANY($2)))
+	AND NOT EXISTS (SELECT 1
+		FROM drink_tags
+		JOIN tags ON tags.id = drink_tags.tag_id
+		WHERE drink_tags.drink_id = drinks.id
+		AND lower(tags.name) = ANY($3))
+	AND drinks.id > $4
+	ORDER BY drinks.id
+	LIMIT $5`
+	rows, err := q.db.Query(q.ctx, sql,
+		normalizeTags(tq.All), normalizeTags(tq.Any), normalizeTags(tq.None),
+		page.AfterID, page.FetchLimit(),
+	)
 	if err != nil {
-		return entities.Drink{}, errlib.WrapError(err, "drinks", "tags can't be set to drink")
+		return nil, errlib.WrapError(err, TABLE_NAME, "drinks")
 	}
+	return ScanDrinks(rows)
+}
 
-	var resultDrink entities.Drink
-	haveTags := ToTags([]string{})
+// CreateDrink inserts drink columns and returns the inserted row,
+// tags and ingredients are set by SetTagsToDrink and SetIngredientsToDrink
+func (q *Query) CreateDrink(drink entities.Drink) (entities.Drink, error) {
+	sql := `INSERT INTO drinks
+	(name, description, price, alcoholic, preparation)
+	VALUES($1, $2, $3, $4, $5)
+	RETURNING id, name, description, price, alcoholic, preparation;`
+	var res entities.Drink
+	err := q.db.QueryRow(q.ctx, sql, drink.Name, drink.Description, drink.Price, drink.Alcoholic, drink.Preparation).
+		Scan(&res.ID, &res.Name, &res.Description, &res.Price, &res.Alcoholic, &res.Preparation)
+	if errlib.CheckErrConflictInDB(err) {
+		return entities.Drink{}, errlib.ConflictErr{Where: TABLE_NAME, What: "drink name"}
+	} else if err != nil {
+		return entities.Drink{}, errlib.WrapError(err, TABLE_NAME, "drink can't be created")
+	}
+	return res, nil
+}
 
-	sql = `SELECT id,name,tags
-	FROM drinks
-	WHERE name=$1;`
-	err = q.db.QueryRow(q.ctx, sql, drinkname).Scan(&resultDrink.ID, &resultDrink.Name, &haveTags)
-	resultDrink.Tags = FromTags(haveTags)
-	if err != nil {
-		return entities.Drink{}, errlib.WrapError(err, "drinks", "tags was set but drink not found")
+// UpdateDrinkFields overwrites all drink columns including name,
+// tags and ingredients are set by SetTagsToDrink and SetIngredientsToDrink
+func (q *Query) UpdateDrinkFields(drinkID int, drink entities.Drink) error {
+	sql := `UPDATE drinks
+	SET name = $2, description = $3, price = $4, alcoholic = $5, preparation = $6
+	WHERE id = $1;`
+	res, err := q.db.Exec(q.ctx, sql, drinkID, drink.Name, drink.Description, drink.Price, drink.Alcoholic, drink.Preparation)
+	if errlib.CheckErrConflictInDB(err) {
+		return errlib.ConflictErr{Where: TABLE_NAME, What: "drink name"}
+	} else if err != nil {
+		return errlib.WrapError(err, TABLE_NAME, "drink can't be updated")
+	}
+	if res.RowsAffected() == 0 {
+		return errlib.NotFoundErr{Where: TABLE_NAME, What: "drink"}
 	}
+	return nil
+}
+
+// ScanDrink reads a row selected with SelectDrinks
+func ScanDrink(row pgx.Row) (entities.Drink, error) {
+	var d entities.Drink
+	err := row.Scan(&d.ID, &d.Name,
+		&d.Description, &d.Price, &d.Alcoholic, &d.Preparation,
+		&d.Tags, &d.Ingredients,
+		&d.RatingAverage, &d.RatingCount,
+	)
+	return d, err
+}
 
-	return resultDrink, nil
+// ScanDrinks reads all rows selected with SelectDrinks and closes them
+func ScanDrinks(rows pgx.Rows) ([]entities.Drink, error) {
+	defer rows.Close()
+	var drinks []entities.Drink
+	for rows.Next() {
+		d, err := ScanDrink(rows)
+		if err != nil {
+			return nil, errlib.WrapError(err, TABLE_NAME, "drinks")
+		}
+		drinks = append(drinks, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errlib.WrapError(err, TABLE_NAME, "drinks")
+	}
+	return drinks, nil
 }
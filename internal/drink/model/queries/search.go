@@ -0,0 +1,45 @@
+package queries
+
+import (
+	"strings"
+
+	"github.com/SapolovichSV/backprogeng/internal/drink/entities"
+	"github.com/SapolovichSV/backprogeng/internal/errlib"
+)
+
+// SearchDrinks ищет напитки по названию и тегам:
+// по префиксу названия, по триграммам (опечатки) и полнотекстово.
+// Сначала идут совпадения по префиксу, затем по убыванию релевантности
+func (q *Query) SearchDrinks(text string, limit int) ([]entities.Drink, error) {
+	sql := SelectDrinks + `
+	CROSS JOIN LATERAL (SELECT
+		lower(drinks.name) LIKE $3 ESCAPE '\' AS prefix,
+		similarity(lower(drinks.name), $2)
+		+ ts_rank(to_tsvector('simple', drinks.name), plainto_tsquery('simple', $1))
+		+ COALESCE((SELECT max(similarity(lower(tags.name), $2))
+			FROM drink_tags
+			JOIN tags ON tags.id = drink_tags.tag_id
+			WHERE drink_tags.drink_id = drinks.id), 0) / 2 AS rank
+	) AS score
+	WHERE lower(drinks.name) LIKE $3 ESCAPE '\'
+	OR lower(drinks.name) % $2
+	OR to_tsvector('simple', drinks.name) @@ plainto_tsquery('simple', $1)
+	OR EXISTS (SELECT 1
+		FROM drink_tags
+		JOIN tags ON tags.id = drink_tags.tag_id
+		WHERE drink_tags.drink_id = drinks.id
+		AND (lower(tags.name) % $2 OR to_tsvector('simple', tags.name) @@ plainto_tsquery('simple', $1)))
+	ORDER BY score.prefix DESC, score.rank DESC, drinks.id
+	LIMIT $4`
+	lowered := strings.ToLower(strings.TrimSpace(text))
+	rows, err := q.db.Query(q.ctx, sql, text, lowered, escapeLike(lowered)+"%", limit)
+	if err != nil {
+		return nil, errlib.WrapError(err, TABLE_NAME, "drinks")
+	}
+	return ScanDrinks(rows)
+}
+
+// escapeLike экранирует спецсимволы LIKE, чтобы они искались как есть
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -1,52 +1,59 @@
 package queries
 
 import (
-	"database/sql/driver"
-	"fmt"
 	"strings"
-)
 
-type tags []tag
+	"github.com/SapolovichSV/backprogeng/internal/errlib"
+)
 
-type tag struct {
-	Name string
-}
+const TAGS_TABLE_NAME = "tags"
 
-func (t tags) Value() (driver.Value, error) {
-	var tagNames []string
-	for _, v := range t {
-		tagNames = append(tagNames, v.Name)
+// SetTagsToDrink replaces drink tags with the given ones,
+// missing tags are created, tag names are unique case-insensitive
+func (q *Query) SetTagsToDrink(drinkID int, tags []string) error {
+	sql := `DELETE FROM drink_tags
+	WHERE drink_id = $1;`
+	if _, err := q.db.Exec(q.ctx, sql, drinkID); err != nil {
+		return errlib.WrapError(err, "drink_tags", "old tags can't be removed from drink")
 	}
-	return strings.Join(tagNames, ","), nil
-}
-
-func (t *tags) Scan(src interface{}) error {
-	switch v := src.(type) {
-	case string:
-		// Разбиваем строку на теги
-		*t = tags{}
-		for _, name := range strings.Split(v, ",") {
-			*t = append(*t, tag{Name: strings.TrimSpace(name)})
-		}
+	if len(tags) == 0 {
 		return nil
-	case []byte:
-		// Если значение приходит в виде []byte
-		return t.Scan(string(v))
-	default:
-		return fmt.Errorf("could not convert %T to tags", src)
 	}
-}
-func ToTags(tgs []string) tags {
-	var t tags
-	for _, v := range tgs {
-		t = append(t, tag{Name: v})
+	sql = `INSERT INTO tags (name)
+	SELECT DISTINCT btrim(tag)
+	FROM unnest($1::text[]) AS tag
+	WHERE btrim(tag) <> ''
+	ON CONFLICT ((lower(name))) DO NOTHING;`
+	if _, err := q.db.Exec(q.ctx, sql, tags); err != nil {
+		return errlib.WrapError(err, TAGS_TABLE_NAME, "tags can't be created")
+	}
+	sql = `INSERT INTO drink_tags (drink_id, tag_id)
+	SELECT $1, tags.id
+	FROM tags
+	WHERE lower(tags.name) = ANY(SELECT lower(btrim(tag)) FROM unnest($2::text[]) AS tag)
+	ON CONFLICT DO NOTHING;`
+	if _, err := q.db.Exec(q.ctx, sql, drinkID, tags); err != nil {
+		return errlib.WrapError(err, "drink_tags", "tags can't be set to drink")
 	}
-	return t
+	return nil
 }
-func FromTags(t tags) []string {
-	var tgs []string
-	for _, v := range t {
-		tgs = append(tgs, v.Name)
+
+// normalizeTags приводит теги к виду, в котором они сравниваются в бд:
+// нижний регистр, без пробелов по краям и без повторов.
+// Всегда возвращает не nil слайс, чтобы в бд ушёл пустой массив, а не NULL
+func normalizeTags(tags []string) []string {
+	res := []string{}
+	seen := make(map[string]struct{}, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" {
+			continue
+		}
+		if _, ok := seen[tag]; ok {
+			continue
+		}
+		seen[tag] = struct{}{}
+		res = append(res, tag)
 	}
-	return tgs
+	return res
 }
@@ -1,6 +1,14 @@
 package errlib
 
-import "github.com/jackc/pgx/v5"
+import (
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// uniqueViolation is SQLSTATE of postgres unique constraint violation
+const uniqueViolation = "23505"
 
 type NotFoundErr struct {
 	Where string
@@ -11,6 +19,15 @@ func (e NotFoundErr) Error() string {
 	return e.What + " not found in " + e.Where
 }
 
+type ConflictErr struct {
+	Where string
+	What  string
+}
+
+func (e ConflictErr) Error() string {
+	return e.What + " conflicts with existing one in " + e.Where
+}
+
 type UnexpectedErr struct {
 	Where string
 	Err   error
@@ -28,6 +45,20 @@ func CheckErrNotFoundInDB(err error) bool {
 	}
 	return false
 }
+
+// CheckErrConflictInDB reports if err is a unique constraint violation
+func CheckErrConflictInDB(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == uniqueViolation
+}
+func CheckErrNotFound(err error) bool {
+	var notFound NotFoundErr
+	return errors.As(err, &notFound)
+}
+func CheckErrConflict(err error) bool {
+	var conflict ConflictErr
+	return errors.As(err, &conflict)
+}
 func CheckErrUnexpectedInDB(err error) bool {
 	return err != nil
 }
@@ -35,6 +66,9 @@ func WrapError(err error, where string, what string) error {
 	if CheckErrNotFoundInDB(err) {
 		return NotFoundErr{Where: where, What: what}
 	}
+	if CheckErrConflictInDB(err) {
+		return ConflictErr{Where: where, What: what}
+	}
 	if CheckErrUnexpectedInDB(err) {
 		return UnexpectedErr{Where: where, Err: err}
 	}
@@ -0,0 +1,91 @@
+package errlib
+
+import (
+	"errors"
+	"time"
+)
+
+// Codes of error responses, clients can branch on them
+const (
+	CodeBadRequest       = "bad_request"
+	CodeValidationFailed = "validation_failed"
+	CodeUnauthorized     = "unauthorized"
+	CodeForbidden        = "forbidden"
+	CodeNotFound         = "not_found"
+	CodeConflict         = "conflict"
+	CodeTooManyRequests  = "too_many_requests"
+	CodeInternal         = "internal"
+)
+
+// Response is the body of every error response of the api
+type Response struct {
+	Code    string            `json:"code" example:"not_found"`
+	Message string            `json:"message" example:"drink not found in drinks"`
+	Details map[string]string `json:"details,omitempty"`
+	// Errors lists every failed rule when validation fails
+	Errors    []FieldError `json:"errors,omitempty"`
+	RequestID string       `json:"request_id,omitempty" example:"rMsRkJvUXbkUfhdJCxJjSDWYYJXTMWVo"`
+}
+
+// FieldError describes a failed validation rule of the field
+type FieldError struct {
+	Field   string `json:"field" example:"username"`
+	Rule    string `json:"rule" example:"max_length"`
+	Message string `json:"message" example:"must be at most 32 characters"`
+}
+
+// InvalidInputErr is returned when request params or body are malformed
+type InvalidInputErr struct {
+	What   string
+	Reason string
+}
+
+func (e InvalidInputErr) Error() string {
+	return e.What + " " + e.Reason
+}
+
+// UnauthorizedErr is returned when request has no valid credentials
+type UnauthorizedErr struct {
+	Err error
+}
+
+func (e UnauthorizedErr) Error() string {
+	return "unauthorized : " + e.Err.Error()
+}
+func (e UnauthorizedErr) Unwrap() error {
+	return e.Err
+}
+
+// ForbiddenErr is returned when the caller is authenticated
+// but isn't allowed to do the request
+type ForbiddenErr struct {
+	Err error
+}
+
+func (e ForbiddenErr) Error() string {
+	return "forbidden : " + e.Err.Error()
+}
+func (e ForbiddenErr) Unwrap() error {
+	return e.Err
+}
+
+// TooManyRequestsErr is returned when client must wait RetryAfter before the next try
+type TooManyRequestsErr struct {
+	RetryAfter time.Duration
+}
+
+func (e TooManyRequestsErr) Error() string {
+	return "too many requests, retry after " + e.RetryAfter.Round(time.Second).String()
+}
+func CheckErrInvalidInput(err error) bool {
+	var invalid InvalidInputErr
+	return errors.As(err, &invalid)
+}
+func CheckErrUnauthorized(err error) bool {
+	var unauthorized UnauthorizedErr
+	return errors.As(err, &unauthorized)
+}
+func CheckErrForbidden(err error) bool {
+	var forbidden ForbiddenErr
+	return errors.As(err, &forbidden)
+}
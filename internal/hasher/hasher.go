@@ -0,0 +1,154 @@
+package hasher
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	Bcrypt   = "bcrypt"
+	Argon2id = "argon2id"
+)
+
+// параметры argon2id из рекомендаций RFC 9106
+const (
+	argonTime    uint32 = 1
+	argonMemory  uint32 = 64 * 1024
+	argonThreads uint8  = 4
+	argonKeyLen  uint32 = 32
+	argonSaltLen        = 16
+)
+
+var ErrUnknownAlgorithm = errors.New("unknown password hash algorithm")
+var errMalformedHash = errors.New("malformed password hash")
+
+// Hasher hashes passwords with the configured algorithm and verifies
+// hashes made by any supported algorithm, including legacy plaintext values
+type Hasher struct {
+	algorithm string
+	// dummy is a hash of the configured algorithm, see VerifyDummy
+	dummy string
+}
+
+// New returns Hasher for "bcrypt" or "argon2id", empty algorithm means bcrypt
+func New(algorithm string) (*Hasher, error) {
+	switch algorithm {
+	case "":
+		algorithm = Bcrypt
+	case Bcrypt, Argon2id:
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownAlgorithm, algorithm)
+	}
+	h := &Hasher{algorithm: algorithm}
+	dummy, err := h.Hash("dummy password")
+	if err != nil {
+		return nil, err
+	}
+	h.dummy = dummy
+	return h, nil
+}
+
+func (h *Hasher) Hash(password string) (string, error) {
+	if h.algorithm == Argon2id {
+		return hashArgon2id(password)
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// Verify reports if password matches the stored hash, needsRehash is true
+// when the password matches but the hash isn't made by the configured algorithm
+// (e.g. it is a legacy plaintext value) and must be replaced with a fresh one
+func (h *Hasher) Verify(hash, password string) (ok bool, needsRehash bool, err error) {
+	switch {
+	case isBcrypt(hash):
+		err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, false, nil
+		} else if err != nil {
+			return false, false, err
+		}
+		cost, err := bcrypt.Cost([]byte(hash))
+		if err != nil {
+			return false, false, err
+		}
+		return true, h.algorithm != Bcrypt || cost != bcrypt.DefaultCost, nil
+	case strings.HasPrefix(hash, "$"+Argon2id+"$"):
+		ok, outdated, err := verifyArgon2id(hash, password)
+		if err != nil || !ok {
+			return false, false, err
+		}
+		return true, h.algorithm != Argon2id || outdated, nil
+	default:
+		// до хеширования пароли хранились открытым текстом
+		ok := subtle.ConstantTimeCompare([]byte(hash), []byte(password)) == 1
+		return ok, ok, nil
+	}
+}
+
+// VerifyDummy compares password with a fixed hash and takes as long as Verify,
+// it is called when there is no stored hash, e.g. the user doesn't exist
+func (h *Hasher) VerifyDummy(password string) {
+	_, _, _ = h.Verify(h.dummy, password)
+}
+
+func isBcrypt(hash string) bool {
+	return strings.HasPrefix(hash, "$2a$") || strings.HasPrefix(hash, "$2b$") || strings.HasPrefix(hash, "$2y$")
+}
+
+// hashArgon2id кодирует хеш в формате PHC:
+// $argon2id$v=19$m=65536,t=1,p=4$<salt>$<key>
+func hashArgon2id(password string) (string, error) {
+	salt := make([]byte, argonSaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, argonTime, argonMemory, argonThreads, argonKeyLen)
+	return fmt.Sprintf("$%s$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		Argon2id, argon2.Version, argonMemory, argonTime, argonThreads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// verifyArgon2id сверяет пароль с хешем его же параметрами,
+// outdated сообщает, что параметры отличаются от текущих
+func verifyArgon2id(hash, password string) (ok bool, outdated bool, err error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 {
+		return false, false, errMalformedHash
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return false, false, errMalformedHash
+	}
+	var memory, time uint32
+	var threads uint8
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &time, &threads); err != nil {
+		return false, false, errMalformedHash
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return false, false, errMalformedHash
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return false, false, errMalformedHash
+	}
+	other := argon2.IDKey([]byte(password), salt, time, memory, threads, uint32(len(key)))
+	if subtle.ConstantTimeCompare(key, other) != 1 {
+		return false, false, nil
+	}
+	outdated = memory != argonMemory || time != argonTime || threads != argonThreads || uint32(len(key)) != argonKeyLen
+	return true, outdated, nil
+}
@@ -0,0 +1,62 @@
+package hasher
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHasher_Verify(t *testing.T) {
+	bcryptHasher, err := New(Bcrypt)
+	require.NoError(t, err)
+	argonHasher, err := New(Argon2id)
+	require.NoError(t, err)
+
+	bcryptHash, err := bcryptHasher.Hash("amahasla")
+	require.NoError(t, err)
+	argonHash, err := argonHasher.Hash("amahasla")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name            string
+		hasher          *Hasher
+		hash            string
+		password        string
+		wantOK          bool
+		wantNeedsRehash bool
+	}{
+		{"bcrypt", bcryptHasher, bcryptHash, "amahasla", true, false},
+		{"bcrypt_wrong_password", bcryptHasher, bcryptHash, "amahaslo", false, false},
+		{"argon2id", argonHasher, argonHash, "amahasla", true, false},
+		{"argon2id_wrong_password", argonHasher, argonHash, "amahaslo", false, false},
+		{"bcrypt_to_argon2id", argonHasher, bcryptHash, "amahasla", true, true},
+		{"argon2id_to_bcrypt", bcryptHasher, argonHash, "amahasla", true, true},
+		{"legacy_plaintext", bcryptHasher, "amahasla", "amahasla", true, true},
+		{"legacy_plaintext_wrong_password", bcryptHasher, "amahasla", "amahaslo", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, needsRehash, err := tt.hasher.Verify(tt.hash, tt.password)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.wantNeedsRehash, needsRehash)
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	h, err := New("")
+	require.NoError(t, err)
+	assert.Equal(t, Bcrypt, h.algorithm)
+	// заглушка сверяется так же долго, как настоящий хеш
+	assert.True(t, isBcrypt(h.dummy))
+
+	h, err = New(Argon2id)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(h.dummy, "$"+Argon2id+"$"))
+
+	_, err = New("md5")
+	assert.ErrorIs(t, err, ErrUnknownAlgorithm)
+}
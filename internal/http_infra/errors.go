@@ -0,0 +1,112 @@
+package httpinfra
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/SapolovichSV/backprogeng/internal/errlib"
+	"github.com/SapolovichSV/backprogeng/internal/validate"
+	"github.com/labstack/echo/v4"
+)
+
+// ErrorHandler is echo.HTTPErrorHandler which turns errors returned by handlers
+// into errlib.Response with the status and the code matching the error type
+func ErrorHandler(err error, c echo.Context) {
+	if c.Response().Committed {
+		return
+	}
+	status, resp := errorResponse(err)
+	var tooMany errlib.TooManyRequestsErr
+	if errors.As(err, &tooMany) {
+		c.Response().Header().Set(echo.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(tooMany.RetryAfter.Seconds()))))
+	}
+	if status == http.StatusInternalServerError {
+		c.Logger().Error(err)
+	}
+	resp.RequestID = requestID(c)
+	if c.Request().Method == http.MethodHead {
+		err = c.NoContent(status)
+	} else {
+		err = c.JSON(status, resp)
+	}
+	if err != nil {
+		c.Logger().Error(err)
+	}
+}
+
+func errorResponse(err error) (int, errlib.Response) {
+	var (
+		invalid    errlib.InvalidInputErr
+		validation validate.Errors
+		notFound   errlib.NotFoundErr
+		conflict   errlib.ConflictErr
+		unauth     errlib.UnauthorizedErr
+		forbidden  errlib.ForbiddenErr
+		tooMany    errlib.TooManyRequestsErr
+		httpErr    *echo.HTTPError
+	)
+	switch {
+	case errors.As(err, &invalid):
+		return http.StatusBadRequest, errlib.Response{
+			Code:    errlib.CodeBadRequest,
+			Message: invalid.Error(),
+			Details: map[string]string{"field": invalid.What},
+		}
+	case errors.As(err, &validation):
+		return http.StatusBadRequest, errlib.Response{
+			Code:    errlib.CodeValidationFailed,
+			Message: validation.Error(),
+			Errors:  validation,
+		}
+	case errors.As(err, &notFound):
+		return http.StatusNotFound, errlib.Response{Code: errlib.CodeNotFound, Message: notFound.Error()}
+	case errors.As(err, &conflict):
+		return http.StatusConflict, errlib.Response{Code: errlib.CodeConflict, Message: conflict.Error()}
+	case errors.As(err, &unauth):
+		return http.StatusUnauthorized, errlib.Response{Code: errlib.CodeUnauthorized, Message: unauth.Error()}
+	case errors.As(err, &forbidden):
+		return http.StatusForbidden, errlib.Response{Code: errlib.CodeForbidden, Message: forbidden.Error()}
+	case errors.As(err, &tooMany):
+		return http.StatusTooManyRequests, errlib.Response{Code: errlib.CodeTooManyRequests, Message: tooMany.Error()}
+	case errors.As(err, &httpErr):
+		return httpErr.Code, errlib.Response{Code: statusCode(httpErr.Code), Message: fmt.Sprint(httpErr.Message)}
+	default:
+		// подробности неожиданных ошибок только в логах
+		return http.StatusInternalServerError, errlib.Response{
+			Code:    errlib.CodeInternal,
+			Message: http.StatusText(http.StatusInternalServerError),
+		}
+	}
+}
+
+// statusCode делает код ошибки из статуса: 405 -> method_not_allowed
+func statusCode(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return errlib.CodeBadRequest
+	case http.StatusUnauthorized:
+		return errlib.CodeUnauthorized
+	case http.StatusForbidden:
+		return errlib.CodeForbidden
+	case http.StatusNotFound:
+		return errlib.CodeNotFound
+	case http.StatusConflict:
+		return errlib.CodeConflict
+	case http.StatusTooManyRequests:
+		return errlib.CodeTooManyRequests
+	case http.StatusInternalServerError:
+		return errlib.CodeInternal
+	}
+	return strings.ReplaceAll(strings.ToLower(http.StatusText(status)), " ", "_")
+}
+
+func requestID(c echo.Context) string {
+	if id := c.Response().Header().Get(echo.HeaderXRequestID); id != "" {
+		return id
+	}
+	return c.Request().Header.Get(echo.HeaderXRequestID)
+}
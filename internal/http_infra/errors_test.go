@@ -0,0 +1,109 @@
+package httpinfra
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/SapolovichSV/backprogeng/internal/errlib"
+	"github.com/SapolovichSV/backprogeng/internal/validate"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrorHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantBody   errlib.Response
+	}{
+		{
+			name:       "invalid_input",
+			err:        errlib.InvalidInputErr{What: "id", Reason: "must be a number"},
+			wantStatus: http.StatusBadRequest,
+			wantBody: errlib.Response{
+				Code:    errlib.CodeBadRequest,
+				Message: "id must be a number",
+				Details: map[string]string{"field": "id"},
+			},
+		},
+		{
+			name: "validation",
+			err: validate.Errors{
+				{Field: "username", Rule: validate.RuleMaxLength, Message: "must be at most 32 characters"},
+				{Field: "password", Rule: validate.RuleBreached, Message: "is found in leaked passwords, choose another one"},
+			},
+			wantStatus: http.StatusBadRequest,
+			wantBody: errlib.Response{
+				Code:    errlib.CodeValidationFailed,
+				Message: "validation failed: username must be at most 32 characters; password is found in leaked passwords, choose another one",
+				Errors: []errlib.FieldError{
+					{Field: "username", Rule: validate.RuleMaxLength, Message: "must be at most 32 characters"},
+					{Field: "password", Rule: validate.RuleBreached, Message: "is found in leaked passwords, choose another one"},
+				},
+			},
+		},
+		{
+			name:       "wrapped_not_found",
+			err:        errlib.WrapErr(errlib.NotFoundErr{Where: "drinks", What: "drink"}, "drink by id"),
+			wantStatus: http.StatusNotFound,
+			wantBody:   errlib.Response{Code: errlib.CodeNotFound, Message: "drink not found in drinks"},
+		},
+		{
+			name:       "conflict",
+			err:        errlib.ConflictErr{Where: "drinks", What: "drink name"},
+			wantStatus: http.StatusConflict,
+			wantBody:   errlib.Response{Code: errlib.CodeConflict, Message: "drink name conflicts with existing one in drinks"},
+		},
+		{
+			name:       "unauthorized",
+			err:        errlib.UnauthorizedErr{Err: errors.New("no token")},
+			wantStatus: http.StatusUnauthorized,
+			wantBody:   errlib.Response{Code: errlib.CodeUnauthorized, Message: "unauthorized : no token"},
+		},
+		{
+			name:       "forbidden",
+			err:        errlib.ForbiddenErr{Err: errors.New("admin role is required")},
+			wantStatus: http.StatusForbidden,
+			wantBody:   errlib.Response{Code: errlib.CodeForbidden, Message: "forbidden : admin role is required"},
+		},
+		{
+			name:       "too_many_requests",
+			err:        errlib.TooManyRequestsErr{RetryAfter: 90 * time.Second},
+			wantStatus: http.StatusTooManyRequests,
+			wantBody:   errlib.Response{Code: errlib.CodeTooManyRequests, Message: "too many requests, retry after 1m30s"},
+		},
+		{
+			name:       "echo_http_error",
+			err:        echo.ErrMethodNotAllowed,
+			wantStatus: http.StatusMethodNotAllowed,
+			wantBody:   errlib.Response{Code: "method_not_allowed", Message: "Method Not Allowed"},
+		},
+		{
+			name:       "unexpected",
+			err:        errlib.UnexpectedErr{Where: "drinks", Err: errors.New("connection refused")},
+			wantStatus: http.StatusInternalServerError,
+			wantBody:   errlib.Response{Code: errlib.CodeInternal, Message: "Internal Server Error"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set(echo.HeaderXRequestID, "req-1")
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			ErrorHandler(tt.err, c)
+
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			tt.wantBody.RequestID = "req-1"
+			want, _ := json.Marshal(tt.wantBody)
+			assert.JSONEq(t, string(want), rec.Body.String())
+		})
+	}
+}
@@ -16,19 +16,24 @@ type Server struct {
 
 func NewServer(port string) *Server {
 	echo := echo.New()
+	echo.HTTPErrorHandler = ErrorHandler
 	echo.GET("/swagger/*", echoSwagger.WrapHandler)
 	echo.Use(middleware.CORSWithConfig(middleware.CORSConfig{
 		AllowOrigins: []string{"*"},
 		AllowMethods: []string{"GET", "POST", "PUT", "DELETE", "PATCH"},
 	}))
+	echo.Use(middleware.RequestID())
 	echo.Use(middleware.Logger())
 	return &Server{
 		port: port,
 		echo: echo,
 	}
 }
-func (s *Server) GetRouter() *echo.Router {
-	return s.echo.Router()
+
+// Group creates a route group with the prefix, middleware are applied
+// only to the routes of the group
+func (s *Server) Group(prefix string, m ...echo.MiddlewareFunc) *echo.Group {
+	return s.echo.Group(prefix, m...)
 }
 func (s *Server) Start() error {
 	return s.echo.Start(":" + s.port)
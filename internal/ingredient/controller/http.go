@@ -0,0 +1,228 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/SapolovichSV/backprogeng/internal/authmiddleware"
+	"github.com/SapolovichSV/backprogeng/internal/errlib"
+	"github.com/SapolovichSV/backprogeng/internal/ingredient/entities"
+	"github.com/SapolovichSV/backprogeng/internal/pagination"
+	"github.com/labstack/echo/v4"
+)
+
+type storage interface {
+	CreateIngredient(ctx context.Context, ingredient entities.Ingredient) (entities.Ingredient, error)
+	IngredientByID(ctx context.Context, id int) (entities.Ingredient, error)
+	AllIngredients(ctx context.Context, page pagination.Request) (pagination.Page[entities.Ingredient], error)
+	UpdateIngredient(ctx context.Context, ingredient entities.Ingredient) (entities.Ingredient, error)
+	DeleteIngredient(ctx context.Context, id int) error
+	Mix(ctx context.Context, inventory entities.Inventory) (entities.MixResult, error)
+}
+
+type httpHandler struct {
+	st   storage
+	echo *echo.Echo
+	ctx  context.Context
+}
+
+func New(st storage, ctx context.Context) *httpHandler {
+	echo := echo.New()
+	return &httpHandler{
+		st:   st,
+		echo: echo,
+		ctx:  ctx,
+	}
+}
+
+// AddRoutes is a method that adds the routes to the group,
+// catalog changes are available only to admins
+// The routes are:
+// POST /{{group prefix}}/ingredient
+// POST /{{group prefix}}/ingredient/mix
+// and e.t.c
+func (h *httpHandler) AddRoutes(group *echo.Group, authenticate echo.MiddlewareFunc) {
+	ingredients := group.Group("/ingredient")
+	ingredients.GET("", h.allIngredients)
+	ingredients.GET("/:id", h.ingredientByID)
+	ingredients.POST("/mix", h.mix)
+
+	// middleware на маршрутах, иначе неизвестные пути под /ingredient отвечают 401
+	admin := authmiddleware.RequireRole(authmiddleware.RoleAdmin)
+	ingredients.POST("", h.createIngredient, authenticate, admin)
+	ingredients.PUT("/:id", h.updateIngredient, authenticate, admin)
+	ingredients.DELETE("/:id", h.deleteIngredient, authenticate, admin)
+}
+
+// createIngredient godoc
+//
+//	@Summary Creates an ingredient
+//	@Description Adds an ingredient to the catalog, names are unique case-insensitive
+//	@Tags ingredient
+//	@Accept json
+//	@Produce json
+//	@Param ingredient body entities.Ingredient true "Ingredient, id will be ignored"
+//	@Success 201 {object} entities.Ingredient
+//	@Failure 400 {object} errlib.Response
+//	@Failure 401 {object} errlib.Response "Not authenticated"
+//	@Failure 403 {object} errlib.Response "Not admin"
+//	@Failure 409 {object} errlib.Response "Ingredient with such name exists"
+//	@Failure 500 {object} errlib.Response
+//	@Router /ingredient [post]
+func (h *httpHandler) createIngredient(c echo.Context) error {
+	var ingredient entities.Ingredient
+	if err := c.Bind(&ingredient); err != nil {
+		return err
+	}
+	ingredient.Name = strings.TrimSpace(ingredient.Name)
+	if ingredient.Name == "" {
+		return errlib.InvalidInputErr{What: "name", Reason: "is required"}
+	}
+	res, err := h.st.CreateIngredient(h.ctx, ingredient)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusCreated, res)
+}
+
+// allIngredients godoc
+//
+//	@Summary Get all ingredients
+//	@Description Get ingredient catalog page by page in id order
+//	@Tags ingredient
+//	@Accept plain
+//	@Produce json
+//	@Param limit query int false "page size, 20 by default, max 100"
+//	@Param cursor query string false "next_cursor from the previous page"
+//	@Success 200 {object} pagination.Page[entities.Ingredient]
+//	@Failure 400 {object} errlib.Response "Bad limit or cursor"
+//	@Failure 500 {object} errlib.Response
+//	@Router /ingredient [get]
+func (h *httpHandler) allIngredients(c echo.Context) error {
+	page, err := pagination.Parse(c.QueryParam("limit"), c.QueryParam("cursor"))
+	if err != nil {
+		return err
+	}
+	res, err := h.st.AllIngredients(h.ctx, page)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, res)
+}
+
+// ingredientByID godoc
+//
+//	@Summary Get ingredient
+//	@Tags ingredient
+//	@Accept plain
+//	@Produce json
+//	@Param id path int true "Ingredient id"
+//	@Success 200 {object} entities.Ingredient
+//	@Failure 400 {object} errlib.Response
+//	@Failure 404 {object} errlib.Response
+//	@Failure 500 {object} errlib.Response
+//	@Router /ingredient/{id} [get]
+func (h *httpHandler) ingredientByID(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return errlib.InvalidInputErr{What: "id", Reason: "must be a number"}
+	}
+	res, err := h.st.IngredientByID(h.ctx, id)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, res)
+}
+
+// updateIngredient godoc
+//
+//	@Summary Renames an ingredient
+//	@Description Renames an ingredient, recipes which use it will show the new name
+//	@Tags ingredient
+//	@Accept json
+//	@Produce json
+//	@Param id path int true "Ingredient id"
+//	@Param ingredient body entities.Ingredient true "Ingredient with a new name, id will be ignored"
+//	@Success 200 {object} entities.Ingredient
+//	@Failure 400 {object} errlib.Response
+//	@Failure 401 {object} errlib.Response "Not authenticated"
+//	@Failure 403 {object} errlib.Response "Not admin"
+//	@Failure 404 {object} errlib.Response
+//	@Failure 409 {object} errlib.Response "Ingredient with such name exists"
+//	@Failure 500 {object} errlib.Response
+//	@Router /ingredient/{id} [put]
+func (h *httpHandler) updateIngredient(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return errlib.InvalidInputErr{What: "id", Reason: "must be a number"}
+	}
+	var ingredient entities.Ingredient
+	if err := c.Bind(&ingredient); err != nil {
+		return err
+	}
+	ingredient.ID = id
+	ingredient.Name = strings.TrimSpace(ingredient.Name)
+	if ingredient.Name == "" {
+		return errlib.InvalidInputErr{What: "name", Reason: "is required"}
+	}
+	res, err := h.st.UpdateIngredient(h.ctx, ingredient)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, res)
+}
+
+// deleteIngredient godoc
+//
+//	@Summary Deletes an ingredient
+//	@Description Deletes an ingredient which isn't used in any recipe
+//	@Tags ingredient
+//	@Accept plain
+//	@Produce json
+//	@Param id path int true "Ingredient id"
+//	@Success 200 {string} deleted
+//	@Failure 400 {object} errlib.Response
+//	@Failure 401 {object} errlib.Response "Not authenticated"
+//	@Failure 403 {object} errlib.Response "Not admin"
+//	@Failure 404 {object} errlib.Response
+//	@Failure 409 {object} errlib.Response "Ingredient is used in recipes"
+//	@Failure 500 {object} errlib.Response
+//	@Router /ingredient/{id} [delete]
+func (h *httpHandler) deleteIngredient(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return errlib.InvalidInputErr{What: "id", Reason: "must be a number"}
+	}
+	if err := h.st.DeleteIngredient(h.ctx, id); err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, "deleted")
+}
+
+// mix godoc
+//
+//	@Summary What can I mix
+//	@Description Returns drinks which can be made from the posted ingredients
+//	@Description and drinks which miss only one ingredient, with this ingredient named.
+//	@Description Ingredients are compared case-insensitive
+//	@Tags ingredient
+//	@Accept json
+//	@Produce json
+//	@Param inventory body entities.Inventory true "Ingredients on hand"
+//	@Success 200 {object} entities.MixResult
+//	@Failure 400 {object} errlib.Response
+//	@Failure 500 {object} errlib.Response
+//	@Router /ingredient/mix [post]
+func (h *httpHandler) mix(c echo.Context) error {
+	var inventory entities.Inventory
+	if err := c.Bind(&inventory); err != nil {
+		return err
+	}
+	res, err := h.st.Mix(h.ctx, inventory)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, res)
+}
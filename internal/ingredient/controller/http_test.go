@@ -0,0 +1,174 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	drEnt "github.com/SapolovichSV/backprogeng/internal/drink/entities"
+	"github.com/SapolovichSV/backprogeng/internal/errlib"
+	httpinfra "github.com/SapolovichSV/backprogeng/internal/http_infra"
+	"github.com/SapolovichSV/backprogeng/internal/ingredient/entities"
+	mocks "github.com/SapolovichSV/backprogeng/mocks/ingredient"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func Test_httpHandler_createIngredient(t *testing.T) {
+	tests := []struct {
+		name         string
+		reqBody      string
+		mockSetup    func(*mocks.MockIngredientModel)
+		wantHTTPCode int
+	}{
+		{
+			name:    "ok",
+			reqBody: `{"name":" Adelhyde "}`,
+			mockSetup: func(m *mocks.MockIngredientModel) {
+				m.EXPECT().CreateIngredient(gomock.Any(), entities.Ingredient{Name: "Adelhyde"}).
+					Return(entities.Ingredient{ID: 1, Name: "Adelhyde"}, nil)
+			},
+			wantHTTPCode: http.StatusCreated,
+		},
+		{
+			name:         "empty_name",
+			reqBody:      `{"name":" "}`,
+			wantHTTPCode: http.StatusBadRequest,
+		},
+		{
+			name:    "conflict",
+			reqBody: `{"name":"adelhyde"}`,
+			mockSetup: func(m *mocks.MockIngredientModel) {
+				m.EXPECT().CreateIngredient(gomock.Any(), entities.Ingredient{Name: "adelhyde"}).
+					Return(entities.Ingredient{}, errlib.ConflictErr{Where: "ingredients", What: "ingredient adelhyde"})
+			},
+			wantHTTPCode: http.StatusConflict,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockStorage := mocks.NewMockIngredientModel(ctrl)
+			if tt.mockSetup != nil {
+				tt.mockSetup(mockStorage)
+			}
+
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPost, "/ingredient", strings.NewReader(tt.reqBody))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			h := &httpHandler{st: mockStorage, echo: e, ctx: context.Background()}
+			serve(h.createIngredient, c)
+			require.Equal(t, tt.wantHTTPCode, rec.Code)
+		})
+	}
+}
+
+func Test_httpHandler_deleteIngredient(t *testing.T) {
+	tests := []struct {
+		name         string
+		id           string
+		mockSetup    func(*mocks.MockIngredientModel)
+		wantHTTPCode int
+	}{
+		{
+			name: "ok",
+			id:   "1",
+			mockSetup: func(m *mocks.MockIngredientModel) {
+				m.EXPECT().DeleteIngredient(gomock.Any(), 1).Return(nil)
+			},
+			wantHTTPCode: http.StatusOK,
+		},
+		{
+			name:         "bad_id",
+			id:           "one",
+			wantHTTPCode: http.StatusBadRequest,
+		},
+		{
+			name: "not_found",
+			id:   "2",
+			mockSetup: func(m *mocks.MockIngredientModel) {
+				m.EXPECT().DeleteIngredient(gomock.Any(), 2).
+					Return(errlib.NotFoundErr{Where: "ingredients", What: "ingredient"})
+			},
+			wantHTTPCode: http.StatusNotFound,
+		},
+		{
+			name: "used_in_recipes",
+			id:   "3",
+			mockSetup: func(m *mocks.MockIngredientModel) {
+				m.EXPECT().DeleteIngredient(gomock.Any(), 3).
+					Return(errlib.ConflictErr{Where: "drink_ingredients", What: "ingredient used in recipes"})
+			},
+			wantHTTPCode: http.StatusConflict,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockStorage := mocks.NewMockIngredientModel(ctrl)
+			if tt.mockSetup != nil {
+				tt.mockSetup(mockStorage)
+			}
+
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodDelete, "/ingredient/"+tt.id, nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("id")
+			c.SetParamValues(tt.id)
+
+			h := &httpHandler{st: mockStorage, echo: e, ctx: context.Background()}
+			serve(h.deleteIngredient, c)
+			require.Equal(t, tt.wantHTTPCode, rec.Code)
+		})
+	}
+}
+
+func Test_httpHandler_mix(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mocks.NewMockIngredientModel(ctrl)
+	want := entities.MixResult{
+		Full: []drEnt.Drink{{ID: 1, Name: "Bad Touch"}},
+		Almost: []entities.AlmostDrink{
+			{Drink: drEnt.Drink{ID: 2, Name: "Piano Man"}, Missing: "Flanergide"},
+		},
+	}
+	mockStorage.EXPECT().Mix(gomock.Any(), entities.Inventory{
+		Ingredients: []string{"Bronson Extract", "Powdered Delta", "Karmotrine"},
+	}).Return(want, nil)
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/ingredient/mix",
+		strings.NewReader(`{"ingredients":["Bronson Extract","Powdered Delta","Karmotrine"]}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	h := &httpHandler{st: mockStorage, echo: e, ctx: context.Background()}
+	serve(h.mix, c)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	resData, _ := json.Marshal(&want)
+	assert.JSONEq(t, string(resData), rec.Body.String())
+}
+
+// serve вызывает обработчик так же, как echo:
+// возвращённая ошибка превращается в ответ через httpinfra.ErrorHandler
+func serve(handler echo.HandlerFunc, c echo.Context) {
+	if err := handler(c); err != nil {
+		httpinfra.ErrorHandler(err, c)
+	}
+}
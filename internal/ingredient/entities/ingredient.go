@@ -0,0 +1,26 @@
+package entities
+
+import drEnt "github.com/SapolovichSV/backprogeng/internal/drink/entities"
+
+type Ingredient struct {
+	ID   int    `json:"id,omitempty" example:"3"`
+	Name string `json:"name" example:"Adelhyde"`
+}
+
+// Inventory is a set of ingredients the client has on hand
+type Inventory struct {
+	Ingredients []string `json:"ingredients" example:"Adelhyde,Karmotrine"`
+}
+
+// MixResult is what can be mixed from the inventory:
+// drinks with all ingredients on hand and drinks missing only one ingredient
+type MixResult struct {
+	Full   []drEnt.Drink `json:"full"`
+	Almost []AlmostDrink `json:"almost"`
+}
+
+// AlmostDrink is a drink which misses only one ingredient from the inventory
+type AlmostDrink struct {
+	Drink   drEnt.Drink `json:"drink"`
+	Missing string      `json:"missing" example:"Flanergide"`
+}
@@ -0,0 +1,102 @@
+package model
+
+import (
+	"context"
+
+	drEnt "github.com/SapolovichSV/backprogeng/internal/drink/entities"
+	drQueries "github.com/SapolovichSV/backprogeng/internal/drink/model/queries"
+	"github.com/SapolovichSV/backprogeng/internal/ingredient/entities"
+	"github.com/SapolovichSV/backprogeng/internal/ingredient/model/queries"
+	"github.com/SapolovichSV/backprogeng/internal/pagination"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// DB:
+// ingredients
+// id | name
+// drink_ingredients
+// drink_id | ingredient_id | position | amount | unit
+type SQLIngredientModel struct {
+	db *pgxpool.Pool
+}
+type IngredientModel interface {
+	CreateIngredient(ctx context.Context, ingredient entities.Ingredient) (entities.Ingredient, error)
+	IngredientByID(ctx context.Context, id int) (entities.Ingredient, error)
+	AllIngredients(ctx context.Context, page pagination.Request) (pagination.Page[entities.Ingredient], error)
+	UpdateIngredient(ctx context.Context, ingredient entities.Ingredient) (entities.Ingredient, error)
+	DeleteIngredient(ctx context.Context, id int) error
+	Mix(ctx context.Context, inventory entities.Inventory) (entities.MixResult, error)
+}
+
+func New(db *pgxpool.Pool) *SQLIngredientModel {
+	return &SQLIngredientModel{
+		db: db,
+	}
+}
+func (m *SQLIngredientModel) CreateIngredient(ctx context.Context, ingredient entities.Ingredient) (entities.Ingredient, error) {
+	return queries.New(ctx, m.db).CreateIngredient(ingredient.Name)
+}
+func (m *SQLIngredientModel) IngredientByID(ctx context.Context, id int) (entities.Ingredient, error) {
+	return queries.New(ctx, m.db).IngredientByID(id)
+}
+
+// AllIngredients returns ingredient catalog page by page in id order
+func (m *SQLIngredientModel) AllIngredients(ctx context.Context, page pagination.Request) (pagination.Page[entities.Ingredient], error) {
+	ingredients, err := queries.New(ctx, m.db).AllIngredients(page)
+	if err != nil {
+		return pagination.Page[entities.Ingredient]{}, err
+	}
+	return pagination.NewPage(ingredients, page, func(i entities.Ingredient) int { return i.ID }), nil
+}
+
+// UpdateIngredient renames ingredient with ingredient.ID
+func (m *SQLIngredientModel) UpdateIngredient(ctx context.Context, ingredient entities.Ingredient) (entities.Ingredient, error) {
+	return queries.New(ctx, m.db).RenameIngredient(ingredient.ID, ingredient.Name)
+}
+
+// DeleteIngredient deletes ingredient from the catalog,
+// ingredients used in recipes can't be deleted
+func (m *SQLIngredientModel) DeleteIngredient(ctx context.Context, id int) error {
+	return queries.New(ctx, m.db).DeleteIngredient(id)
+}
+
+// Mix returns drinks which can be made from the inventory fully
+// and drinks which miss only one ingredient with this ingredient named
+func (m *SQLIngredientModel) Mix(ctx context.Context, inventory entities.Inventory) (entities.MixResult, error) {
+	res := entities.MixResult{
+		Full:   []drEnt.Drink{},
+		Almost: []entities.AlmostDrink{},
+	}
+	mixable, err := queries.New(ctx, m.db).MixableDrinks(inventory.Ingredients)
+	if err != nil {
+		return entities.MixResult{}, err
+	}
+	if len(mixable) == 0 {
+		return res, nil
+	}
+	ids := make([]int, len(mixable))
+	for i, v := range mixable {
+		ids[i] = v.DrinkID
+	}
+	drinks, err := drQueries.New(ctx, m.db).DrinksByIDs(ids)
+	if err != nil {
+		return entities.MixResult{}, err
+	}
+	byID := make(map[int]drEnt.Drink, len(drinks))
+	for _, d := range drinks {
+		byID[d.ID] = d
+	}
+	for _, v := range mixable {
+		d, ok := byID[v.DrinkID]
+		if !ok {
+			// напиток удалили между запросами
+			continue
+		}
+		if v.Missing == "" {
+			res.Full = append(res.Full, d)
+		} else {
+			res.Almost = append(res.Almost, entities.AlmostDrink{Drink: d, Missing: v.Missing})
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,166 @@
+package model
+
+import (
+	"context"
+	"testing"
+
+	drEnt "github.com/SapolovichSV/backprogeng/internal/drink/entities"
+	drinkModel "github.com/SapolovichSV/backprogeng/internal/drink/model"
+	"github.com/SapolovichSV/backprogeng/internal/errlib"
+	"github.com/SapolovichSV/backprogeng/internal/ingredient/entities"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+)
+
+// sudo docker run --rm --name test-postgres -e POSTGRES_PASSWORD=password -e POSTGRES_USER=username -e POSTGRES_DB=dbname -p 5432:5432 -d postgres
+const QUERY_CREATE_TABLES = `CREATE EXTENSION IF NOT EXISTS pg_trgm;
+CREATE TABLE drinks (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    price INT NOT NULL DEFAULT 0 CHECK (price >= 0),
+    alcoholic BOOLEAN NOT NULL DEFAULT true,
+    preparation VARCHAR(16) NOT NULL DEFAULT 'mixed'
+        CHECK (preparation IN ('mixed', 'aged', 'iced', 'blended'))
+);
+CREATE UNIQUE INDEX drinks_name_lower_key ON drinks (lower(name));
+CREATE TABLE tags (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL
+);
+CREATE UNIQUE INDEX tags_name_lower_idx ON tags (lower(name));
+CREATE TABLE drink_tags (
+    drink_id INT NOT NULL,
+    FOREIGN KEY (drink_id) REFERENCES drinks(id) ON DELETE CASCADE,
+    tag_id INT NOT NULL,
+    FOREIGN KEY (tag_id) REFERENCES tags(id) ON DELETE CASCADE,
+    PRIMARY KEY (drink_id, tag_id)
+);
+CREATE TABLE ingredients (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL
+);
+CREATE UNIQUE INDEX ingredients_name_lower_idx ON ingredients (lower(name));
+CREATE TABLE drink_ingredients (
+    drink_id INT NOT NULL,
+    FOREIGN KEY (drink_id) REFERENCES drinks(id) ON DELETE CASCADE,
+    ingredient_id INT NOT NULL,
+    FOREIGN KEY (ingredient_id) REFERENCES ingredients(id) ON DELETE RESTRICT,
+    position INT NOT NULL,
+    amount NUMERIC(10, 2) NOT NULL CHECK (amount > 0),
+    unit VARCHAR(32) NOT NULL DEFAULT '',
+    PRIMARY KEY (drink_id, ingredient_id)
+);
+CREATE TABLE users (
+    id SERIAL PRIMARY KEY,
+    username VARCHAR(255) NOT NULL,
+    password VARCHAR(255),
+    role VARCHAR(16) NOT NULL DEFAULT 'user'
+);
+CREATE TABLE favs (
+    user_id INT NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES users(id)  ON DELETE CASCADE,
+    drink_id INT NOT NULL,
+   FOREIGN KEY (drink_id) REFERENCES drinks(id) ON DELETE CASCADE,
+    PRIMARY KEY (user_id, drink_id)
+);
+CREATE TABLE ratings (
+    user_id INT NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    drink_id INT NOT NULL,
+    FOREIGN KEY (drink_id) REFERENCES drinks(id) ON DELETE CASCADE,
+    score SMALLINT NOT NULL CHECK (score BETWEEN 1 AND 5),
+    review VARCHAR(1000) NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (user_id, drink_id)
+);`
+const QUERY_DROP_TABLES = `DROP TABLE drink_ingredients CASCADE;
+DROP TABLE ingredients CASCADE;
+DROP TABLE drink_tags CASCADE;
+DROP TABLE tags CASCADE;
+DROP TABLE drinks CASCADE;
+DROP TABLE users CASCADE;
+DROP TABLE favs CASCADE;
+DROP TABLE ratings CASCADE;`
+
+func TestSQLIngredientModel_Mix(t *testing.T) {
+	db, err := pgxpool.New(context.TODO(), "host=localhost user=username password=password dbname=dbname sslmode=disable")
+	if err != nil {
+		t.Fatalf("Failed to connect to the database: %v", err)
+	}
+	defer db.Close()
+	_, err = db.Exec(context.TODO(), QUERY_CREATE_TABLES)
+	defer db.Exec(context.TODO(), QUERY_DROP_TABLES)
+	if err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	drinks := drinkModel.New(db)
+	model := New(db)
+
+	ctx := context.Background()
+	for _, drink := range []drEnt.Drink{
+		{Name: "Bad Touch", Ingredients: []drEnt.Ingredient{
+			{Name: "Bronson Extract", Amount: 2},
+			{Name: "Powdered Delta", Amount: 2},
+			{Name: "Karmotrine", Amount: 4},
+		}},
+		{Name: "Piano Man", Ingredients: []drEnt.Ingredient{
+			{Name: "Bronson Extract", Amount: 2},
+			{Name: "Flanergide", Amount: 3},
+			{Name: "Karmotrine", Amount: 2},
+		}},
+		{Name: "Sugar Rush", Ingredients: []drEnt.Ingredient{
+			{Name: "Adelhyde", Amount: 2},
+			{Name: "Flanergide", Amount: 1},
+		}},
+	} {
+		if _, err := drinks.CreateDrink(ctx, drink); err != nil {
+			t.Fatalf("Failed to create drink: %v", err)
+		}
+	}
+
+	res, err := model.Mix(ctx, entities.Inventory{
+		Ingredients: []string{"bronson extract", "Powdered Delta", "Karmotrine"},
+	})
+	assert.NoError(t, err)
+	if assert.Len(t, res.Full, 1) {
+		assert.Equal(t, "Bad Touch", res.Full[0].Name)
+	}
+	if assert.Len(t, res.Almost, 1) {
+		assert.Equal(t, "Piano Man", res.Almost[0].Drink.Name)
+		assert.Equal(t, "Flanergide", res.Almost[0].Missing)
+	}
+}
+
+func TestSQLIngredientModel_DeleteIngredient(t *testing.T) {
+	db, err := pgxpool.New(context.TODO(), "host=localhost user=username password=password dbname=dbname sslmode=disable")
+	if err != nil {
+		t.Fatalf("Failed to connect to the database: %v", err)
+	}
+	defer db.Close()
+	_, err = db.Exec(context.TODO(), QUERY_CREATE_TABLES)
+	defer db.Exec(context.TODO(), QUERY_DROP_TABLES)
+	if err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	drinks := drinkModel.New(db)
+	model := New(db)
+
+	ctx := context.Background()
+	used, err := model.CreateIngredient(ctx, entities.Ingredient{Name: "Karmotrine"})
+	assert.NoError(t, err)
+	free, err := model.CreateIngredient(ctx, entities.Ingredient{Name: "Adelhyde"})
+	assert.NoError(t, err)
+	_, err = model.CreateIngredient(ctx, entities.Ingredient{Name: "adelhyde"})
+	assert.True(t, errlib.CheckErrConflict(err))
+	_, err = drinks.CreateDrink(ctx, drEnt.Drink{
+		Name:        "Beer",
+		Ingredients: []drEnt.Ingredient{{Name: "Karmotrine", Amount: 1}},
+	})
+	assert.NoError(t, err)
+
+	assert.True(t, errlib.CheckErrConflict(model.DeleteIngredient(ctx, used.ID)))
+	assert.NoError(t, model.DeleteIngredient(ctx, free.ID))
+	assert.True(t, errlib.CheckErrNotFound(model.DeleteIngredient(ctx, free.ID)))
+}
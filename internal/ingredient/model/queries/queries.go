@@ -0,0 +1,157 @@
+package queries
+
+import (
+	"context"
+
+	"github.com/SapolovichSV/backprogeng/internal/dblib"
+	"github.com/SapolovichSV/backprogeng/internal/errlib"
+	"github.com/SapolovichSV/backprogeng/internal/ingredient/entities"
+	"github.com/SapolovichSV/backprogeng/internal/pagination"
+	"github.com/jackc/pgx/v5"
+)
+
+type Query struct {
+	ctx context.Context
+	db  dblib.DBTX
+}
+
+const TABLE_NAME = "ingredients"
+
+// Mixable is a drink which can be mixed from the inventory,
+// Missing is empty if all ingredients are on hand
+type Mixable struct {
+	DrinkID int
+	Missing string
+}
+
+func New(ctx context.Context, db dblib.DBTX) *Query {
+	return &Query{
+		ctx: ctx,
+		db:  db,
+	}
+}
+func (q *Query) CreateIngredient(name string) (entities.Ingredient, error) {
+	sql := `INSERT INTO ingredients (name)
+	VALUES ($1)
+	ON CONFLICT ((lower(name))) DO NOTHING
+	RETURNING id, name;`
+	var ingredient entities.Ingredient
+	err := q.db.QueryRow(q.ctx, sql, name).Scan(&ingredient.ID, &ingredient.Name)
+	if err == pgx.ErrNoRows {
+		return entities.Ingredient{}, errlib.ConflictErr{Where: TABLE_NAME, What: "ingredient " + name}
+	} else if err != nil {
+		return entities.Ingredient{}, errlib.WrapError(err, TABLE_NAME, "ingredient can't be created")
+	}
+	return ingredient, nil
+}
+func (q *Query) IngredientByID(id int) (entities.Ingredient, error) {
+	sql := `SELECT id, name
+	FROM ingredients
+	WHERE id = $1;`
+	var ingredient entities.Ingredient
+	err := q.db.QueryRow(q.ctx, sql, id).Scan(&ingredient.ID, &ingredient.Name)
+	if err != nil {
+		return entities.Ingredient{}, errlib.WrapError(err, TABLE_NAME, "ingredient")
+	}
+	return ingredient, nil
+}
+
+// AllIngredients returns up to page.FetchLimit() ingredients after page.AfterID in id order
+func (q *Query) AllIngredients(page pagination.Request) ([]entities.Ingredient, error) {
+	sql := `SELECT id, name
+	FROM ingredients
+	WHERE id > $1
+	ORDER BY id
+	LIMIT $2;`
+	rows, err := q.db.Query(q.ctx, sql, page.AfterID, page.FetchLimit())
+	if err != nil {
+		return nil, errlib.WrapError(err, TABLE_NAME, "ingredients")
+	}
+	defer rows.Close()
+	var ingredients []entities.Ingredient
+	for rows.Next() {
+		var ingredient entities.Ingredient
+		if err := rows.Scan(&ingredient.ID, &ingredient.Name); err != nil {
+			return nil, errlib.WrapError(err, TABLE_NAME, "ingredients")
+		}
+		ingredients = append(ingredients, ingredient)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errlib.WrapError(err, TABLE_NAME, "ingredients")
+	}
+	return ingredients, nil
+}
+
+// RenameIngredient renames ingredient in the catalog and so in all recipes
+func (q *Query) RenameIngredient(id int, name string) (entities.Ingredient, error) {
+	sql := `UPDATE ingredients
+	SET name = $2
+	WHERE id = $1
+	AND NOT EXISTS (SELECT 1 FROM ingredients WHERE lower(name) = lower($2) AND id <> $1)
+	RETURNING id, name;`
+	var ingredient entities.Ingredient
+	err := q.db.QueryRow(q.ctx, sql, id, name).Scan(&ingredient.ID, &ingredient.Name)
+	if err == pgx.ErrNoRows {
+		// строка не обновилась: либо нет такого ингредиента, либо имя занято
+		if _, err := q.IngredientByID(id); err != nil {
+			return entities.Ingredient{}, err
+		}
+		return entities.Ingredient{}, errlib.ConflictErr{Where: TABLE_NAME, What: "ingredient " + name}
+	} else if err != nil {
+		return entities.Ingredient{}, errlib.WrapError(err, TABLE_NAME, "ingredient can't be renamed")
+	}
+	return ingredient, nil
+}
+
+// DeleteIngredient deletes ingredient which isn't used in any recipe
+func (q *Query) DeleteIngredient(id int) error {
+	sql := `DELETE FROM ingredients
+	WHERE id = $1
+	AND NOT EXISTS (SELECT 1 FROM drink_ingredients WHERE ingredient_id = $1);`
+	res, err := q.db.Exec(q.ctx, sql, id)
+	if err != nil {
+		return errlib.WrapError(err, TABLE_NAME, "ingredient can't be deleted")
+	}
+	if res.RowsAffected() == 0 {
+		if _, err := q.IngredientByID(id); err != nil {
+			return err
+		}
+		return errlib.ConflictErr{Where: "drink_ingredients", What: "ingredient used in recipes"}
+	}
+	return nil
+}
+
+// MixableDrinks returns drinks which recipes miss at most one ingredient
+// from the given ones, drinks without missing ingredients go first
+func (q *Query) MixableDrinks(ingredients []string) ([]Mixable, error) {
+	sql := `WITH have AS (
+		SELECT id
+		FROM ingredients
+		WHERE lower(name) = ANY(SELECT lower(btrim(name)) FROM unnest($1::text[]) AS name)
+	)
+	SELECT drink_ingredients.drink_id,
+		COALESCE(min(ingredients.name) FILTER (WHERE have.id IS NULL), '') AS missing
+	FROM drink_ingredients
+	JOIN ingredients ON ingredients.id = drink_ingredients.ingredient_id
+	LEFT JOIN have ON have.id = drink_ingredients.ingredient_id
+	GROUP BY drink_ingredients.drink_id
+	HAVING count(*) FILTER (WHERE have.id IS NULL) <= 1
+	ORDER BY count(*) FILTER (WHERE have.id IS NULL), drink_ingredients.drink_id;`
+	rows, err := q.db.Query(q.ctx, sql, ingredients)
+	if err != nil {
+		return nil, errlib.WrapError(err, "drink_ingredients", "mixable drinks")
+	}
+	defer rows.Close()
+	var res []Mixable
+	for rows.Next() {
+		var m Mixable
+		if err := rows.Scan(&m.DrinkID, &m.Missing); err != nil {
+			return nil, errlib.WrapError(err, "drink_ingredients", "mixable drinks")
+		}
+		res = append(res, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errlib.WrapError(err, "drink_ingredients", "mixable drinks")
+	}
+	return res, nil
+}
@@ -0,0 +1,99 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strconv"
+
+	"github.com/SapolovichSV/backprogeng/internal/errlib"
+)
+
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+var (
+	ErrBadLimit  = errlib.InvalidInputErr{What: "limit", Reason: "must be a number from 1 to 100"}
+	ErrBadCursor = errlib.InvalidInputErr{What: "cursor", Reason: "is invalid"}
+)
+
+// Request is a keyset page request: at most Limit items
+// which go after the item with id AfterID in id order
+type Request struct {
+	Limit   int
+	AfterID int
+}
+
+// Page is a response envelope for paginated listings,
+// NextCursor is empty when there are no more items
+type Page[T any] struct {
+	Items      []T    `json:"items"`
+	NextCursor string `json:"next_cursor,omitempty" example:"eyJpZCI6MjB9"`
+}
+
+type cursor struct {
+	ID int `json:"id"`
+}
+
+// Parse builds Request from limit and cursor query params,
+// empty params mean the first page with DefaultLimit
+func Parse(limit string, cursor string) (Request, error) {
+	req := Request{Limit: DefaultLimit}
+	if limit != "" {
+		l, err := strconv.Atoi(limit)
+		if err != nil || l < 1 || l > MaxLimit {
+			return Request{}, ErrBadLimit
+		}
+		req.Limit = l
+	}
+	if cursor != "" {
+		id, err := decodeCursor(cursor)
+		if err != nil {
+			return Request{}, ErrBadCursor
+		}
+		req.AfterID = id
+	}
+	return req, nil
+}
+
+// FetchLimit is how many rows must be selected from db:
+// one more than Limit to know if there is a next page
+func (r Request) FetchLimit() int {
+	return r.Limit + 1
+}
+
+// NewPage cuts items selected with FetchLimit to the page
+// and sets the next cursor if there is something after it
+func NewPage[T any](items []T, r Request, idOf func(T) int) Page[T] {
+	if items == nil {
+		items = []T{}
+	}
+	if len(items) <= r.Limit {
+		return Page[T]{Items: items}
+	}
+	items = items[:r.Limit]
+	return Page[T]{
+		Items:      items,
+		NextCursor: EncodeCursor(idOf(items[len(items)-1])),
+	}
+}
+
+func EncodeCursor(id int) string {
+	raw, _ := json.Marshal(cursor{ID: id})
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+func decodeCursor(s string) (int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return 0, err
+	}
+	var c cursor
+	if err := json.Unmarshal(raw, &c); err != nil {
+		return 0, err
+	}
+	if c.ID < 0 {
+		return 0, ErrBadCursor
+	}
+	return c.ID, nil
+}
@@ -0,0 +1,71 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		limit   string
+		cursor  string
+		want    Request
+		wantErr error
+	}{
+		{name: "Defaults", want: Request{Limit: DefaultLimit}},
+		{name: "MinLimit", limit: "1", want: Request{Limit: 1}},
+		{name: "MaxLimit", limit: "100", want: Request{Limit: MaxLimit}},
+		{name: "ZeroLimit", limit: "0", wantErr: ErrBadLimit},
+		{name: "TooBigLimit", limit: "101", wantErr: ErrBadLimit},
+		{name: "NegativeLimit", limit: "-5", wantErr: ErrBadLimit},
+		{name: "NotNumberLimit", limit: "ten", wantErr: ErrBadLimit},
+		{name: "Cursor", limit: "5", cursor: EncodeCursor(42), want: Request{Limit: 5, AfterID: 42}},
+		{name: "NotBase64Cursor", cursor: "broken!", wantErr: ErrBadCursor},
+		{name: "NotJSONCursor", cursor: base64.RawURLEncoding.EncodeToString([]byte("42")), wantErr: ErrBadCursor},
+		{name: "NegativeCursor", cursor: base64.RawURLEncoding.EncodeToString([]byte(`{"id":-1}`)), wantErr: ErrBadCursor},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			have, err := Parse(tt.limit, tt.cursor)
+			if tt.wantErr != nil {
+				assert.Equal(t, tt.wantErr, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, have)
+		})
+	}
+}
+
+func TestCursor_roundTrip(t *testing.T) {
+	for _, id := range []int{0, 1, 20, 1 << 40} {
+		have, err := decodeCursor(EncodeCursor(id))
+		require.NoError(t, err)
+		assert.Equal(t, id, have)
+	}
+}
+
+func TestNewPage(t *testing.T) {
+	idOf := func(i int) int { return i }
+	r := Request{Limit: 2}
+
+	// выбрано FetchLimit строк: есть следующая страница
+	page := NewPage([]int{1, 2, 3}, r, idOf)
+	assert.Equal(t, []int{1, 2}, page.Items)
+	assert.Equal(t, EncodeCursor(2), page.NextCursor)
+
+	page = NewPage([]int{1, 2}, r, idOf)
+	assert.Equal(t, []int{1, 2}, page.Items)
+	assert.Empty(t, page.NextCursor)
+
+	// пустой результат отдаётся как [], а не null
+	page = NewPage[int](nil, r, idOf)
+	assert.Equal(t, []int{}, page.Items)
+	assert.Empty(t, page.NextCursor)
+
+	assert.Equal(t, 3, r.FetchLimit())
+}
@@ -0,0 +1,90 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// FailureLimiter blocks a key (e.g. client ip and username) for lockout
+// after maxFailures failures in a row, failures are forgotten
+// when there were none for lockout
+type FailureLimiter struct {
+	mu          sync.Mutex
+	maxFailures int
+	lockout     time.Duration
+	now         func() time.Time
+	entries     map[string]*failures
+}
+
+type failures struct {
+	count        int
+	last         time.Time
+	blockedUntil time.Time
+}
+
+// sweepSize is the count of keys after which expired keys are removed
+const sweepSize = 1024
+
+func NewFailureLimiter(maxFailures int, lockout time.Duration) *FailureLimiter {
+	return &FailureLimiter{
+		maxFailures: maxFailures,
+		lockout:     lockout,
+		now:         time.Now,
+		entries:     make(map[string]*failures),
+	}
+}
+
+// Blocked returns how long the key stays blocked, 0 if it isn't blocked
+func (l *FailureLimiter) Blocked(key string) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	f, ok := l.entries[key]
+	if !ok {
+		return 0
+	}
+	if wait := f.blockedUntil.Sub(l.now()); wait > 0 {
+		return wait
+	}
+	return 0
+}
+
+// Fail records a failure of the key and blocks it when there are too many
+func (l *FailureLimiter) Fail(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	if len(l.entries) >= sweepSize {
+		l.sweep(now)
+	}
+	f, ok := l.entries[key]
+	if !ok || l.expired(f, now) {
+		f = &failures{}
+		l.entries[key] = f
+	}
+	f.count++
+	f.last = now
+	if f.count >= l.maxFailures {
+		f.blockedUntil = now.Add(l.lockout)
+		f.count = 0
+	}
+}
+
+// Reset forgets failures of the key, e.g. after successful login
+func (l *FailureLimiter) Reset(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.entries, key)
+}
+
+func (l *FailureLimiter) expired(f *failures, now time.Time) bool {
+	return now.Sub(f.last) > l.lockout && !now.Before(f.blockedUntil)
+}
+
+// sweep удаляет ключи, которые уже не заблокированы и давно не ошибались
+func (l *FailureLimiter) sweep(now time.Time) {
+	for key, f := range l.entries {
+		if l.expired(f, now) {
+			delete(l.entries, key)
+		}
+	}
+}
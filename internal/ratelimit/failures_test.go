@@ -0,0 +1,40 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFailureLimiter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	l := NewFailureLimiter(3, time.Minute)
+	l.now = func() time.Time { return now }
+
+	l.Fail("ip user")
+	l.Fail("ip user")
+	assert.Zero(t, l.Blocked("ip user"))
+
+	l.Fail("ip user")
+	assert.Equal(t, time.Minute, l.Blocked("ip user"))
+	assert.Zero(t, l.Blocked("ip other"))
+
+	now = now.Add(30 * time.Second)
+	assert.Equal(t, 30*time.Second, l.Blocked("ip user"))
+
+	now = now.Add(31 * time.Second)
+	assert.Zero(t, l.Blocked("ip user"))
+
+	l.Fail("ip user")
+	l.Fail("ip user")
+	l.Reset("ip user")
+	l.Fail("ip user")
+	assert.Zero(t, l.Blocked("ip user"))
+
+	// старые неудачи забываются
+	now = now.Add(2 * time.Minute)
+	l.Fail("ip user")
+	l.Fail("ip user")
+	assert.Zero(t, l.Blocked("ip user"))
+}
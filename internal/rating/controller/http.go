@@ -0,0 +1,184 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/SapolovichSV/backprogeng/internal/authmiddleware"
+	"github.com/SapolovichSV/backprogeng/internal/errlib"
+	"github.com/SapolovichSV/backprogeng/internal/pagination"
+	"github.com/SapolovichSV/backprogeng/internal/rating/entities"
+	"github.com/labstack/echo/v4"
+)
+
+type storage interface {
+	Rate(ctx context.Context, userID int, drinkID int, rating entities.NewRating) (entities.Rating, error)
+	DeleteRating(ctx context.Context, userID int, drinkID int) error
+	DrinkRatings(ctx context.Context, drinkID int, page pagination.Request) (pagination.Page[entities.Rating], error)
+	UserRatings(ctx context.Context, userID int, page pagination.Request) (pagination.Page[entities.Rating], error)
+}
+
+// inputValidator is implemented by validate.Validator
+type inputValidator interface {
+	Rating(rating entities.NewRating) error
+}
+
+type httpHandler struct {
+	st        storage
+	echo      *echo.Echo
+	ctx       context.Context
+	validator inputValidator
+}
+
+func New(st storage, validator inputValidator, ctx context.Context) *httpHandler {
+	echo := echo.New()
+	return &httpHandler{
+		st:        st,
+		echo:      echo,
+		ctx:       ctx,
+		validator: validator,
+	}
+}
+
+// AddRoutes is a method that adds the routes to the group,
+// ratings are read by everyone, the rater is the authenticated user
+// The routes are:
+// GET /{{group prefix}}/drink/:id/ratings
+// PUT /{{group prefix}}/drink/:id/rating
+// and e.t.c
+func (h *httpHandler) AddRoutes(group *echo.Group, authenticate echo.MiddlewareFunc) {
+	group.GET("/drink/:id/ratings", h.drinkRatings)
+	group.GET("/user/:id/ratings", h.userRatings)
+
+	group.PUT("/drink/:id/rating", h.rate, authenticate)
+	group.DELETE("/drink/:id/rating", h.deleteRating, authenticate)
+}
+
+// rate godoc
+//
+//	@Summary Rates a drink
+//	@Description Rates the drink from 1 to 5 with an optional review by the user which id contains in token,
+//	@Description rating the drink again replaces the previous rating
+//	@Tags rating
+//	@Accept json
+//	@Produce json
+//	@Param id path int true "Drink id"
+//	@Param rating body entities.NewRating true "Score and review"
+//	@Success 200 {object} entities.Rating
+//	@Failure 400 {object} errlib.Response "Score isn't from 1 to 5 or review is too long, every failed rule is in errors"
+//	@Failure 401 {object} errlib.Response "Not authenticated"
+//	@Failure 404 {object} errlib.Response "Drink not found"
+//	@Failure 500 {object} errlib.Response
+//	@Router /drink/{id}/rating [put]
+func (h *httpHandler) rate(c echo.Context) error {
+	principal, err := authmiddleware.PrincipalFrom(c)
+	if err != nil {
+		return err
+	}
+	drinkID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return errlib.InvalidInputErr{What: "id", Reason: "must be a number"}
+	}
+	var rating entities.NewRating
+	if err := c.Bind(&rating); err != nil {
+		return err
+	}
+	if err := h.validator.Rating(rating); err != nil {
+		return err
+	}
+	res, err := h.st.Rate(h.ctx, principal.UserID, drinkID, rating)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, res)
+}
+
+// deleteRating godoc
+//
+//	@Summary Deletes own rating of a drink
+//	@Tags rating
+//	@Accept plain
+//	@Param id path int true "Drink id"
+//	@Success 204
+//	@Failure 400 {object} errlib.Response
+//	@Failure 401 {object} errlib.Response "Not authenticated"
+//	@Failure 404 {object} errlib.Response "Drink isn't rated by the user"
+//	@Failure 500 {object} errlib.Response
+//	@Router /drink/{id}/rating [delete]
+func (h *httpHandler) deleteRating(c echo.Context) error {
+	principal, err := authmiddleware.PrincipalFrom(c)
+	if err != nil {
+		return err
+	}
+	drinkID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return errlib.InvalidInputErr{What: "id", Reason: "must be a number"}
+	}
+	if err := h.st.DeleteRating(h.ctx, principal.UserID, drinkID); err != nil {
+		return err
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// drinkRatings godoc
+//
+//	@Summary Get ratings of a drink
+//	@Description Get ratings and reviews of the drink page by page in user id order
+//	@Tags rating
+//	@Accept plain
+//	@Produce json
+//	@Param id path int true "Drink id"
+//	@Param limit query int false "page size, 20 by default, max 100"
+//	@Param cursor query string false "next_cursor from the previous page"
+//	@Success 200 {object} pagination.Page[entities.Rating]
+//	@Failure 400 {object} errlib.Response "Bad id, limit or cursor"
+//	@Failure 404 {object} errlib.Response "Drink not found"
+//	@Failure 500 {object} errlib.Response
+//	@Router /drink/{id}/ratings [get]
+func (h *httpHandler) drinkRatings(c echo.Context) error {
+	drinkID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return errlib.InvalidInputErr{What: "id", Reason: "must be a number"}
+	}
+	page, err := pagination.Parse(c.QueryParam("limit"), c.QueryParam("cursor"))
+	if err != nil {
+		return err
+	}
+	res, err := h.st.DrinkRatings(h.ctx, drinkID, page)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, res)
+}
+
+// userRatings godoc
+//
+//	@Summary Get ratings given by a user
+//	@Description Get ratings and reviews given by the user page by page in drink id order
+//	@Tags rating
+//	@Accept plain
+//	@Produce json
+//	@Param id path int true "User id"
+//	@Param limit query int false "page size, 20 by default, max 100"
+//	@Param cursor query string false "next_cursor from the previous page"
+//	@Success 200 {object} pagination.Page[entities.Rating]
+//	@Failure 400 {object} errlib.Response "Bad id, limit or cursor"
+//	@Failure 404 {object} errlib.Response "User not found"
+//	@Failure 500 {object} errlib.Response
+//	@Router /user/{id}/ratings [get]
+func (h *httpHandler) userRatings(c echo.Context) error {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return errlib.InvalidInputErr{What: "id", Reason: "must be a number"}
+	}
+	page, err := pagination.Parse(c.QueryParam("limit"), c.QueryParam("cursor"))
+	if err != nil {
+		return err
+	}
+	res, err := h.st.UserRatings(h.ctx, userID, page)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, res)
+}
@@ -0,0 +1,287 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/SapolovichSV/backprogeng/internal/authmiddleware"
+	"github.com/SapolovichSV/backprogeng/internal/config"
+	"github.com/SapolovichSV/backprogeng/internal/errlib"
+	httpinfra "github.com/SapolovichSV/backprogeng/internal/http_infra"
+	"github.com/SapolovichSV/backprogeng/internal/pagination"
+	"github.com/SapolovichSV/backprogeng/internal/rating/entities"
+	"github.com/SapolovichSV/backprogeng/internal/validate"
+	mocks "github.com/SapolovichSV/backprogeng/mocks/rating"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func Test_httpHandler_rate(t *testing.T) {
+	tests := []struct {
+		name         string
+		id           string
+		reqBody      string
+		noPrincipal  bool
+		mockSetup    func(*mocks.MockRatingModel)
+		wantHTTPCode int
+	}{
+		{
+			name:    "ok",
+			id:      "12",
+			reqBody: `{"score":5,"review":"Sour and tasty"}`,
+			mockSetup: func(m *mocks.MockRatingModel) {
+				m.EXPECT().Rate(gomock.Any(), 7, 12, entities.NewRating{Score: 5, Review: "Sour and tasty"}).
+					Return(entities.Rating{UserID: 7, DrinkID: 12, Score: 5, Review: "Sour and tasty"}, nil)
+			},
+			wantHTTPCode: http.StatusOK,
+		},
+		{
+			name:         "score_too_low",
+			id:           "12",
+			reqBody:      `{"score":0}`,
+			wantHTTPCode: http.StatusBadRequest,
+		},
+		{
+			name:         "score_too_high",
+			id:           "12",
+			reqBody:      `{"score":6}`,
+			wantHTTPCode: http.StatusBadRequest,
+		},
+		{
+			name:         "review_too_long",
+			id:           "12",
+			reqBody:      `{"score":3,"review":"` + strings.Repeat("ы", entities.ReviewMaxLength+1) + `"}`,
+			wantHTTPCode: http.StatusBadRequest,
+		},
+		{
+			name:         "bad_id",
+			id:           "cola",
+			reqBody:      `{"score":3}`,
+			wantHTTPCode: http.StatusBadRequest,
+		},
+		{
+			name:    "unknown_drink",
+			id:      "13",
+			reqBody: `{"score":3}`,
+			mockSetup: func(m *mocks.MockRatingModel) {
+				m.EXPECT().Rate(gomock.Any(), 7, 13, entities.NewRating{Score: 3}).
+					Return(entities.Rating{}, errlib.NotFoundErr{Where: "drinks", What: "drink"})
+			},
+			wantHTTPCode: http.StatusNotFound,
+		},
+		{
+			name:         "not_authenticated",
+			id:           "12",
+			reqBody:      `{"score":3}`,
+			noPrincipal:  true,
+			wantHTTPCode: http.StatusUnauthorized,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockStorage := mocks.NewMockRatingModel(ctrl)
+			if tt.mockSetup != nil {
+				tt.mockSetup(mockStorage)
+			}
+
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPut, "/drink/"+tt.id+"/rating", strings.NewReader(tt.reqBody))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("id")
+			c.SetParamValues(tt.id)
+			if !tt.noPrincipal {
+				authmiddleware.SetPrincipal(c, authmiddleware.Principal{UserID: 7, Role: authmiddleware.RoleUser})
+			}
+
+			h := &httpHandler{st: mockStorage, echo: e, ctx: context.Background(), validator: newTestValidator(t)}
+			serve(h.rate, c)
+			require.Equal(t, tt.wantHTTPCode, rec.Code)
+		})
+	}
+}
+
+func Test_httpHandler_rate_validationErrors(t *testing.T) {
+	e := echo.New()
+	body := `{"score":0,"review":"` + strings.Repeat("ы", entities.ReviewMaxLength+1) + `"}`
+	req := httptest.NewRequest(http.MethodPut, "/drink/12/rating", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues("12")
+	authmiddleware.SetPrincipal(c, authmiddleware.Principal{UserID: 7, Role: authmiddleware.RoleUser})
+
+	h := &httpHandler{st: nil, echo: e, ctx: context.Background(), validator: newTestValidator(t)}
+	serve(h.rate, c)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+
+	var resp errlib.Response
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, []errlib.FieldError{
+		{Field: "score", Rule: validate.RuleMin, Message: "must be at least 1"},
+		{Field: "review", Rule: validate.RuleMaxLength, Message: "must be at most 1000 characters"},
+	}, resp.Errors)
+}
+
+func Test_httpHandler_deleteRating(t *testing.T) {
+	tests := []struct {
+		name         string
+		id           string
+		mockSetup    func(*mocks.MockRatingModel)
+		wantHTTPCode int
+	}{
+		{
+			name: "ok",
+			id:   "12",
+			mockSetup: func(m *mocks.MockRatingModel) {
+				m.EXPECT().DeleteRating(gomock.Any(), 7, 12).Return(nil)
+			},
+			wantHTTPCode: http.StatusNoContent,
+		},
+		{
+			name: "not_rated",
+			id:   "13",
+			mockSetup: func(m *mocks.MockRatingModel) {
+				m.EXPECT().DeleteRating(gomock.Any(), 7, 13).
+					Return(errlib.NotFoundErr{Where: "ratings", What: "rating"})
+			},
+			wantHTTPCode: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockStorage := mocks.NewMockRatingModel(ctrl)
+			if tt.mockSetup != nil {
+				tt.mockSetup(mockStorage)
+			}
+
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodDelete, "/drink/"+tt.id+"/rating", nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("id")
+			c.SetParamValues(tt.id)
+			authmiddleware.SetPrincipal(c, authmiddleware.Principal{UserID: 7, Role: authmiddleware.RoleUser})
+
+			h := &httpHandler{st: mockStorage, echo: e, ctx: context.Background(), validator: newTestValidator(t)}
+			serve(h.deleteRating, c)
+			require.Equal(t, tt.wantHTTPCode, rec.Code)
+		})
+	}
+}
+
+func Test_httpHandler_drinkRatings(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mocks.NewMockRatingModel(ctrl)
+	want := pagination.Page[entities.Rating]{
+		Items:      []entities.Rating{{UserID: 7, Username: "Stas001", DrinkID: 12, DrinkName: "Bad Touch", Score: 4}},
+		NextCursor: pagination.EncodeCursor(7),
+	}
+	mockStorage.EXPECT().DrinkRatings(gomock.Any(), 12, pagination.Request{Limit: 1}).Return(want, nil)
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/drink/12/ratings?limit=1", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues("12")
+
+	h := &httpHandler{st: mockStorage, echo: e, ctx: context.Background(), validator: newTestValidator(t)}
+	serve(h.drinkRatings, c)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	resData, _ := json.Marshal(&want)
+	assert.JSONEq(t, string(resData), rec.Body.String())
+}
+
+func Test_httpHandler_userRatings(t *testing.T) {
+	tests := []struct {
+		name         string
+		id           string
+		mockSetup    func(*mocks.MockRatingModel)
+		wantHTTPCode int
+	}{
+		{
+			name: "ok",
+			id:   "7",
+			mockSetup: func(m *mocks.MockRatingModel) {
+				m.EXPECT().UserRatings(gomock.Any(), 7, pagination.Request{Limit: pagination.DefaultLimit}).
+					Return(pagination.Page[entities.Rating]{Items: []entities.Rating{}}, nil)
+			},
+			wantHTTPCode: http.StatusOK,
+		},
+		{
+			name: "unknown_user",
+			id:   "8",
+			mockSetup: func(m *mocks.MockRatingModel) {
+				m.EXPECT().UserRatings(gomock.Any(), 8, pagination.Request{Limit: pagination.DefaultLimit}).
+					Return(pagination.Page[entities.Rating]{}, errlib.NotFoundErr{Where: "users", What: "user"})
+			},
+			wantHTTPCode: http.StatusNotFound,
+		},
+		{
+			name:         "bad_id",
+			id:           "me",
+			wantHTTPCode: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockStorage := mocks.NewMockRatingModel(ctrl)
+			if tt.mockSetup != nil {
+				tt.mockSetup(mockStorage)
+			}
+
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/user/"+tt.id+"/ratings", nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("id")
+			c.SetParamValues(tt.id)
+
+			h := &httpHandler{st: mockStorage, echo: e, ctx: context.Background(), validator: newTestValidator(t)}
+			serve(h.userRatings, c)
+			require.Equal(t, tt.wantHTTPCode, rec.Code)
+		})
+	}
+}
+
+func newTestValidator(t *testing.T) *validate.Validator {
+	v, err := validate.New(config.Validation{
+		UsernameMinLength: 4,
+		UsernameMaxLength: 32,
+		UsernameCharset:   "A-Za-z0-9_.-",
+		PasswordMinLength: 8,
+		PasswordMaxLength: 72,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+// serve вызывает обработчик так же, как echo:
+// возвращённая ошибка превращается в ответ через httpinfra.ErrorHandler
+func serve(handler echo.HandlerFunc, c echo.Context) {
+	if err := handler(c); err != nil {
+		httpinfra.ErrorHandler(err, c)
+	}
+}
@@ -0,0 +1,30 @@
+package entities
+
+import "time"
+
+// Limits of the rating, the same as in the ratings table
+const (
+	MinScore        = 1
+	MaxScore        = 5
+	ReviewMaxLength = 1000
+)
+
+// Rating is a score of the drink given by the user with an optional review,
+// the user has at most one rating of the drink
+type Rating struct {
+	UserID    int       `json:"user_id" example:"7"`
+	Username  string    `json:"username" example:"Stas001"`
+	DrinkID   int       `json:"drink_id" example:"12"`
+	DrinkName string    `json:"drink_name" example:"Bad Touch"`
+	Score     int       `json:"score" example:"5" minimum:"1" maximum:"5"`
+	Review    string    `json:"review" example:"Sour and tasty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// NewRating is sent by the user to rate the drink,
+// rating the drink again replaces the previous rating
+type NewRating struct {
+	Score  int    `json:"score" example:"5" minimum:"1" maximum:"5"`
+	Review string `json:"review" example:"Sour and tasty"`
+}
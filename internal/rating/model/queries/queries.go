@@ -0,0 +1,140 @@
+package queries
+
+import (
+	"context"
+
+	"github.com/SapolovichSV/backprogeng/internal/dblib"
+	"github.com/SapolovichSV/backprogeng/internal/errlib"
+	"github.com/SapolovichSV/backprogeng/internal/pagination"
+	"github.com/SapolovichSV/backprogeng/internal/rating/entities"
+	"github.com/jackc/pgx/v5"
+)
+
+type Query struct {
+	ctx context.Context
+	db  dblib.DBTX
+}
+
+const TABLE_NAME = "ratings"
+
+// selectRatings выбирает оценки вместе с именем пользователя и названием напитка,
+// строки читаются через scanRating
+const selectRatings = `SELECT ratings.user_id, users.username,
+	ratings.drink_id, drinks.name,
+	ratings.score, ratings.review, ratings.created_at, ratings.updated_at
+	FROM ratings
+	JOIN users ON users.id = ratings.user_id
+	JOIN drinks ON drinks.id = ratings.drink_id`
+
+func New(ctx context.Context, db dblib.DBTX) *Query {
+	return &Query{
+		ctx: ctx,
+		db:  db,
+	}
+}
+
+// UpsertRating rates the drink or replaces the previous rating of the user,
+// errlib.NotFoundErr is returned if there is no such drink
+func (q *Query) UpsertRating(userID int, drinkID int, rating entities.NewRating) (entities.Rating, error) {
+	// CTE называется ratings, поэтому selectRatings читает только что записанную строку
+	sql := `WITH ratings AS (
+		INSERT INTO ratings (user_id, drink_id, score, review)
+		SELECT $1, drinks.id, $3, $4
+		FROM drinks
+		WHERE drinks.id = $2
+		ON CONFLICT (user_id, drink_id) DO UPDATE
+		SET score = EXCLUDED.score, review = EXCLUDED.review, updated_at = now()
+		RETURNING *
+	)
+	` + selectRatings + `;`
+	res, err := scanRating(q.db.QueryRow(q.ctx, sql, userID, drinkID, rating.Score, rating.Review))
+	if err != nil {
+		return entities.Rating{}, errlib.WrapError(err, "drinks", "drink")
+	}
+	return res, nil
+}
+
+// DeleteRating deletes the rating of the drink given by the user
+func (q *Query) DeleteRating(userID int, drinkID int) error {
+	sql := `DELETE FROM ratings
+	WHERE user_id = $1 AND drink_id = $2;`
+	res, err := q.db.Exec(q.ctx, sql, userID, drinkID)
+	if err != nil {
+		return errlib.WrapError(err, TABLE_NAME, "rating can't be deleted")
+	}
+	if res.RowsAffected() == 0 {
+		return errlib.NotFoundErr{Where: TABLE_NAME, What: "rating"}
+	}
+	return nil
+}
+
+// RatingsByDrinkID returns up to page.FetchLimit() ratings of the drink
+// after the user page.AfterID in user id order
+func (q *Query) RatingsByDrinkID(drinkID int, page pagination.Request) ([]entities.Rating, error) {
+	sql := selectRatings + `
+	WHERE ratings.drink_id = $1 AND ratings.user_id > $2
+	ORDER BY ratings.user_id
+	LIMIT $3;`
+	rows, err := q.db.Query(q.ctx, sql, drinkID, page.AfterID, page.FetchLimit())
+	if err != nil {
+		return nil, errlib.WrapError(err, TABLE_NAME, "ratings")
+	}
+	return scanRatings(rows)
+}
+
+// RatingsByUserID returns up to page.FetchLimit() ratings given by the user
+// after the drink page.AfterID in drink id order
+func (q *Query) RatingsByUserID(userID int, page pagination.Request) ([]entities.Rating, error) {
+	sql := selectRatings + `
+	WHERE ratings.user_id = $1 AND ratings.drink_id > $2
+	ORDER BY ratings.drink_id
+	LIMIT $3;`
+	rows, err := q.db.Query(q.ctx, sql, userID, page.AfterID, page.FetchLimit())
+	if err != nil {
+		return nil, errlib.WrapError(err, TABLE_NAME, "ratings")
+	}
+	return scanRatings(rows)
+}
+
+// DrinkExists reports errlib.NotFoundErr if there is no drink with the id
+func (q *Query) DrinkExists(drinkID int) error {
+	sql := `SELECT 1 FROM drinks WHERE id = $1;`
+	var one int
+	if err := q.db.QueryRow(q.ctx, sql, drinkID).Scan(&one); err != nil {
+		return errlib.WrapError(err, "drinks", "drink")
+	}
+	return nil
+}
+
+// UserExists reports errlib.NotFoundErr if there is no user with the id
+func (q *Query) UserExists(userID int) error {
+	sql := `SELECT 1 FROM users WHERE id = $1;`
+	var one int
+	if err := q.db.QueryRow(q.ctx, sql, userID).Scan(&one); err != nil {
+		return errlib.WrapError(err, "users", "user")
+	}
+	return nil
+}
+func scanRating(row pgx.Row) (entities.Rating, error) {
+	var r entities.Rating
+	err := row.Scan(&r.UserID, &r.Username,
+		&r.DrinkID, &r.DrinkName,
+		&r.Score, &r.Review, &r.CreatedAt, &r.UpdatedAt,
+	)
+	return r, err
+}
+func scanRatings(rows pgx.Rows) ([]entities.Rating, error) {
+	defer rows.Close()
+	var ratings []entities.Rating
+	for rows.Next() {
+		r, err := scanRating(rows)
+		if err != nil {
+			return nil, errlib.WrapError(err, TABLE_NAME, "ratings")
+		}
+		ratings = append(ratings, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errlib.WrapError(err, TABLE_NAME, "ratings")
+	}
+	return ratings, nil
+}
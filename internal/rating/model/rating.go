@@ -0,0 +1,73 @@
+package model
+
+import (
+	"context"
+
+	"github.com/SapolovichSV/backprogeng/internal/pagination"
+	"github.com/SapolovichSV/backprogeng/internal/rating/entities"
+	"github.com/SapolovichSV/backprogeng/internal/rating/model/queries"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// DB:
+// ratings
+// user_id | drink_id | score | review | created_at | updated_at
+type SQLRatingModel struct {
+	db *pgxpool.Pool
+}
+type RatingModel interface {
+	Rate(ctx context.Context, userID int, drinkID int, rating entities.NewRating) (entities.Rating, error)
+	DeleteRating(ctx context.Context, userID int, drinkID int) error
+	DrinkRatings(ctx context.Context, drinkID int, page pagination.Request) (pagination.Page[entities.Rating], error)
+	UserRatings(ctx context.Context, userID int, page pagination.Request) (pagination.Page[entities.Rating], error)
+}
+
+func New(db *pgxpool.Pool) *SQLRatingModel {
+	return &SQLRatingModel{
+		db: db,
+	}
+}
+
+// Rate rates the drink by the user, rating the drink again replaces the previous rating
+func (m *SQLRatingModel) Rate(ctx context.Context, userID int, drinkID int, rating entities.NewRating) (entities.Rating, error) {
+	return queries.New(ctx, m.db).UpsertRating(userID, drinkID, rating)
+}
+
+// DeleteRating deletes the rating of the drink by the user,
+// errlib.NotFoundErr is returned if the user hasn't rated the drink
+func (m *SQLRatingModel) DeleteRating(ctx context.Context, userID int, drinkID int) error {
+	return queries.New(ctx, m.db).DeleteRating(userID, drinkID)
+}
+
+// DrinkRatings returns ratings of the drink page by page in user id order,
+// errlib.NotFoundErr is returned if there is no such drink
+func (m *SQLRatingModel) DrinkRatings(ctx context.Context, drinkID int, page pagination.Request) (pagination.Page[entities.Rating], error) {
+	query := queries.New(ctx, m.db)
+	ratings, err := query.RatingsByDrinkID(drinkID, page)
+	if err != nil {
+		return pagination.Page[entities.Rating]{}, err
+	}
+	// пустая страница: либо оценок нет, либо нет самого напитка
+	if len(ratings) == 0 {
+		if err := query.DrinkExists(drinkID); err != nil {
+			return pagination.Page[entities.Rating]{}, err
+		}
+	}
+	return pagination.NewPage(ratings, page, func(r entities.Rating) int { return r.UserID }), nil
+}
+
+// UserRatings returns ratings given by the user page by page in drink id order,
+// errlib.NotFoundErr is returned if there is no such user
+func (m *SQLRatingModel) UserRatings(ctx context.Context, userID int, page pagination.Request) (pagination.Page[entities.Rating], error) {
+	query := queries.New(ctx, m.db)
+	ratings, err := query.RatingsByUserID(userID, page)
+	if err != nil {
+		return pagination.Page[entities.Rating]{}, err
+	}
+	if len(ratings) == 0 {
+		if err := query.UserExists(userID); err != nil {
+			return pagination.Page[entities.Rating]{}, err
+		}
+	}
+	return pagination.NewPage(ratings, page, func(r entities.Rating) int { return r.DrinkID }), nil
+}
@@ -0,0 +1,153 @@
+package model
+
+import (
+	"context"
+	"testing"
+
+	drEnt "github.com/SapolovichSV/backprogeng/internal/drink/entities"
+	drinkModel "github.com/SapolovichSV/backprogeng/internal/drink/model"
+	drQueries "github.com/SapolovichSV/backprogeng/internal/drink/model/queries"
+	"github.com/SapolovichSV/backprogeng/internal/errlib"
+	"github.com/SapolovichSV/backprogeng/internal/pagination"
+	"github.com/SapolovichSV/backprogeng/internal/rating/entities"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+)
+
+// sudo docker run --rm --name test-postgres -e POSTGRES_PASSWORD=password -e POSTGRES_USER=username -e POSTGRES_DB=dbname -p 5432:5432 -d postgres
+const QUERY_CREATE_TABLES = `CREATE EXTENSION IF NOT EXISTS pg_trgm;
+CREATE TABLE drinks (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    price INT NOT NULL DEFAULT 0 CHECK (price >= 0),
+    alcoholic BOOLEAN NOT NULL DEFAULT true,
+    preparation VARCHAR(16) NOT NULL DEFAULT 'mixed'
+        CHECK (preparation IN ('mixed', 'aged', 'iced', 'blended'))
+);
+CREATE UNIQUE INDEX drinks_name_lower_key ON drinks (lower(name));
+CREATE TABLE tags (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL
+);
+CREATE UNIQUE INDEX tags_name_lower_idx ON tags (lower(name));
+CREATE TABLE drink_tags (
+    drink_id INT NOT NULL,
+    FOREIGN KEY (drink_id) REFERENCES drinks(id) ON DELETE CASCADE,
+    tag_id INT NOT NULL,
+    FOREIGN KEY (tag_id) REFERENCES tags(id) ON DELETE CASCADE,
+    PRIMARY KEY (drink_id, tag_id)
+);
+CREATE TABLE ingredients (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL
+);
+CREATE UNIQUE INDEX ingredients_name_lower_idx ON ingredients (lower(name));
+CREATE TABLE drink_ingredients (
+    drink_id INT NOT NULL,
+    FOREIGN KEY (drink_id) REFERENCES drinks(id) ON DELETE CASCADE,
+    ingredient_id INT NOT NULL,
+    FOREIGN KEY (ingredient_id) REFERENCES ingredients(id) ON DELETE RESTRICT,
+    position INT NOT NULL,
+    amount NUMERIC(10, 2) NOT NULL CHECK (amount > 0),
+    unit VARCHAR(32) NOT NULL DEFAULT '',
+    PRIMARY KEY (drink_id, ingredient_id)
+);
+CREATE TABLE users (
+    id SERIAL PRIMARY KEY,
+    username VARCHAR(255) NOT NULL,
+    password VARCHAR(255),
+    role VARCHAR(16) NOT NULL DEFAULT 'user'
+);
+CREATE TABLE favs (
+    user_id INT NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES users(id)  ON DELETE CASCADE,
+    drink_id INT NOT NULL,
+   FOREIGN KEY (drink_id) REFERENCES drinks(id) ON DELETE CASCADE,
+    PRIMARY KEY (user_id, drink_id)
+);
+CREATE TABLE ratings (
+    user_id INT NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    drink_id INT NOT NULL,
+    FOREIGN KEY (drink_id) REFERENCES drinks(id) ON DELETE CASCADE,
+    score SMALLINT NOT NULL CHECK (score BETWEEN 1 AND 5),
+    review VARCHAR(1000) NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (user_id, drink_id)
+);`
+const QUERY_DROP_TABLES = `DROP TABLE drink_ingredients CASCADE;
+DROP TABLE ingredients CASCADE;
+DROP TABLE drink_tags CASCADE;
+DROP TABLE tags CASCADE;
+DROP TABLE drinks CASCADE;
+DROP TABLE users CASCADE;
+DROP TABLE favs CASCADE;
+DROP TABLE ratings CASCADE;`
+
+func TestSQLRatingModel(t *testing.T) {
+	db, err := pgxpool.New(context.TODO(), "host=localhost user=username password=password dbname=dbname sslmode=disable")
+	if err != nil {
+		t.Fatalf("Failed to connect to the database: %v", err)
+	}
+	defer db.Close()
+	_, err = db.Exec(context.TODO(), QUERY_CREATE_TABLES)
+	defer db.Exec(context.TODO(), QUERY_DROP_TABLES)
+	if err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	ctx := context.Background()
+	drink, err := drinkModel.New(db).CreateDrink(ctx, drEnt.Drink{Name: "Bad Touch"})
+	if err != nil {
+		t.Fatalf("Failed to create drink: %v", err)
+	}
+	var stas, vova int
+	err = db.QueryRow(ctx, `INSERT INTO users (username, password) VALUES ('Stas001', '') RETURNING id`).Scan(&stas)
+	assert.NoError(t, err)
+	err = db.QueryRow(ctx, `INSERT INTO users (username, password) VALUES ('Vova001', '') RETURNING id`).Scan(&vova)
+	assert.NoError(t, err)
+	m := New(db)
+
+	rating, err := m.Rate(ctx, stas, drink.ID, entities.NewRating{Score: 2, Review: "too sour"})
+	assert.NoError(t, err)
+	assert.Equal(t, "Stas001", rating.Username)
+	assert.Equal(t, "Bad Touch", rating.DrinkName)
+	// повторная оценка заменяет прежнюю
+	rating, err = m.Rate(ctx, stas, drink.ID, entities.NewRating{Score: 4, Review: "tasty after all"})
+	assert.NoError(t, err)
+	assert.Equal(t, 4, rating.Score)
+	assert.Equal(t, "tasty after all", rating.Review)
+	_, err = m.Rate(ctx, vova, drink.ID, entities.NewRating{Score: 5})
+	assert.NoError(t, err)
+	_, err = m.Rate(ctx, stas, drink.ID+100, entities.NewRating{Score: 5})
+	assert.True(t, errlib.CheckErrNotFound(err))
+
+	rated, err := drQueries.New(ctx, db).DrinkByID(drink.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 4.5, rated.RatingAverage)
+	assert.Equal(t, 2, rated.RatingCount)
+
+	page, err := m.DrinkRatings(ctx, drink.ID, pagination.Request{Limit: 1})
+	assert.NoError(t, err)
+	if assert.Len(t, page.Items, 1) {
+		assert.Equal(t, stas, page.Items[0].UserID)
+	}
+	assert.NotEmpty(t, page.NextCursor)
+	page, err = m.UserRatings(ctx, vova, pagination.Request{Limit: 10})
+	assert.NoError(t, err)
+	if assert.Len(t, page.Items, 1) {
+		assert.Equal(t, 5, page.Items[0].Score)
+	}
+
+	assert.NoError(t, m.DeleteRating(ctx, vova, drink.ID))
+	assert.True(t, errlib.CheckErrNotFound(m.DeleteRating(ctx, vova, drink.ID)))
+	// у пользователя нет оценок, но сам он есть
+	page, err = m.UserRatings(ctx, vova, pagination.Request{Limit: 10})
+	assert.NoError(t, err)
+	assert.Empty(t, page.Items)
+	_, err = m.UserRatings(ctx, vova+100, pagination.Request{Limit: 10})
+	assert.True(t, errlib.CheckErrNotFound(err))
+	_, err = m.DrinkRatings(ctx, drink.ID+100, pagination.Request{Limit: 10})
+	assert.True(t, errlib.CheckErrNotFound(err))
+}
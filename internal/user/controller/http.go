@@ -2,9 +2,17 @@ package controller
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/SapolovichSV/backprogeng/internal/authmiddleware"
+	drEnt "github.com/SapolovichSV/backprogeng/internal/drink/entities"
+	"github.com/SapolovichSV/backprogeng/internal/errlib"
+	"github.com/SapolovichSV/backprogeng/internal/pagination"
 	"github.com/SapolovichSV/backprogeng/internal/user/entities"
 	"github.com/labstack/echo/v4"
 )
@@ -12,35 +20,85 @@ import (
 type storage interface {
 	CreateUser(context.Context, entities.User) (entities.User, error)
 	UserByID(context.Context, int) (entities.User, error)
+	UserByUsername(ctx context.Context, username string) (entities.User, error)
+	VerifyPassword(ctx context.Context, userID int, password string) error
+	VerifyUnknownUserPassword(ctx context.Context, password string) error
+	SetRole(ctx context.Context, userID int, role string) (entities.User, error)
 	AddFav(ctx context.Context, drinkName string, userID int) (entities.User, error)
+	RemoveFav(ctx context.Context, drinkName string, userID int) error
+	Favourites(ctx context.Context, userID int, page pagination.Request) (pagination.Page[drEnt.Drink], error)
+	Recommendations(ctx context.Context, userID int, limit int) ([]entities.Recommendation, error)
 }
 type authService interface {
 	Auth(c echo.Context) (entities.User, error)
-	Login(c echo.Context) (entities.User, error)
-	Register(c echo.Context, user entities.User) error
+	Register(c echo.Context, user entities.User) (entities.Tokens, error)
+	Refresh(c echo.Context) (entities.Session, error)
+	Logout(c echo.Context) error
+	LogoutAll(c echo.Context) error
+	RevokeAccess(c echo.Context, userID int) error
+	CreateAPIKey(c echo.Context, userID int, req entities.NewAPIKey) (entities.CreatedAPIKey, error)
+	APIKeys(c echo.Context, userID int) ([]entities.APIKey, error)
+	RevokeAPIKey(c echo.Context, userID int, keyID int) error
 }
+
+// loginLimiter is implemented by ratelimit.FailureLimiter
+type loginLimiter interface {
+	Blocked(key string) time.Duration
+	Fail(key string)
+	Reset(key string)
+}
+
+// inputValidator is implemented by validate.Validator
+type inputValidator interface {
+	User(user entities.User) error
+}
+
+var ErrBadCredentials = errors.New("wrong username or password")
+
 type httpHandler struct {
-	st   storage
-	echo *echo.Echo
-	ctx  context.Context
-	auth authService
+	st        storage
+	echo      *echo.Echo
+	ctx       context.Context
+	auth      authService
+	limiter   loginLimiter
+	validator inputValidator
 }
 
-func New(st storage, auth authService, ctx context.Context) *httpHandler {
+func New(st storage, auth authService, limiter loginLimiter, validator inputValidator, ctx context.Context) *httpHandler {
 	e := echo.New()
 	return &httpHandler{
-		st:   st,
-		echo: e,
-		ctx:  ctx,
-		auth: auth,
+		st:        st,
+		echo:      e,
+		ctx:       ctx,
+		auth:      auth,
+		limiter:   limiter,
+		validator: validator,
 	}
 }
 
-func (h *httpHandler) AddRoutes(pathRoutesName string, router *echo.Router) {
-	router.Add("POST", "/"+pathRoutesName+"/user", h.CreateUser)
-	router.Add("GET", "/"+pathRoutesName+"/user/:id", h.UserByID)
-	router.Add("PATCH", "/"+pathRoutesName+"/user/fav", h.AddFav)
-	router.Add("GET", "/"+pathRoutesName+"/user/login", h.Login)
+// AddRoutes adds the routes to the group,
+// routes of the user's own data are available only to the user itself,
+// roles are changed only by admins, API keys are managed only with tokens
+func (h *httpHandler) AddRoutes(group *echo.Group, authenticate echo.MiddlewareFunc) {
+	group.POST("/user", h.CreateUser)
+	group.POST("/user/login", h.Login)
+	group.POST("/auth/refresh", h.Refresh)
+	group.POST("/auth/logout", h.Logout)
+	group.POST("/auth/logout-all", h.LogoutAll)
+
+	// middleware на маршрутах, а не на группе /user:
+	// группа с middleware отвечала бы 401 вместо 404 на любой неизвестный путь
+	group.GET("/user/:id", h.UserByID, authenticate, authmiddleware.RequireSelfOrRole("id", authmiddleware.RoleAdmin))
+	group.PUT("/user/:id/role", h.SetRole, authenticate, authmiddleware.RequireRole(authmiddleware.RoleAdmin))
+	group.PATCH("/user/fav", h.AddFav, authenticate)
+	group.GET("/user/fav", h.Favourites, authenticate)
+	group.PUT("/user/fav/:drink", h.PutFav, authenticate)
+	group.DELETE("/user/fav/:drink", h.RemoveFav, authenticate)
+	group.GET("/user/recommendations", h.Recommendations, authenticate)
+
+	group.POST("/user/apikeys", h.CreateAPIKey, authenticate, authmiddleware.DenyAPIKeys)
+	group.GET("/user/apikeys", h.APIKeys, authenticate, authmiddleware.DenyAPIKeys)
+	group.DELETE("/user/apikeys/:id", h.RevokeAPIKey, authenticate, authmiddleware.DenyAPIKeys)
 }
 
 // CreateUser godoc
@@ -54,83 +112,226 @@ func (h *httpHandler) AddRoutes(pathRoutesName string, router *echo.Router) {
 // @Accept json
 // @Produce json
 // @Param user body entities.User true "User object"
+// @Param token_in_body query bool false "Return tokens in the body, response is entities.Session"
 // @Success 201 {object} entities.User
-// @Failure 400 {object} string
-// @Failure 500 {object} string
+// @Failure 400 {object} errlib.Response "Invalid username or password, every failed rule is in errors"
+// @Failure 404 {object} errlib.Response "Favourite drink not found"
+// @Failure 409 {object} errlib.Response "Username is taken"
+// @Failure 500 {object} errlib.Response
 // @Router /user [post]
 func (h *httpHandler) CreateUser(c echo.Context) error {
 	var user entities.User
 	if err := c.Bind(&user); err != nil {
-		return c.JSON(http.StatusBadRequest, err.Error())
+		return err
+	}
+	if err := h.validator.User(user); err != nil {
+		return err
 	}
-
 	user, err := h.st.CreateUser(h.ctx, user)
 	fmt.Println(user)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, err)
+		return err
 	}
-	if err := h.auth.Register(c, user); err != nil {
-		return c.JSON(http.StatusBadRequest, err)
+	tokens, err := h.auth.Register(c, user)
+	if err != nil {
+		return err
 	}
-	return c.JSON(http.StatusCreated, user)
+	return c.JSON(http.StatusCreated, withTokens(c, user, tokens))
 }
 
 // Login godoc
 // @Summary Login
-// @Description Login user,if user non-existent: error
-// @Description otherwise return user info
-// @Description assigment to user cookie(encoded jwt token) wih user info
+// @Description Login user by username and password,
+// @Description assigment to user cookie(encoded jwt token) wih user info and return user info.
+// @Description After several failed attempts client is blocked for a while
 // @Tags user
 // @Accept json
 // @Produce json
+// @Param credentials body entities.Credentials true "Username and password"
+// @Param token_in_body query bool false "Return tokens in the body, response is entities.Session"
 // @Success 200 {object} entities.User
-// @Failure 401 {object} string
-// @Failure 500 {object} string
-// @Router /user/login [get]
+// @Failure 400 {object} errlib.Response
+// @Failure 401 {object} errlib.Response "Wrong username or password"
+// @Failure 429 {object} errlib.Response "Too many failed attempts, see Retry-After header"
+// @Failure 500 {object} errlib.Response
+// @Router /user/login [post]
 func (h *httpHandler) Login(c echo.Context) error {
-	user, err := h.auth.Login(c)
-	if err != nil {
-		return c.JSON(http.StatusUnauthorized, err)
+	var creds entities.Credentials
+	if err := c.Bind(&creds); err != nil {
+		return err
+	}
+	key := c.RealIP() + " " + strings.ToLower(creds.Username)
+	if wait := h.limiter.Blocked(key); wait > 0 {
+		return errlib.TooManyRequestsErr{RetryAfter: wait}
+	}
+	user, err := h.st.UserByUsername(h.ctx, creds.Username)
+	if errlib.CheckErrNotFound(err) {
+		// пароль всё равно сверяем, иначе по времени ответа видно, что такого имени нет
+		err = h.st.VerifyUnknownUserPassword(h.ctx, creds.Password)
+	} else if err == nil {
+		err = h.st.VerifyPassword(h.ctx, user.ID, creds.Password)
+	}
+	// не сообщаем, что именно не так: имя или пароль
+	if errlib.CheckErrNotFound(err) || errlib.CheckErrUnauthorized(err) {
+		h.limiter.Fail(key)
+		return errlib.UnauthorizedErr{Err: ErrBadCredentials}
+	} else if err != nil {
+		return err
 	}
+	h.limiter.Reset(key)
 	user, err = h.st.UserByID(h.ctx, user.ID)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, err)
+		return err
 	}
-	return c.JSON(http.StatusOK, user)
+	tokens, err := h.auth.Register(c, user)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, withTokens(c, user, tokens))
+}
+
+// Refresh godoc
+// @Summary Refresh tokens
+// @Description Exchange refresh token(which contains in cookie refresh_token or in the body) for a new pair
+// @Description of access and refresh tokens and return user info.
+// @Description Every refresh token can be used only once, reuse of it revokes all tokens of the session
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param refresh body entities.RefreshRequest false "Refresh token for clients without cookies"
+// @Param token_in_body query bool false "Return tokens in the body, response is entities.Session"
+// @Success 200 {object} entities.User
+// @Failure 401 {object} errlib.Response
+// @Failure 500 {object} errlib.Response
+// @Router /auth/refresh [post]
+func (h *httpHandler) Refresh(c echo.Context) error {
+	session, err := h.auth.Refresh(c)
+	if err != nil {
+		return err
+	}
+	user, err := h.st.UserByID(h.ctx, session.User.ID)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, withTokens(c, user, session.Tokens))
+}
+
+// withTokens отдаёт токены в теле только по запросу клиента,
+// браузерам хватает кук
+func withTokens(c echo.Context, user entities.User, tokens entities.Tokens) any {
+	if inBody, _ := strconv.ParseBool(c.QueryParam("token_in_body")); inBody {
+		return entities.Session{User: user, Tokens: tokens}
+	}
+	return user
+}
+
+// Logout godoc
+// @Summary Logout
+// @Description Revoke access and refresh tokens of the session and clear token cookies
+// @Tags auth
+// @Accept plain
+// @Success 204
+// @Failure 500 {object} errlib.Response
+// @Router /auth/logout [post]
+func (h *httpHandler) Logout(c echo.Context) error {
+	if err := h.auth.Logout(c); err != nil {
+		return err
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// LogoutAll godoc
+// @Summary Logout on all devices
+// @Description Revoke all access and refresh tokens of the user(which id contains in cookie: jwt token)
+// @Description and clear token cookies
+// @Tags auth
+// @Accept plain
+// @Success 204
+// @Failure 401 {object} errlib.Response
+// @Failure 500 {object} errlib.Response
+// @Router /auth/logout-all [post]
+func (h *httpHandler) LogoutAll(c echo.Context) error {
+	if err := h.auth.LogoutAll(c); err != nil {
+		return err
+	}
+	return c.NoContent(http.StatusNoContent)
 }
 
 // UserByID godoc
 // @Summary Get user
-// @Description Get user by ID(which contains in cookie: jwt token)
+// @Description Get user by ID, users can get only themselves, admins can get anyone
 // @Tags user
 // @Accept plain
 // @Produce json
+// @Param id path int true "User ID"
 // @Success 200 {object} entities.User
-// @Failure 400 {object} string
-// @Failure 500 {object} string
+// @Failure 400 {object} errlib.Response
+// @Failure 401 {object} errlib.Response
+// @Failure 403 {object} errlib.Response "Not the user itself or admin"
+// @Failure 404 {object} errlib.Response
+// @Failure 500 {object} errlib.Response
 // @Router /user/{id} [get]
 func (h *httpHandler) UserByID(c echo.Context) error {
-	userInfo, err := h.auth.Auth(c)
+	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		return c.JSON(http.StatusUnauthorized, err)
+		return errlib.InvalidInputErr{What: "id", Reason: "must be a number"}
 	}
-	user, err := h.st.UserByID(h.ctx, userInfo.ID)
+	user, err := h.st.UserByID(h.ctx, id)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, err)
+		return err
+	}
+	return c.JSON(http.StatusOK, user)
+}
+
+// SetRole godoc
+// @Summary Change role of user
+// @Description Promote user to admin or demote to user, available only to admins.
+// @Description Access tokens of the user are revoked, so the new role comes into force
+// @Description on the next refresh or login and a demoted admin loses admin rights at once
+// @Tags user
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Param role body entities.RoleChange true "New role: user or admin"
+// @Success 200 {object} entities.User
+// @Failure 400 {object} errlib.Response
+// @Failure 401 {object} errlib.Response
+// @Failure 403 {object} errlib.Response "Not admin"
+// @Failure 404 {object} errlib.Response
+// @Failure 500 {object} errlib.Response
+// @Router /user/{id}/role [put]
+func (h *httpHandler) SetRole(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return errlib.InvalidInputErr{What: "id", Reason: "must be a number"}
+	}
+	var change entities.RoleChange
+	if err := c.Bind(&change); err != nil {
+		return err
+	}
+	user, err := h.st.SetRole(h.ctx, id, change.Role)
+	if err != nil {
+		return err
+	}
+	// иначе разжалованный админ остаётся админом, пока не истечёт его токен
+	if err := h.auth.RevokeAccess(c, id); err != nil {
+		return err
 	}
 	return c.JSON(http.StatusOK, user)
 }
 
 // AddFav godoc
 // @Summary Add a favourite drink to user
-// @Description Add a favourite drink to user
+// @Description Add a favourite drink to user, use PUT /user/fav/{drink} instead
 // @Tags user
+// @Deprecated
 // @Accept json
 // @Produce json
 // @Param drinkname path string true "Drink name"
 // @Success 202 {object} entities.User
-// @Failure 400 {object} string
-// @Failure 500 {object} string
+// @Failure 400 {object} errlib.Response
+// @Failure 401 {object} errlib.Response
+// @Failure 500 {object} errlib.Response
 // @Router /user/fav [patch]
 func (h *httpHandler) AddFav(c echo.Context) error {
 
@@ -139,14 +340,207 @@ func (h *httpHandler) AddFav(c echo.Context) error {
 	if len(drinkName) == 0 {
 		drinkName = c.QueryParam("drinkname")
 	}
-	userInfo, err := h.auth.Auth(c)
-	fmt.Println(userInfo)
+	principal, err := authmiddleware.PrincipalFrom(c)
 	if err != nil {
-		return c.JSON(http.StatusUnauthorized, err)
+		return err
 	}
-	user, err := h.st.AddFav(h.ctx, drinkName, userInfo.ID)
+	user, err := h.st.AddFav(h.ctx, drinkName, principal.UserID)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, err)
+		return err
 	}
 	return c.JSON(http.StatusAccepted, user)
 }
+
+// PutFav godoc
+// @Summary Add a favourite drink
+// @Description Add the drink to favourites of the user(which id contains in token) and return the user.
+// @Description Adding the drink which is already favourite changes nothing
+// @Tags user
+// @Accept plain
+// @Produce json
+// @Param drink path string true "Drink name"
+// @Success 200 {object} entities.User
+// @Failure 401 {object} errlib.Response
+// @Failure 404 {object} errlib.Response "Drink not found"
+// @Failure 500 {object} errlib.Response
+// @Router /user/fav/{drink} [put]
+func (h *httpHandler) PutFav(c echo.Context) error {
+	principal, err := authmiddleware.PrincipalFrom(c)
+	if err != nil {
+		return err
+	}
+	user, err := h.st.AddFav(h.ctx, c.Param("drink"), principal.UserID)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, user)
+}
+
+// RemoveFav godoc
+// @Summary Remove a favourite drink
+// @Description Remove the drink from favourites of the user(which id contains in token)
+// @Tags user
+// @Accept plain
+// @Param drink path string true "Drink name"
+// @Success 204
+// @Failure 401 {object} errlib.Response
+// @Failure 404 {object} errlib.Response "Drink not found or isn't favourite"
+// @Failure 500 {object} errlib.Response
+// @Router /user/fav/{drink} [delete]
+func (h *httpHandler) RemoveFav(c echo.Context) error {
+	principal, err := authmiddleware.PrincipalFrom(c)
+	if err != nil {
+		return err
+	}
+	if err := h.st.RemoveFav(h.ctx, c.Param("drink"), principal.UserID); err != nil {
+		return err
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// Favourites godoc
+// @Summary List favourite drinks
+// @Description List favourite drinks of the user(which id contains in cookie: jwt token)
+// @Description page by page in drink id order.
+// @Description To get the next page pass next_cursor of the current page as cursor
+// @Tags user
+// @Accept plain
+// @Produce json
+// @Param limit query int false "page size, 20 by default, max 100"
+// @Param cursor query string false "next_cursor from the previous page"
+// @Success 200 {object} pagination.Page[entities.Drink]
+// @Failure 400 {object} errlib.Response
+// @Failure 401 {object} errlib.Response
+// @Failure 500 {object} errlib.Response
+// @Router /user/fav [get]
+func (h *httpHandler) Favourites(c echo.Context) error {
+	page, err := pagination.Parse(c.QueryParam("limit"), c.QueryParam("cursor"))
+	if err != nil {
+		return err
+	}
+	principal, err := authmiddleware.PrincipalFrom(c)
+	if err != nil {
+		return err
+	}
+	favs, err := h.st.Favourites(h.ctx, principal.UserID, page)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, favs)
+}
+
+// Recommendations godoc
+// @Summary Recommended drinks
+// @Description Drinks the user(which id contains in token) hasn't favourited, best first.
+// @Description Score is 0.6 * tag_score + 0.4 * co_score: tag_score is similarity of the drink tags
+// @Description and tags of favourite drinks, co_score is a share of users with common favourites
+// @Description who have favourited the drink. Every result explains its score.
+// @Description User without favourites gets an empty list
+// @Tags user
+// @Accept plain
+// @Produce json
+// @Param limit query int false "max count of drinks, 20 by default, max 100"
+// @Success 200 {array} entities.Recommendation
+// @Failure 400 {object} errlib.Response "Bad limit"
+// @Failure 401 {object} errlib.Response
+// @Failure 500 {object} errlib.Response
+// @Router /user/recommendations [get]
+func (h *httpHandler) Recommendations(c echo.Context) error {
+	page, err := pagination.Parse(c.QueryParam("limit"), "")
+	if err != nil {
+		return err
+	}
+	principal, err := authmiddleware.PrincipalFrom(c)
+	if err != nil {
+		return err
+	}
+	res, err := h.st.Recommendations(h.ctx, principal.UserID, page.Limit)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, res)
+}
+
+// CreateAPIKey godoc
+// @Summary Create API key
+// @Description Create a personal API key of the user for scripts and services.
+// @Description The key is shown only in this response, store it at once.
+// @Description Send it in X-API-Key header or as Authorization: Bearer token.
+// @Description Scope read allows GET requests, write allows the others, key without scopes can only read
+// @Tags apikeys
+// @Accept json
+// @Produce json
+// @Param key body entities.NewAPIKey true "Name, scopes and optional expiry of the key"
+// @Success 201 {object} entities.CreatedAPIKey
+// @Failure 400 {object} errlib.Response
+// @Failure 401 {object} errlib.Response
+// @Failure 403 {object} errlib.Response "Request is made with API key"
+// @Failure 409 {object} errlib.Response "Key with such name exists"
+// @Failure 500 {object} errlib.Response
+// @Router /user/apikeys [post]
+func (h *httpHandler) CreateAPIKey(c echo.Context) error {
+	var req entities.NewAPIKey
+	if err := c.Bind(&req); err != nil {
+		return err
+	}
+	principal, err := authmiddleware.PrincipalFrom(c)
+	if err != nil {
+		return err
+	}
+	key, err := h.auth.CreateAPIKey(c, principal.UserID, req)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusCreated, key)
+}
+
+// APIKeys godoc
+// @Summary List API keys
+// @Description List personal API keys of the user, only prefixes of the keys are shown
+// @Tags apikeys
+// @Accept plain
+// @Produce json
+// @Success 200 {array} entities.APIKey
+// @Failure 401 {object} errlib.Response
+// @Failure 403 {object} errlib.Response "Request is made with API key"
+// @Failure 500 {object} errlib.Response
+// @Router /user/apikeys [get]
+func (h *httpHandler) APIKeys(c echo.Context) error {
+	principal, err := authmiddleware.PrincipalFrom(c)
+	if err != nil {
+		return err
+	}
+	keys, err := h.auth.APIKeys(c, principal.UserID)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, keys)
+}
+
+// RevokeAPIKey godoc
+// @Summary Revoke API key
+// @Description Revoke personal API key of the user, requests with it are rejected at once
+// @Tags apikeys
+// @Accept plain
+// @Param id path int true "API key ID"
+// @Success 204
+// @Failure 400 {object} errlib.Response
+// @Failure 401 {object} errlib.Response
+// @Failure 403 {object} errlib.Response "Request is made with API key"
+// @Failure 404 {object} errlib.Response
+// @Failure 500 {object} errlib.Response
+// @Router /user/apikeys/{id} [delete]
+func (h *httpHandler) RevokeAPIKey(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return errlib.InvalidInputErr{What: "id", Reason: "must be a number"}
+	}
+	principal, err := authmiddleware.PrincipalFrom(c)
+	if err != nil {
+		return err
+	}
+	if err := h.auth.RevokeAPIKey(c, principal.UserID, id); err != nil {
+		return err
+	}
+	return c.NoContent(http.StatusNoContent)
+}
@@ -2,12 +2,23 @@ package controller
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/SapolovichSV/backprogeng/internal/authmiddleware"
+	"github.com/SapolovichSV/backprogeng/internal/config"
+	drEnt "github.com/SapolovichSV/backprogeng/internal/drink/entities"
+	"github.com/SapolovichSV/backprogeng/internal/errlib"
+	httpinfra "github.com/SapolovichSV/backprogeng/internal/http_infra"
+	"github.com/SapolovichSV/backprogeng/internal/pagination"
+	"github.com/SapolovichSV/backprogeng/internal/ratelimit"
 	"github.com/SapolovichSV/backprogeng/internal/user/entities"
+	"github.com/SapolovichSV/backprogeng/internal/validate"
 	mockAuth "github.com/SapolovichSV/backprogeng/mocks/authmiddleware"
 	mocks "github.com/SapolovichSV/backprogeng/mocks/user"
 	"github.com/labstack/echo/v4"
@@ -17,34 +28,53 @@ import (
 
 // Пример тестов для httpHandler.Login
 func Test_httpHandler_Login(t *testing.T) {
-	type args struct {
-		c echo.Context
-	}
 	tests := []struct {
 		name         string
+		creds        string
 		mockSetup    func(*mocks.MockuserModel, *mockAuth.MockauthService)
 		wantHTTPCode int
-		wantErr      bool
 	}{
 		{
-			name: "login_ok",
+			name:  "login_ok",
+			creds: `{"username": "TestName", "password": "amahasla"}`,
 			mockSetup: func(mu *mocks.MockuserModel, ma *mockAuth.MockauthService) {
-				ma.EXPECT().Login(gomock.Any()).
+				mu.EXPECT().UserByUsername(gomock.Any(), "TestName").
 					Return(entities.User{ID: 100, Username: "TestName"}, nil)
+				mu.EXPECT().VerifyPassword(gomock.Any(), 100, "amahasla").
+					Return(nil)
 				mu.EXPECT().UserByID(gomock.Any(), 100).
 					Return(entities.User{ID: 100, Username: "TestName"}, nil)
+				ma.EXPECT().Register(gomock.Any(), entities.User{ID: 100, Username: "TestName"}).
+					Return(entities.Tokens{AccessToken: "access"}, nil)
 			},
 			wantHTTPCode: http.StatusOK,
-			wantErr:      false,
 		},
 		{
-			name: "login_unauthorized",
+			name:  "wrong_password",
+			creds: `{"username": "TestName", "password": "amahaslo"}`,
+			mockSetup: func(mu *mocks.MockuserModel, ma *mockAuth.MockauthService) {
+				mu.EXPECT().UserByUsername(gomock.Any(), "TestName").
+					Return(entities.User{ID: 100, Username: "TestName"}, nil)
+				mu.EXPECT().VerifyPassword(gomock.Any(), 100, "amahaslo").
+					Return(errlib.UnauthorizedErr{Err: errors.New("wrong password")})
+			},
+			wantHTTPCode: http.StatusUnauthorized,
+		},
+		{
+			name:  "unknown_user",
+			creds: `{"username": "Nobody", "password": "amahasla"}`,
 			mockSetup: func(mu *mocks.MockuserModel, ma *mockAuth.MockauthService) {
-				ma.EXPECT().Login(gomock.Any()).
-					Return(entities.User{}, errors.New("unauthorized"))
+				mu.EXPECT().UserByUsername(gomock.Any(), "Nobody").
+					Return(entities.User{}, errlib.NotFoundErr{Where: "users", What: "user"})
+				mu.EXPECT().VerifyUnknownUserPassword(gomock.Any(), "amahasla").
+					Return(errlib.UnauthorizedErr{Err: errors.New("wrong password")})
 			},
 			wantHTTPCode: http.StatusUnauthorized,
-			wantErr:      true,
+		},
+		{
+			name:         "bad_body",
+			creds:        `{"username": `,
+			wantHTTPCode: http.StatusBadRequest,
 		},
 	}
 	for _, tt := range tests {
@@ -60,22 +90,95 @@ func Test_httpHandler_Login(t *testing.T) {
 			}
 
 			e := echo.New()
-			req := httptest.NewRequest(http.MethodPost, "/login", nil)
+			req := httptest.NewRequest(http.MethodPost, "/user/login", strings.NewReader(tt.creds))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 			rec := httptest.NewRecorder()
 			c := e.NewContext(req, rec)
 
 			h := &httpHandler{
-				st:   mockStorage,
-				echo: e,
-				ctx:  context.Background(),
-				auth: mockAuth,
+				st:      mockStorage,
+				echo:    e,
+				ctx:     context.Background(),
+				auth:    mockAuth,
+				limiter: ratelimit.NewFailureLimiter(5, time.Minute),
 			}
-			_ = h.Login(c)
+			serve(h.Login, c)
 			require.Equal(t, tt.wantHTTPCode, rec.Code)
 		})
 	}
 }
 
+func Test_httpHandler_Login_blocked(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mocks.NewMockuserModel(ctrl)
+	mockStorage.EXPECT().UserByUsername(gomock.Any(), "TestName").
+		Return(entities.User{ID: 100, Username: "TestName"}, nil).Times(2)
+	mockStorage.EXPECT().VerifyPassword(gomock.Any(), 100, "amahaslo").
+		Return(errlib.UnauthorizedErr{Err: errors.New("wrong password")}).Times(2)
+
+	h := &httpHandler{
+		st:      mockStorage,
+		ctx:     context.Background(),
+		limiter: ratelimit.NewFailureLimiter(2, time.Minute),
+	}
+	wantCodes := []int{http.StatusUnauthorized, http.StatusUnauthorized, http.StatusTooManyRequests}
+	for _, wantCode := range wantCodes {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/user/login",
+			strings.NewReader(`{"username": "TestName", "password": "amahaslo"}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		serve(h.Login, c)
+		require.Equal(t, wantCode, rec.Code)
+	}
+}
+
+func Test_httpHandler_Login_tokenInBody(t *testing.T) {
+	for _, tt := range []struct {
+		query      string
+		wantTokens bool
+	}{
+		{query: "", wantTokens: false},
+		{query: "?token_in_body=true", wantTokens: true},
+	} {
+		ctrl := gomock.NewController(t)
+		mockStorage := mocks.NewMockuserModel(ctrl)
+		mockStorage.EXPECT().UserByUsername(gomock.Any(), "TestName").
+			Return(entities.User{ID: 100, Username: "TestName"}, nil)
+		mockStorage.EXPECT().VerifyPassword(gomock.Any(), 100, "amahasla").Return(nil)
+		mockStorage.EXPECT().UserByID(gomock.Any(), 100).
+			Return(entities.User{ID: 100, Username: "TestName"}, nil)
+		mockAuth := mockAuth.NewMockauthService(ctrl)
+		mockAuth.EXPECT().Register(gomock.Any(), gomock.Any()).
+			Return(entities.Tokens{AccessToken: "access", RefreshToken: "refresh", TokenType: "Bearer", ExpiresIn: 900}, nil)
+
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/user/login"+tt.query,
+			strings.NewReader(`{"username": "TestName", "password": "amahasla"}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		h := &httpHandler{
+			st:      mockStorage,
+			ctx:     context.Background(),
+			auth:    mockAuth,
+			limiter: ratelimit.NewFailureLimiter(5, time.Minute),
+		}
+		serve(h.Login, e.NewContext(req, rec))
+		require.Equal(t, http.StatusOK, rec.Code)
+		if tt.wantTokens {
+			require.JSONEq(t, `{"user":{"id":100,"username":"TestName","role":"","favourites":null},
+				"access_token":"access","refresh_token":"refresh","token_type":"Bearer","expires_in":900}`, rec.Body.String())
+		} else {
+			require.NotContains(t, rec.Body.String(), "access_token")
+		}
+		ctrl.Finish()
+	}
+}
+
 // Пример тестов для httpHandler.UserByID
 func Test_httpHandler_UserByID(t *testing.T) {
 	type args struct {
@@ -83,15 +186,15 @@ func Test_httpHandler_UserByID(t *testing.T) {
 	}
 	tests := []struct {
 		name         string
+		principal    *authmiddleware.Principal
 		mockSetup    func(*mocks.MockuserModel, *mockAuth.MockauthService)
 		wantHTTPCode int
 		wantErr      bool
 	}{
 		{
-			name: "ok",
+			name:      "ok",
+			principal: &authmiddleware.Principal{UserID: 50},
 			mockSetup: func(mu *mocks.MockuserModel, ma *mockAuth.MockauthService) {
-				ma.EXPECT().Auth(gomock.Any()).
-					Return(entities.User{ID: 50}, nil)
 				mu.EXPECT().UserByID(gomock.Any(), 50).
 					Return(entities.User{ID: 50, Username: "John"}, nil)
 			},
@@ -99,12 +202,13 @@ func Test_httpHandler_UserByID(t *testing.T) {
 			wantErr:      false,
 		},
 		{
-			name: "no_token",
+			name:      "not_found",
+			principal: &authmiddleware.Principal{UserID: 1, Role: authmiddleware.RoleAdmin},
 			mockSetup: func(mu *mocks.MockuserModel, ma *mockAuth.MockauthService) {
-				ma.EXPECT().Auth(gomock.Any()).
-					Return(entities.User{}, errors.New("no token"))
+				mu.EXPECT().UserByID(gomock.Any(), 50).
+					Return(entities.User{}, errlib.NotFoundErr{Where: "users", What: "user"})
 			},
-			wantHTTPCode: http.StatusUnauthorized,
+			wantHTTPCode: http.StatusNotFound,
 			wantErr:      true,
 		},
 	}
@@ -124,6 +228,8 @@ func Test_httpHandler_UserByID(t *testing.T) {
 			req := httptest.NewRequest(http.MethodGet, "/user/50", nil)
 			rec := httptest.NewRecorder()
 			c := e.NewContext(req, rec)
+			c.SetParamNames("id")
+			c.SetParamValues("50")
 
 			h := &httpHandler{
 				st:   mockStorage,
@@ -131,8 +237,150 @@ func Test_httpHandler_UserByID(t *testing.T) {
 				ctx:  context.Background(),
 				auth: mockAuth,
 			}
-			_ = h.UserByID(c)
+			if tt.principal != nil {
+				authmiddleware.SetPrincipal(c, *tt.principal)
+			}
+			serve(h.UserByID, c)
+
+			require.Equal(t, tt.wantHTTPCode, rec.Code)
+		})
+	}
+}
+
+func Test_httpHandler_Refresh(t *testing.T) {
+	tests := []struct {
+		name         string
+		mockSetup    func(*mocks.MockuserModel, *mockAuth.MockauthService)
+		wantHTTPCode int
+	}{
+		{
+			name: "refresh_ok",
+			mockSetup: func(mu *mocks.MockuserModel, ma *mockAuth.MockauthService) {
+				ma.EXPECT().Refresh(gomock.Any()).
+					Return(entities.Session{User: entities.User{ID: 100}}, nil)
+				mu.EXPECT().UserByID(gomock.Any(), 100).
+					Return(entities.User{ID: 100, Username: "TestName"}, nil)
+			},
+			wantHTTPCode: http.StatusOK,
+		},
+		{
+			name: "refresh_token_reused",
+			mockSetup: func(mu *mocks.MockuserModel, ma *mockAuth.MockauthService) {
+				ma.EXPECT().Refresh(gomock.Any()).
+					Return(entities.Session{}, errlib.UnauthorizedErr{Err: errors.New("refresh token is already used")})
+			},
+			wantHTTPCode: http.StatusUnauthorized,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockStorage := mocks.NewMockuserModel(ctrl)
+			mockAuth := mockAuth.NewMockauthService(ctrl)
+			tt.mockSetup(mockStorage, mockAuth)
+
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPost, "/auth/refresh", nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
 
+			h := &httpHandler{
+				st:   mockStorage,
+				ctx:  context.Background(),
+				auth: mockAuth,
+			}
+			serve(h.Refresh, c)
+			require.Equal(t, tt.wantHTTPCode, rec.Code)
+		})
+	}
+}
+
+func Test_httpHandler_SetRole(t *testing.T) {
+	tests := []struct {
+		name         string
+		id           string
+		body         string
+		mockSetup    func(*mocks.MockuserModel, *mockAuth.MockauthService)
+		wantHTTPCode int
+	}{
+		{
+			name: "promote",
+			id:   "50",
+			body: `{"role": "admin"}`,
+			mockSetup: func(mu *mocks.MockuserModel, ma *mockAuth.MockauthService) {
+				mu.EXPECT().SetRole(gomock.Any(), 50, entities.RoleAdmin).
+					Return(entities.User{ID: 50, Username: "John", Role: entities.RoleAdmin}, nil)
+				ma.EXPECT().RevokeAccess(gomock.Any(), 50).Return(nil)
+			},
+			wantHTTPCode: http.StatusOK,
+		},
+		{
+			// токены разжалованного админа отзываются сразу
+			name: "demote",
+			id:   "51",
+			body: `{"role": "user"}`,
+			mockSetup: func(mu *mocks.MockuserModel, ma *mockAuth.MockauthService) {
+				mu.EXPECT().SetRole(gomock.Any(), 51, entities.RoleUser).
+					Return(entities.User{ID: 51, Username: "Ann", Role: entities.RoleUser}, nil)
+				ma.EXPECT().RevokeAccess(gomock.Any(), 51).Return(nil)
+			},
+			wantHTTPCode: http.StatusOK,
+		},
+		{
+			name: "revoke_failed",
+			id:   "51",
+			body: `{"role": "user"}`,
+			mockSetup: func(mu *mocks.MockuserModel, ma *mockAuth.MockauthService) {
+				mu.EXPECT().SetRole(gomock.Any(), 51, entities.RoleUser).
+					Return(entities.User{ID: 51, Username: "Ann", Role: entities.RoleUser}, nil)
+				ma.EXPECT().RevokeAccess(gomock.Any(), 51).Return(errors.New("db is down"))
+			},
+			wantHTTPCode: http.StatusInternalServerError,
+		},
+		{
+			name: "unknown_role",
+			id:   "50",
+			body: `{"role": "owner"}`,
+			mockSetup: func(mu *mocks.MockuserModel, ma *mockAuth.MockauthService) {
+				mu.EXPECT().SetRole(gomock.Any(), 50, "owner").
+					Return(entities.User{}, errlib.InvalidInputErr{What: "role", Reason: "must be one of user, admin"})
+			},
+			wantHTTPCode: http.StatusBadRequest,
+		},
+		{
+			name:         "bad_id",
+			id:           "john",
+			body:         `{"role": "admin"}`,
+			wantHTTPCode: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockStorage := mocks.NewMockuserModel(ctrl)
+			mockAuth := mockAuth.NewMockauthService(ctrl)
+			if tt.mockSetup != nil {
+				tt.mockSetup(mockStorage, mockAuth)
+			}
+
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPut, "/user/"+tt.id+"/role", strings.NewReader(tt.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("id")
+			c.SetParamValues(tt.id)
+
+			h := &httpHandler{
+				st:   mockStorage,
+				ctx:  context.Background(),
+				auth: mockAuth,
+			}
+			serve(h.SetRole, c)
 			require.Equal(t, tt.wantHTTPCode, rec.Code)
 		})
 	}
@@ -145,26 +393,22 @@ func Test_httpHandler_AddFav(t *testing.T) {
 	}
 	tests := []struct {
 		name         string
+		principal    *authmiddleware.Principal
 		mockSetup    func(*mocks.MockuserModel, *mockAuth.MockauthService)
 		wantHTTPCode int
 		wantErr      bool
 	}{
 		{
-			name: "add_fav_ok",
+			name:      "add_fav_ok",
+			principal: &authmiddleware.Principal{UserID: 10},
 			mockSetup: func(mu *mocks.MockuserModel, ma *mockAuth.MockauthService) {
-				ma.EXPECT().Auth(gomock.Any()).
-					Return(entities.User{ID: 10}, nil)
-				mu.EXPECT().AddFav(gomock.Any(), "Coke", entities.User{ID: 10}.ID)
+				mu.EXPECT().AddFav(gomock.Any(), "Coke", 10)
 			},
 			wantHTTPCode: http.StatusAccepted,
 			wantErr:      false,
 		},
 		{
-			name: "auth_error",
-			mockSetup: func(mu *mocks.MockuserModel, ma *mockAuth.MockauthService) {
-				ma.EXPECT().Auth(gomock.Any()).
-					Return(entities.User{}, errors.New("unauthorized"))
-			},
+			name:         "auth_error",
 			wantHTTPCode: http.StatusUnauthorized,
 			wantErr:      true,
 		},
@@ -193,8 +437,451 @@ func Test_httpHandler_AddFav(t *testing.T) {
 				ctx:  context.Background(),
 				auth: mockAuth,
 			}
-			_ = h.AddFav(c)
+			if tt.principal != nil {
+				authmiddleware.SetPrincipal(c, *tt.principal)
+			}
+			serve(h.AddFav, c)
+
+			require.Equal(t, tt.wantHTTPCode, rec.Code)
+		})
+	}
+}
+
+func Test_httpHandler_Favourites(t *testing.T) {
+	tests := []struct {
+		name         string
+		query        string
+		principal    *authmiddleware.Principal
+		mockSetup    func(*mocks.MockuserModel, *mockAuth.MockauthService)
+		wantHTTPCode int
+	}{
+		{
+			name:      "ok",
+			query:     "?limit=2",
+			principal: &authmiddleware.Principal{UserID: 10},
+			mockSetup: func(mu *mocks.MockuserModel, ma *mockAuth.MockauthService) {
+				mu.EXPECT().Favourites(gomock.Any(), 10, pagination.Request{Limit: 2}).
+					Return(pagination.Page[drEnt.Drink]{Items: []drEnt.Drink{{ID: 1, Name: "Coke"}}}, nil)
+			},
+			wantHTTPCode: http.StatusOK,
+		},
+		{
+			name:         "bad_cursor",
+			query:        "?cursor=broken",
+			wantHTTPCode: http.StatusBadRequest,
+		},
+		{
+			name:         "auth_error",
+			wantHTTPCode: http.StatusUnauthorized,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockStorage := mocks.NewMockuserModel(ctrl)
+			mockAuth := mockAuth.NewMockauthService(ctrl)
+
+			if tt.mockSetup != nil {
+				tt.mockSetup(mockStorage, mockAuth)
+			}
+
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/user/fav"+tt.query, nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			h := &httpHandler{
+				st:   mockStorage,
+				echo: e,
+				ctx:  context.Background(),
+				auth: mockAuth,
+			}
+			if tt.principal != nil {
+				authmiddleware.SetPrincipal(c, *tt.principal)
+			}
+			serve(h.Favourites, c)
+
+			require.Equal(t, tt.wantHTTPCode, rec.Code)
+		})
+	}
+}
+
+func Test_httpHandler_Recommendations(t *testing.T) {
+	tests := []struct {
+		name         string
+		query        string
+		principal    *authmiddleware.Principal
+		mockSetup    func(*mocks.MockuserModel)
+		wantHTTPCode int
+		wantBody     string
+	}{
+		{
+			name:      "ok",
+			query:     "?limit=1",
+			principal: &authmiddleware.Principal{UserID: 10},
+			mockSetup: func(mu *mocks.MockuserModel) {
+				mu.EXPECT().Recommendations(gomock.Any(), 10, 1).
+					Return([]entities.Recommendation{{
+						Drink: drEnt.Drink{ID: 3, Name: "Sugar Rush"},
+						Score: 0.7,
+						Explanation: entities.Explanation{
+							SharedTags:   []string{"sweet"},
+							TagScore:     0.5,
+							CoFavourites: 1,
+							CoScore:      1,
+							Reason:       "shares tags sweet with your favourites; favourited by 1 user who likes your drinks",
+						},
+					}}, nil)
+			},
+			wantHTTPCode: http.StatusOK,
+			wantBody: `[{"drink":{"id":3,"name":"Sugar Rush","tags":null,"description":"","price":0,"alcoholic":false,
+				"preparation":"","ingredients":null,"rating_average":0,"rating_count":0},
+				"score":0.7,"explanation":{"shared_tags":["sweet"],"tag_score":0.5,"co_favourites":1,"co_score":1,
+				"reason":"shares tags sweet with your favourites; favourited by 1 user who likes your drinks"}}]`,
+		},
+		{
+			name:         "bad_limit",
+			query:        "?limit=0",
+			principal:    &authmiddleware.Principal{UserID: 10},
+			wantHTTPCode: http.StatusBadRequest,
+		},
+		{
+			name:         "auth_error",
+			wantHTTPCode: http.StatusUnauthorized,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockStorage := mocks.NewMockuserModel(ctrl)
+			if tt.mockSetup != nil {
+				tt.mockSetup(mockStorage)
+			}
+
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/user/recommendations"+tt.query, nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			h := &httpHandler{
+				st:   mockStorage,
+				echo: e,
+				ctx:  context.Background(),
+			}
+			if tt.principal != nil {
+				authmiddleware.SetPrincipal(c, *tt.principal)
+			}
+			serve(h.Recommendations, c)
+
+			require.Equal(t, tt.wantHTTPCode, rec.Code)
+			if tt.wantBody != "" {
+				require.JSONEq(t, tt.wantBody, rec.Body.String())
+			}
+		})
+	}
+}
+
+// serve вызывает обработчик так же, как echo:
+// возвращённая ошибка превращается в ответ через httpinfra.ErrorHandler
+func serve(handler echo.HandlerFunc, c echo.Context) {
+	if err := handler(c); err != nil {
+		httpinfra.ErrorHandler(err, c)
+	}
+}
+
+func Test_httpHandler_CreateAPIKey(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         string
+		mockSetup    func(*mockAuth.MockauthService)
+		wantHTTPCode int
+	}{
+		{
+			name: "created",
+			body: `{"name": "seeder", "scopes": ["read", "write"]}`,
+			mockSetup: func(ma *mockAuth.MockauthService) {
+				ma.EXPECT().CreateAPIKey(gomock.Any(), 50, entities.NewAPIKey{Name: "seeder", Scopes: []string{"read", "write"}}).
+					Return(entities.CreatedAPIKey{APIKey: entities.APIKey{ID: 1, Name: "seeder"}, Key: "bpg_key"}, nil)
+			},
+			wantHTTPCode: http.StatusCreated,
+		},
+		{
+			name: "name_taken",
+			body: `{"name": "seeder"}`,
+			mockSetup: func(ma *mockAuth.MockauthService) {
+				ma.EXPECT().CreateAPIKey(gomock.Any(), 50, entities.NewAPIKey{Name: "seeder"}).
+					Return(entities.CreatedAPIKey{}, errlib.ConflictErr{Where: "api_keys", What: "api key seeder"})
+			},
+			wantHTTPCode: http.StatusConflict,
+		},
+		{
+			name:         "bad_body",
+			body:         `{"name": `,
+			wantHTTPCode: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockAuth := mockAuth.NewMockauthService(ctrl)
+			if tt.mockSetup != nil {
+				tt.mockSetup(mockAuth)
+			}
+
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPost, "/user/apikeys", strings.NewReader(tt.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			authmiddleware.SetPrincipal(c, authmiddleware.Principal{UserID: 50})
+
+			h := &httpHandler{
+				ctx:  context.Background(),
+				auth: mockAuth,
+			}
+			serve(h.CreateAPIKey, c)
+			require.Equal(t, tt.wantHTTPCode, rec.Code)
+		})
+	}
+}
+
+func Test_httpHandler_RevokeAPIKey(t *testing.T) {
+	tests := []struct {
+		name         string
+		id           string
+		mockSetup    func(*mockAuth.MockauthService)
+		wantHTTPCode int
+	}{
+		{
+			name: "revoked",
+			id:   "1",
+			mockSetup: func(ma *mockAuth.MockauthService) {
+				ma.EXPECT().RevokeAPIKey(gomock.Any(), 50, 1).Return(nil)
+			},
+			wantHTTPCode: http.StatusNoContent,
+		},
+		{
+			name: "key_of_other_user",
+			id:   "2",
+			mockSetup: func(ma *mockAuth.MockauthService) {
+				ma.EXPECT().RevokeAPIKey(gomock.Any(), 50, 2).
+					Return(errlib.NotFoundErr{Where: "api_keys", What: "api key"})
+			},
+			wantHTTPCode: http.StatusNotFound,
+		},
+		{
+			name:         "bad_id",
+			id:           "seeder",
+			wantHTTPCode: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockAuth := mockAuth.NewMockauthService(ctrl)
+			if tt.mockSetup != nil {
+				tt.mockSetup(mockAuth)
+			}
 
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodDelete, "/user/apikeys/"+tt.id, nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("id")
+			c.SetParamValues(tt.id)
+			authmiddleware.SetPrincipal(c, authmiddleware.Principal{UserID: 50})
+
+			h := &httpHandler{
+				ctx:  context.Background(),
+				auth: mockAuth,
+			}
+			serve(h.RevokeAPIKey, c)
+			require.Equal(t, tt.wantHTTPCode, rec.Code)
+		})
+	}
+}
+
+func Test_httpHandler_CreateUser(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         string
+		mockSetup    func(*mocks.MockuserModel, *mockAuth.MockauthService)
+		wantHTTPCode int
+		wantErrors   []errlib.FieldError
+	}{
+		{
+			name: "created",
+			body: `{"username": "Stas001", "password": "amahasla1"}`,
+			mockSetup: func(mu *mocks.MockuserModel, ma *mockAuth.MockauthService) {
+				mu.EXPECT().CreateUser(gomock.Any(), entities.User{Username: "Stas001", Password: "amahasla1"}).
+					Return(entities.User{ID: 100, Username: "Stas001", Role: entities.RoleUser}, nil)
+				ma.EXPECT().Register(gomock.Any(), entities.User{ID: 100, Username: "Stas001", Role: entities.RoleUser}).
+					Return(entities.Tokens{}, nil)
+			},
+			wantHTTPCode: http.StatusCreated,
+		},
+		{
+			name: "username_taken",
+			body: `{"username": "Stas001", "password": "amahasla1"}`,
+			mockSetup: func(mu *mocks.MockuserModel, ma *mockAuth.MockauthService) {
+				mu.EXPECT().CreateUser(gomock.Any(), gomock.Any()).
+					Return(entities.User{}, errlib.ConflictErr{Where: "users", What: "username Stas001"})
+			},
+			wantHTTPCode: http.StatusConflict,
+		},
+		{
+			// до модели невалидный пользователь не доходит
+			name:         "every_error_at_once",
+			body:         `{"username": "St as", "password": "stas"}`,
+			wantHTTPCode: http.StatusBadRequest,
+			wantErrors: []errlib.FieldError{
+				{Field: "username", Rule: validate.RuleCharset, Message: "may contain only A-Za-z0-9_.-"},
+				{Field: "password", Rule: validate.RuleMinLength, Message: "must be at least 8 characters"},
+				{Field: "password", Rule: validate.RuleRequirePrefix + "digit", Message: "must contain a digit character"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockStorage := mocks.NewMockuserModel(ctrl)
+			mockAuth := mockAuth.NewMockauthService(ctrl)
+			if tt.mockSetup != nil {
+				tt.mockSetup(mockStorage, mockAuth)
+			}
+			validator, err := validate.New(config.Validation{
+				UsernameMinLength: 4,
+				UsernameMaxLength: 32,
+				UsernameCharset:   "A-Za-z0-9_.-",
+				PasswordMinLength: 8,
+				PasswordMaxLength: 72,
+				PasswordRequire:   []string{"letter", "digit"},
+			})
+			require.NoError(t, err)
+
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPost, "/user", strings.NewReader(tt.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			h := &httpHandler{
+				st:        mockStorage,
+				ctx:       context.Background(),
+				auth:      mockAuth,
+				validator: validator,
+			}
+			serve(h.CreateUser, c)
+			require.Equal(t, tt.wantHTTPCode, rec.Code)
+			if tt.wantErrors != nil {
+				var resp errlib.Response
+				require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+				require.Equal(t, tt.wantErrors, resp.Errors)
+			}
+		})
+	}
+}
+
+func Test_httpHandler_PutFav(t *testing.T) {
+	tests := []struct {
+		name         string
+		mockSetup    func(*mocks.MockuserModel)
+		wantHTTPCode int
+	}{
+		{
+			name: "added",
+			mockSetup: func(mu *mocks.MockuserModel) {
+				mu.EXPECT().AddFav(gomock.Any(), "Bad Touch", 10).
+					Return(entities.User{ID: 10, Favourites: []drEnt.Drink{{ID: 3, Name: "Bad Touch"}}}, nil)
+			},
+			wantHTTPCode: http.StatusOK,
+		},
+		{
+			name: "unknown_drink",
+			mockSetup: func(mu *mocks.MockuserModel) {
+				mu.EXPECT().AddFav(gomock.Any(), "Bad Touch", 10).
+					Return(entities.User{}, errlib.NotFoundErr{Where: "drinks", What: "Bad Touch"})
+			},
+			wantHTTPCode: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockStorage := mocks.NewMockuserModel(ctrl)
+			tt.mockSetup(mockStorage)
+
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPut, "/user/fav/Bad%20Touch", nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("drink")
+			c.SetParamValues("Bad Touch")
+			authmiddleware.SetPrincipal(c, authmiddleware.Principal{UserID: 10})
+
+			h := &httpHandler{
+				st:  mockStorage,
+				ctx: context.Background(),
+			}
+			serve(h.PutFav, c)
+			require.Equal(t, tt.wantHTTPCode, rec.Code)
+		})
+	}
+}
+
+func Test_httpHandler_RemoveFav(t *testing.T) {
+	tests := []struct {
+		name         string
+		mockSetup    func(*mocks.MockuserModel)
+		wantHTTPCode int
+	}{
+		{
+			name: "removed",
+			mockSetup: func(mu *mocks.MockuserModel) {
+				mu.EXPECT().RemoveFav(gomock.Any(), "Bad Touch", 10).Return(nil)
+			},
+			wantHTTPCode: http.StatusNoContent,
+		},
+		{
+			name: "not_favourite",
+			mockSetup: func(mu *mocks.MockuserModel) {
+				mu.EXPECT().RemoveFav(gomock.Any(), "Bad Touch", 10).
+					Return(errlib.NotFoundErr{Where: "favs", What: "favourite drink"})
+			},
+			wantHTTPCode: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockStorage := mocks.NewMockuserModel(ctrl)
+			tt.mockSetup(mockStorage)
+
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodDelete, "/user/fav/Bad%20Touch", nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("drink")
+			c.SetParamValues("Bad Touch")
+			authmiddleware.SetPrincipal(c, authmiddleware.Principal{UserID: 10})
+
+			h := &httpHandler{
+				st:  mockStorage,
+				ctx: context.Background(),
+			}
+			serve(h.RemoveFav, c)
 			require.Equal(t, tt.wantHTTPCode, rec.Code)
 		})
 	}
@@ -0,0 +1,33 @@
+package entities
+
+import "time"
+
+// Scopes of API keys: read allows only GET requests, write allows the others
+const (
+	ScopeRead  = "read"
+	ScopeWrite = "write"
+)
+
+// NewAPIKey is sent by user to create a personal API key,
+// key without scopes can only read
+type NewAPIKey struct {
+	Name      string     `json:"name" example:"catalog-seeder"`
+	Scopes    []string   `json:"scopes" example:"read,write"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty" example:"2026-01-01T00:00:00Z"`
+}
+
+// APIKey describes a personal API key, the key itself is never shown again
+type APIKey struct {
+	ID        int        `json:"id"`
+	Name      string     `json:"name" example:"catalog-seeder"`
+	Prefix    string     `json:"prefix" example:"bpg_3fK9aQ1z"`
+	Scopes    []string   `json:"scopes" example:"read,write"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// CreatedAPIKey is returned once on creation of the key
+type CreatedAPIKey struct {
+	APIKey
+	Key string `json:"key" example:"bpg_3fK9aQ1zXn..."`
+}
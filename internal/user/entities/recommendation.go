@@ -0,0 +1,24 @@
+package entities
+
+import drEnt "github.com/SapolovichSV/backprogeng/internal/drink/entities"
+
+// Recommendation is a drink the user hasn't favourited yet,
+// Score is TagScore and CoScore summed with weights, both are from 0 to 1
+type Recommendation struct {
+	Drink       drEnt.Drink `json:"drink"`
+	Score       float64     `json:"score" example:"0.55"`
+	Explanation Explanation `json:"explanation"`
+}
+
+// Explanation tells why the drink is recommended
+type Explanation struct {
+	// SharedTags are tags the drink shares with favourite drinks of the user
+	SharedTags []string `json:"shared_tags" example:"sour,sweet"`
+	// TagScore is Jaccard similarity of tags of the drink and tags of favourite drinks
+	TagScore float64 `json:"tag_score" example:"0.5"`
+	// CoFavourites is how many users with common favourite drinks have favourited the drink
+	CoFavourites int `json:"co_favourites" example:"3"`
+	// CoScore is CoFavourites divided by the count of users with common favourite drinks
+	CoScore float64 `json:"co_score" example:"0.6"`
+	Reason  string  `json:"reason" example:"shares tags sour, sweet with your favourites; favourited by 3 users who like your drinks"`
+}
@@ -2,16 +2,73 @@ package entities
 
 import (
 	"database/sql/driver"
+	"encoding/json"
 	"errors"
 	"strings"
+
+	drEnt "github.com/SapolovichSV/backprogeng/internal/drink/entities"
+)
+
+// Roles of users, only admins can change the drink catalog and roles of users
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
 )
 
 type User struct {
-	ID                  int        `json:"id"`
-	Username            string     `json:"username"`
-	Password            string     `json:"password"`
-	FavouritesDrinkName Drinknames `json:"drinknames"`
+	ID       int    `json:"id"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+	// Role is ignored in requests, it is changed only by admins
+	Role string `json:"role"`
+	// FavouritesDrinkName are names of favourite drinks sent on registration,
+	// responses have whole drinks in Favourites instead
+	FavouritesDrinkName Drinknames    `json:"drinknames,omitempty"`
+	Favourites          []drEnt.Drink `json:"favourites"`
+}
+
+// MarshalJSON writes user without password: it is only read from requests
+// and must never be sent back
+func (u User) MarshalJSON() ([]byte, error) {
+	type user User
+	return json.Marshal(struct {
+		user
+		Password string `json:"password,omitempty"`
+	}{user: user(u)})
+}
+
+// RoleChange is sent by admin to change role of the user
+type RoleChange struct {
+	Role string `json:"role" example:"admin"`
+}
+
+// Tokens are issued on login, registration and refresh,
+// they are sent in the body only when the client asks for it
+type Tokens struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type" example:"Bearer"`
+	// ExpiresIn is lifetime of the access token in seconds
+	ExpiresIn int `json:"expires_in" example:"900"`
+}
+
+// Session is the user with tokens of the new session
+type Session struct {
+	User User `json:"user"`
+	Tokens
+}
+
+// RefreshRequest carries refresh token in the body for clients without cookies
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// Credentials are sent by user to log in
+type Credentials struct {
+	Username string `json:"username" example:"Stas001"`
+	Password string `json:"password" example:"amahasla"`
 }
+
 type Drinknames []string
 
 func (ds Drinknames) Value() (driver.Value, error) {
@@ -4,18 +4,21 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/SapolovichSV/backprogeng/internal/dblib"
+	drEnt "github.com/SapolovichSV/backprogeng/internal/drink/entities"
+	drQueries "github.com/SapolovichSV/backprogeng/internal/drink/model/queries"
 	"github.com/SapolovichSV/backprogeng/internal/errlib"
+	"github.com/SapolovichSV/backprogeng/internal/pagination"
 	"github.com/SapolovichSV/backprogeng/internal/user/entities"
 	"github.com/jackc/pgx/v5"
-	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type Query struct {
-	db  *pgxpool.Pool
+	db  dblib.DBTX
 	ctx context.Context
 }
 
-func New(db *pgxpool.Pool, ctx context.Context) *Query {
+func New(db dblib.DBTX, ctx context.Context) *Query {
 	return &Query{
 		db:  db,
 		ctx: ctx,
@@ -37,38 +40,75 @@ func (q *Query) DrinksIdByDrinkNames(drinknames entities.Drinknames) ([]int, err
 	}
 	return drinksId, nil
 }
-func (q *Query) UserWithHisFavsByUserID(id int) (entities.User, error) {
-	queryUser := `SELECT users.username,users.password,drinks.name
-        FROM users INNER JOIN drinks ON drinks.id IN (SELECT favs.drink_id
-	FROM favs
-	WHERE user_id = $1)
-WHERE users.id = $2;`
-	var res entities.User
-	row, _ := q.db.Query(q.ctx, queryUser, id, id)
 
-	for i := 0; row.Next(); i++ {
-		var drinkName string
-		if i == 0 {
-			row.Scan(&res.Username, &res.Password, &drinkName)
-		} else {
-			row.Scan(nil, nil, &drinkName)
-		}
-		res.FavouritesDrinkName = append(res.FavouritesDrinkName, drinkName)
+// UserWithHisFavsByUserID returns the user with role and favourite drinks in id order,
+// user without favourites has empty Favourites, unknown user is errlib.NotFoundErr
+func (q *Query) UserWithHisFavsByUserID(id int) (entities.User, error) {
+	sql := `SELECT users.id, users.username, users.role,
+	COALESCE(array_agg(favs.drink_id ORDER BY favs.drink_id)
+		FILTER (WHERE favs.drink_id IS NOT NULL), '{}') AS fav_ids
+	FROM users
+	LEFT JOIN favs ON favs.user_id = users.id
+	WHERE users.id = $1
+	GROUP BY users.id;`
+	var (
+		res    entities.User
+		favIDs []int
+	)
+	err := q.db.QueryRow(q.ctx, sql, id).Scan(&res.ID, &res.Username, &res.Role, &favIDs)
+	if err != nil {
+		return entities.User{}, errlib.WrapError(err, "users", "user")
 	}
-	if row.Err() != nil {
-		fmt.Println(row.Err())
-		return entities.User{}, errlib.WrapError(row.Err(), "users", "user")
+	res.Favourites = []drEnt.Drink{}
+	if len(favIDs) == 0 {
+		return res, nil
+	}
+	res.Favourites, err = drQueries.New(q.ctx, q.db).DrinksByIDs(favIDs)
+	if err != nil {
+		return entities.User{}, err
 	}
-	// } else if len(res.Username) == 0 {
-	// 	return entities.User{}, errlib.NotFoundErr{Where: "users", What: "user"}
-	// }
 	return res, nil
 }
-func (q *Query) AddToUserNewFavoriteDrink(userID int, drinkID int) error {
+
+// FavouriteDrinks returns user's favourite drinks with tags and recipes
+// page by page in drink id order
+func (q *Query) FavouriteDrinks(userID int, page pagination.Request) ([]drEnt.Drink, error) {
+	sql := drQueries.SelectDrinks + `
+	JOIN favs ON favs.drink_id = drinks.id
+	WHERE favs.user_id = $1 AND drinks.id > $2
+	ORDER BY drinks.id
+	LIMIT $3;`
+	rows, err := q.db.Query(q.ctx, sql, userID, page.AfterID, page.FetchLimit())
+	if err != nil {
+		return nil, errlib.WrapError(err, "favs", "favourite drinks")
+	}
+	return drQueries.ScanDrinks(rows)
+}
+
+// AddToUserNewFavoriteDrink adds the drink to favourites of the user,
+// added is false if the drink is already there
+func (q *Query) AddToUserNewFavoriteDrink(userID int, drinkID int) (added bool, err error) {
 	queryAddToUserNewFavDrink := `INSERT INTO favs (user_id,drink_id)
-	VALUES ($1,$2);`
-	if _, err := q.db.Exec(q.ctx, queryAddToUserNewFavDrink, userID, drinkID); err != nil {
-		return errlib.WrapError(err, "favs", "cannot add new favorite drink")
+	VALUES ($1,$2)
+	ON CONFLICT (user_id, drink_id) DO NOTHING;`
+	res, err := q.db.Exec(q.ctx, queryAddToUserNewFavDrink, userID, drinkID)
+	if err != nil {
+		return false, errlib.WrapError(err, "favs", "cannot add new favorite drink")
+	}
+	return res.RowsAffected() == 1, nil
+}
+
+// DeleteFavoriteDrink removes the drink from favourites of the user,
+// errlib.NotFoundErr is returned if it isn't there
+func (q *Query) DeleteFavoriteDrink(userID int, drinkID int) error {
+	sql := `DELETE FROM favs
+	WHERE user_id = $1 AND drink_id = $2;`
+	res, err := q.db.Exec(q.ctx, sql, userID, drinkID)
+	if err != nil {
+		return errlib.WrapError(err, "favs", "cannot delete favorite drink")
+	}
+	if res.RowsAffected() == 0 {
+		return errlib.NotFoundErr{Where: "favs", What: "favourite drink"}
 	}
 	return nil
 }
@@ -86,12 +126,74 @@ func (q *Query) DrinkIDByName(drinkname string) (int, error) {
 	}
 	return drinkID, nil
 }
-func (q *Query) CreateUser(username string, password string) (int, error) {
+func (q *Query) CreateUser(username string, passwordHash string) (int, error) {
 	sql := "INSERT INTO users (username,password) VALUES ($1,$2) RETURNING id"
 	var userID int
-	err := q.db.QueryRow(q.ctx, sql, username, password).Scan(&userID)
+	err := q.db.QueryRow(q.ctx, sql, username, passwordHash).Scan(&userID)
 	if err != nil {
-		return 0, errlib.WrapError(err, "users", "cannot create user")
+		return 0, errlib.WrapError(err, "users", "username "+username)
 	}
 	return userID, nil
 }
+
+// UserByUsername returns id and username of the user,
+// usernames are unique and compared case-insensitive
+func (q *Query) UserByUsername(username string) (entities.User, error) {
+	sql := `SELECT id, username
+	FROM users
+	WHERE lower(username) = lower($1);`
+	var user entities.User
+	if err := q.db.QueryRow(q.ctx, sql, username).Scan(&user.ID, &user.Username); err != nil {
+		return entities.User{}, errlib.WrapError(err, "users", "user")
+	}
+	return user, nil
+}
+
+// PasswordHashByUserID returns stored password hash of the user,
+// users created before hashing have plaintext password there
+func (q *Query) PasswordHashByUserID(userID int) (string, error) {
+	sql := `SELECT COALESCE(password, '')
+	FROM users
+	WHERE id = $1;`
+	var hash string
+	if err := q.db.QueryRow(q.ctx, sql, userID).Scan(&hash); err != nil {
+		return "", errlib.WrapError(err, "users", "user")
+	}
+	return hash, nil
+}
+func (q *Query) SetPasswordHash(userID int, hash string) error {
+	sql := `UPDATE users
+	SET password = $2
+	WHERE id = $1;`
+	res, err := q.db.Exec(q.ctx, sql, userID, hash)
+	if err != nil {
+		return errlib.WrapError(err, "users", "password can't be updated")
+	}
+	if res.RowsAffected() == 0 {
+		return errlib.NotFoundErr{Where: "users", What: "user"}
+	}
+	return nil
+}
+func (q *Query) RoleByUserID(userID int) (string, error) {
+	sql := `SELECT role
+	FROM users
+	WHERE id = $1;`
+	var role string
+	if err := q.db.QueryRow(q.ctx, sql, userID).Scan(&role); err != nil {
+		return "", errlib.WrapError(err, "users", "user")
+	}
+	return role, nil
+}
+func (q *Query) SetRole(userID int, role string) error {
+	sql := `UPDATE users
+	SET role = $2
+	WHERE id = $1;`
+	res, err := q.db.Exec(q.ctx, sql, userID, role)
+	if err != nil {
+		return errlib.WrapError(err, "users", "role can't be updated")
+	}
+	if res.RowsAffected() == 0 {
+		return errlib.NotFoundErr{Where: "users", What: "user"}
+	}
+	return nil
+}
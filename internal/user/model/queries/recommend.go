@@ -0,0 +1,92 @@
+package queries
+
+import (
+	"github.com/SapolovichSV/backprogeng/internal/errlib"
+)
+
+// Scored is a drink ranked for the user, see RecommendedDrinks
+type Scored struct {
+	DrinkID      int
+	SharedTags   []string
+	CoFavourites int
+	TagScore     float64
+	CoScore      float64
+	Score        float64
+}
+
+// RecommendedDrinks ranks drinks the user hasn't favourited by
+// tagWeight * tag_score + coWeight * co_score, drinks with zero score are skipped.
+// tag_score is Jaccard similarity of the drink tags and tags of the user's favourites,
+// co_score is a share of users with common favourites who have favourited the drink
+func (q *Query) RecommendedDrinks(userID int, tagWeight float64, coWeight float64, limit int) ([]Scored, error) {
+	sql := `WITH my_favs AS (
+		SELECT drink_id
+		FROM favs
+		WHERE user_id = $1
+	),
+	my_tags AS (
+		SELECT DISTINCT drink_tags.tag_id
+		FROM drink_tags
+		JOIN my_favs ON my_favs.drink_id = drink_tags.drink_id
+	),
+	-- соседи: другие пользователи, у которых есть общий с нами любимый напиток
+	neighbours AS (
+		SELECT DISTINCT favs.user_id
+		FROM favs
+		JOIN my_favs ON my_favs.drink_id = favs.drink_id
+		WHERE favs.user_id <> $1
+	),
+	candidates AS (
+		SELECT drinks.id,
+			ARRAY(SELECT tags.name
+				FROM drink_tags
+				JOIN tags ON tags.id = drink_tags.tag_id
+				WHERE drink_tags.drink_id = drinks.id
+				AND drink_tags.tag_id IN (SELECT tag_id FROM my_tags)
+				ORDER BY tags.name) AS shared_tags,
+			(SELECT count(*)
+				FROM drink_tags
+				WHERE drink_tags.drink_id = drinks.id) AS own_tags,
+			(SELECT count(*)
+				FROM favs
+				WHERE favs.drink_id = drinks.id
+				AND favs.user_id IN (SELECT user_id FROM neighbours)) AS co_favourites
+		FROM drinks
+		WHERE drinks.id NOT IN (SELECT drink_id FROM my_favs)
+	),
+	scored AS (
+		SELECT id, shared_tags, co_favourites,
+			-- |общие теги| / |объединение тегов|
+			CASE WHEN cardinality(shared_tags) = 0 THEN 0
+				ELSE cardinality(shared_tags)::float8
+					/ (own_tags + (SELECT count(*) FROM my_tags) - cardinality(shared_tags))
+			END AS tag_score,
+			CASE WHEN co_favourites = 0 THEN 0
+				ELSE co_favourites::float8 / (SELECT count(*) FROM neighbours)
+			END AS co_score
+		FROM candidates
+		WHERE cardinality(shared_tags) > 0 OR co_favourites > 0
+	)
+	SELECT id, shared_tags, co_favourites, tag_score, co_score,
+		$2 * tag_score + $3 * co_score AS score
+	FROM scored
+	ORDER BY score DESC, id
+	LIMIT $4;`
+	rows, err := q.db.Query(q.ctx, sql, userID, tagWeight, coWeight, limit)
+	if err != nil {
+		return nil, errlib.WrapError(err, "favs", "recommended drinks")
+	}
+	defer rows.Close()
+	var res []Scored
+	for rows.Next() {
+		var s Scored
+		if err := rows.Scan(&s.DrinkID, &s.SharedTags, &s.CoFavourites, &s.TagScore, &s.CoScore, &s.Score); err != nil {
+			return nil, errlib.WrapError(err, "favs", "recommended drinks")
+		}
+		res = append(res, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errlib.WrapError(err, "favs", "recommended drinks")
+	}
+	return res, nil
+}
@@ -2,80 +2,249 @@ package model
 
 import (
 	"context"
-	"fmt"
+	"errors"
+	"strconv"
+	"strings"
 
+	"github.com/SapolovichSV/backprogeng/internal/dblib"
+	drEnt "github.com/SapolovichSV/backprogeng/internal/drink/entities"
+	drQueries "github.com/SapolovichSV/backprogeng/internal/drink/model/queries"
+	"github.com/SapolovichSV/backprogeng/internal/errlib"
+	"github.com/SapolovichSV/backprogeng/internal/pagination"
 	"github.com/SapolovichSV/backprogeng/internal/user/entities"
 	"github.com/SapolovichSV/backprogeng/internal/user/model/queries"
-	"github.com/SapolovichSV/backprogeng/internal/user/model/validate"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+var ErrWrongPassword = errors.New("wrong password")
+
 type SQLUserModel struct {
-	db *pgxpool.Pool
+	db     *pgxpool.Pool
+	hasher passwordHasher
+}
+
+// passwordHasher is implemented by hasher.Hasher
+type passwordHasher interface {
+	Hash(password string) (string, error)
+	Verify(hash, password string) (ok bool, needsRehash bool, err error)
+	VerifyDummy(password string)
 }
 
 //go:generate mockgen -destination=../mocks/user/mock_user.go -package=mocks github.com/SapolovichSV/backprogeng/internal/user/model userModel
 type userModel interface {
 	CreateUser(ctx context.Context, user entities.User) (entities.User, error)
 	UserByID(ctx context.Context, id int) (entities.User, error)
+	UserByUsername(ctx context.Context, username string) (entities.User, error)
 	AddFav(ctx context.Context, drinkName string, useriD int) (entities.User, error)
+	RemoveFav(ctx context.Context, drinkName string, userID int) error
+	Favourites(ctx context.Context, userID int, page pagination.Request) (pagination.Page[drEnt.Drink], error)
+	Recommendations(ctx context.Context, userID int, limit int) ([]entities.Recommendation, error)
+	VerifyPassword(ctx context.Context, userID int, password string) error
+	VerifyUnknownUserPassword(ctx context.Context, password string) error
+	RoleByUserID(ctx context.Context, userID int) (string, error)
+	SetRole(ctx context.Context, userID int, role string) (entities.User, error)
 }
 
-func New(db *pgxpool.Pool) *SQLUserModel {
+func New(db *pgxpool.Pool, hasher passwordHasher) *SQLUserModel {
 	return &SQLUserModel{
-		db: db,
+		db:     db,
+		hasher: hasher,
 	}
 }
 
+// CreateUser stores user with hashed password,
+// returned user has no password
 func (m *SQLUserModel) CreateUser(ctx context.Context, user entities.User) (entities.User, error) {
-	if err := validate.UserName(user.Username); err != nil {
+	hash, err := m.hasher.Hash(user.Password)
+	if err != nil {
+		return entities.User{}, errlib.WrapErr(err, "create user")
+	}
+	user.Password = ""
+	// роль при регистрации не выбирают, её меняет только админ
+	user.Role = entities.RoleUser
+	err = dblib.WithTx(ctx, m.db, func(tx dblib.DBTX) error {
+		query := queries.New(tx, ctx)
+		drinksId, err := query.DrinksIdByDrinkNames(user.FavouritesDrinkName)
+		if err != nil {
+			return err
+		}
+		user.ID, err = query.CreateUser(user.Username, hash)
+		if err != nil {
+			return err
+		}
+		for _, drinkID := range drinksId {
+			if _, err := query.AddToUserNewFavoriteDrink(user.ID, drinkID); err != nil {
+				return err
+			}
+		}
+		user, err = query.UserWithHisFavsByUserID(user.ID)
+		return err
+	})
+	if err != nil {
 		return entities.User{}, err
 	}
-	if err := validate.VPassword(user.Password); err != nil {
+	return user, nil
+}
+
+// UserByID returns the user with role and favourite drinks
+func (m *SQLUserModel) UserByID(ctx context.Context, id int) (entities.User, error) {
+	return queries.New(m.db, ctx).UserWithHisFavsByUserID(id)
+}
+
+// RoleByUserID returns role of the user, it is put into tokens
+func (m *SQLUserModel) RoleByUserID(ctx context.Context, userID int) (string, error) {
+	return queries.New(m.db, ctx).RoleByUserID(userID)
+}
+
+// SetRole changes role of the user and returns the user
+func (m *SQLUserModel) SetRole(ctx context.Context, userID int, role string) (entities.User, error) {
+	if role != entities.RoleUser && role != entities.RoleAdmin {
+		return entities.User{}, errlib.InvalidInputErr{What: "role", Reason: "must be one of user, admin"}
+	}
+	if err := queries.New(m.db, ctx).SetRole(userID, role); err != nil {
 		return entities.User{}, err
 	}
+	return m.UserByID(ctx, userID)
+}
+
+// UserByUsername returns user without favourites and password
+func (m *SQLUserModel) UserByUsername(ctx context.Context, username string) (entities.User, error) {
+	return queries.New(m.db, ctx).UserByUsername(username)
+}
+
+// AddFav adds the drink to favourites of the user and returns the user,
+// adding the drink which is already there changes nothing
+func (m *SQLUserModel) AddFav(ctx context.Context, drinkName string, userID int) (entities.User, error) {
 	query := queries.New(m.db, ctx)
-	drinksId, err := query.DrinksIdByDrinkNames(user.FavouritesDrinkName)
+	drinkID, err := query.DrinkIDByName(drinkName)
 	if err != nil {
 		return entities.User{}, err
 	}
-	user.ID, err = query.CreateUser(user.Username, user.Password)
-	if err != nil {
+	if _, err := query.AddToUserNewFavoriteDrink(userID, drinkID); err != nil {
 		return entities.User{}, err
 	}
-	for _, drinkID := range drinksId {
-		query.AddToUserNewFavoriteDrink(user.ID, drinkID)
-	}
-	return user, nil
+	return query.UserWithHisFavsByUserID(userID)
 }
 
-func (m *SQLUserModel) UserByID(ctx context.Context, id int) (entities.User, error) {
-	userRes := entities.User{}
+// RemoveFav removes the drink from favourites of the user,
+// errlib.NotFoundErr is returned if there is no such drink or it isn't favourite
+func (m *SQLUserModel) RemoveFav(ctx context.Context, drinkName string, userID int) error {
 	query := queries.New(m.db, ctx)
-	userRes, err := query.UserWithHisFavsByUserID(id)
+	drinkID, err := query.DrinkIDByName(drinkName)
 	if err != nil {
-		return entities.User{}, err
+		return err
+	}
+	return query.DeleteFavoriteDrink(userID, drinkID)
+}
+
+// Favourites returns user's favourite drinks page by page in drink id order
+func (m *SQLUserModel) Favourites(ctx context.Context, userID int, page pagination.Request) (pagination.Page[drEnt.Drink], error) {
+	drinks, err := queries.New(m.db, ctx).FavouriteDrinks(userID, page)
+	if err != nil {
+		return pagination.Page[drEnt.Drink]{}, err
 	}
-	userRes.ID = id
-	return userRes, nil
+	return pagination.NewPage(drinks, page, func(d drEnt.Drink) int { return d.ID }), nil
 }
-func (m *SQLUserModel) AddFav(ctx context.Context, drinkName string, userID int) (res entities.User, err error) {
+
+// Weights of the recommendation score, see Recommendations
+const (
+	RecommendTagWeight = 0.6
+	RecommendCoWeight  = 0.4
+)
+
+// Recommendations returns up to limit drinks the user hasn't favourited,
+// ranked by tag overlap with favourite drinks and by how often the drink
+// is favourited by users with common favourites. User without favourites gets nothing
+func (m *SQLUserModel) Recommendations(ctx context.Context, userID int, limit int) ([]entities.Recommendation, error) {
 	query := queries.New(m.db, ctx)
-	drinkID, err := query.DrinkIDByName(drinkName)
+	scored, err := query.RecommendedDrinks(userID, RecommendTagWeight, RecommendCoWeight, limit)
 	if err != nil {
-		fmt.Println(err.Error() + "storage add fav drink ID by name")
-		return entities.User{}, err
-	} // err == nil
-	res, err = query.UserWithHisFavsByUserID(userID)
+		return nil, err
+	}
+	res := []entities.Recommendation{}
+	if len(scored) == 0 {
+		return res, nil
+	}
+	ids := make([]int, len(scored))
+	for i, s := range scored {
+		ids[i] = s.DrinkID
+	}
+	drinks, err := drQueries.New(ctx, m.db).DrinksByIDs(ids)
 	if err != nil {
-		fmt.Println(err.Error() + "storage add fav userwithfavs")
-		return entities.User{}, err
+		return nil, err
 	}
-	if err := query.AddToUserNewFavoriteDrink(userID, drinkID); err != nil {
-		fmt.Println(err.Error() + "storage add fav add to user ")
-		return entities.User{}, err
+	byID := make(map[int]drEnt.Drink, len(drinks))
+	for _, d := range drinks {
+		byID[d.ID] = d
+	}
+	for _, s := range scored {
+		d, ok := byID[s.DrinkID]
+		if !ok {
+			// напиток удалили между запросами
+			continue
+		}
+		res = append(res, entities.Recommendation{
+			Drink: d,
+			Score: s.Score,
+			Explanation: entities.Explanation{
+				SharedTags:   s.SharedTags,
+				TagScore:     s.TagScore,
+				CoFavourites: s.CoFavourites,
+				CoScore:      s.CoScore,
+				Reason:       reason(s),
+			},
+		})
 	}
-	res.FavouritesDrinkName = append(res.FavouritesDrinkName, drinkName)
-	res.ID = userID
 	return res, nil
 }
+
+// reason описывает оценку рекомендации словами
+func reason(s queries.Scored) string {
+	var parts []string
+	if len(s.SharedTags) > 0 {
+		parts = append(parts, "shares tags "+strings.Join(s.SharedTags, ", ")+" with your favourites")
+	}
+	if s.CoFavourites == 1 {
+		parts = append(parts, "favourited by 1 user who likes your drinks")
+	} else if s.CoFavourites > 1 {
+		parts = append(parts, "favourited by "+strconv.Itoa(s.CoFavourites)+" users who like your drinks")
+	}
+	return strings.Join(parts, "; ")
+}
+
+// VerifyPassword checks password of the user and returns errlib.UnauthorizedErr
+// if it doesn't match. Hash of the matched password is replaced with a fresh one
+// when it is made by another algorithm or is a legacy plaintext value
+func (m *SQLUserModel) VerifyPassword(ctx context.Context, userID int, password string) error {
+	query := queries.New(m.db, ctx)
+	hash, err := query.PasswordHashByUserID(userID)
+	if err != nil {
+		return err
+	}
+	if hash == "" {
+		return errlib.UnauthorizedErr{Err: ErrWrongPassword}
+	}
+	ok, needsRehash, err := m.hasher.Verify(hash, password)
+	if err != nil {
+		return errlib.WrapErr(err, "verify password")
+	}
+	if !ok {
+		return errlib.UnauthorizedErr{Err: ErrWrongPassword}
+	}
+	if !needsRehash {
+		return nil
+	}
+	hash, err = m.hasher.Hash(password)
+	if err != nil {
+		return errlib.WrapErr(err, "rehash password")
+	}
+	return query.SetPasswordHash(userID, hash)
+}
+
+// VerifyUnknownUserPassword spends as much time as VerifyPassword checking
+// the password against a dummy hash and returns errlib.UnauthorizedErr,
+// so the response time doesn't tell if the user exists
+func (m *SQLUserModel) VerifyUnknownUserPassword(ctx context.Context, password string) error {
+	m.hasher.VerifyDummy(password)
+	return errlib.UnauthorizedErr{Err: ErrWrongPassword}
+}
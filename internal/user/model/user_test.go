@@ -6,6 +6,8 @@ import (
 
 	drEnt "github.com/SapolovichSV/backprogeng/internal/drink/entities"
 	"github.com/SapolovichSV/backprogeng/internal/drink/model"
+	"github.com/SapolovichSV/backprogeng/internal/errlib"
+	"github.com/SapolovichSV/backprogeng/internal/hasher"
 	"github.com/SapolovichSV/backprogeng/internal/user/entities"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/stretchr/testify/assert"
@@ -15,22 +17,73 @@ import (
 const QUERY_CREATE_TABLES = `CREATE TABLE drinks (
     id SERIAL PRIMARY KEY,
     name VARCHAR(255) NOT NULL,
-    tags TEXT
+    description TEXT NOT NULL DEFAULT '',
+    price INT NOT NULL DEFAULT 0 CHECK (price >= 0),
+    alcoholic BOOLEAN NOT NULL DEFAULT true,
+    preparation VARCHAR(16) NOT NULL DEFAULT 'mixed'
+        CHECK (preparation IN ('mixed', 'aged', 'iced', 'blended'))
+);
+CREATE UNIQUE INDEX drinks_name_lower_key ON drinks (lower(name));
+CREATE TABLE tags (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL
+);
+CREATE UNIQUE INDEX tags_name_lower_idx ON tags (lower(name));
+CREATE TABLE drink_tags (
+    drink_id INT NOT NULL,
+    FOREIGN KEY (drink_id) REFERENCES drinks(id) ON DELETE CASCADE,
+    tag_id INT NOT NULL,
+    FOREIGN KEY (tag_id) REFERENCES tags(id) ON DELETE CASCADE,
+    PRIMARY KEY (drink_id, tag_id)
+);
+CREATE TABLE ingredients (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL
+);
+CREATE UNIQUE INDEX ingredients_name_lower_idx ON ingredients (lower(name));
+CREATE TABLE drink_ingredients (
+    drink_id INT NOT NULL,
+    FOREIGN KEY (drink_id) REFERENCES drinks(id) ON DELETE CASCADE,
+    ingredient_id INT NOT NULL,
+    FOREIGN KEY (ingredient_id) REFERENCES ingredients(id) ON DELETE RESTRICT,
+    position INT NOT NULL,
+    amount NUMERIC(10, 2) NOT NULL CHECK (amount > 0),
+    unit VARCHAR(32) NOT NULL DEFAULT '',
+    PRIMARY KEY (drink_id, ingredient_id)
 );
 CREATE TABLE users (
     id SERIAL PRIMARY KEY,
     username VARCHAR(255) NOT NULL,
-    password VARCHAR(255)
+    password VARCHAR(255),
+    role VARCHAR(16) NOT NULL DEFAULT 'user'
 );
+CREATE UNIQUE INDEX users_username_lower_key ON users (lower(username));
 CREATE TABLE favs (
     user_id INT NOT NULL,
     FOREIGN KEY (user_id) REFERENCES users(id)  ON DELETE CASCADE,
     drink_id INT NOT NULL,
-   FOREIGN KEY (drink_id) REFERENCES drinks(id) ON DELETE CASCADE
+   FOREIGN KEY (drink_id) REFERENCES drinks(id) ON DELETE CASCADE,
+    PRIMARY KEY (user_id, drink_id)
+);
+CREATE TABLE ratings (
+    user_id INT NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    drink_id INT NOT NULL,
+    FOREIGN KEY (drink_id) REFERENCES drinks(id) ON DELETE CASCADE,
+    score SMALLINT NOT NULL CHECK (score BETWEEN 1 AND 5),
+    review VARCHAR(1000) NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (user_id, drink_id)
 );`
-const QUERY_DROP_TABLES = `DROP TABLE drinks CASCADE;
+const QUERY_DROP_TABLES = `DROP TABLE drink_ingredients CASCADE;
+DROP TABLE ingredients CASCADE;
+DROP TABLE drink_tags CASCADE;
+DROP TABLE tags CASCADE;
+DROP TABLE drinks CASCADE;
 DROP TABLE users CASCADE;
-DROP TABLE favs CASCADE;`
+DROP TABLE favs CASCADE;
+DROP TABLE ratings CASCADE;`
 
 type TestInit struct{}
 
@@ -49,6 +102,19 @@ func (t *TestInit) Init() (context.Context, *pgxpool.Pool, error) {
 	}
 	return ctx, db, nil
 }
+func newTestHasher() *hasher.Hasher {
+	h, _ := hasher.New(hasher.Bcrypt)
+	return h
+}
+
+// favNames оставляет от любимых напитков только имена, чтобы сравнивать с FavouritesDrinkName
+func favNames(user entities.User) entities.Drinknames {
+	names := entities.Drinknames{}
+	for _, drink := range user.Favourites {
+		names = append(names, drink.Name)
+	}
+	return names
+}
 func TestSQLUserModel_CreateUser(t *testing.T) {
 	ctx, db, err := NewTest().Init()
 	assert.NoError(t, err)
@@ -87,29 +153,19 @@ func TestSQLUserModel_CreateUser(t *testing.T) {
 			},
 		},
 		{
-			name: "BadUserName",
+			// username и пароль проверяет validate до модели, здесь только уникальность
+			name: "UsernameTaken",
 			beforeCreate: entities.User{
-				Username:            "",
+				Username:            "STAS001",
 				Password:            "amahasla",
 				FavouritesDrinkName: nil,
 			},
 			wantErr:                    true,
 			mustCreateDrinksBeforeTest: false,
-			drinks:                     nil,
-		},
-		{
-			name: "BadPassword",
-			beforeCreate: entities.User{
-				Username:            "Stas003",
-				Password:            "",
-				FavouritesDrinkName: nil,
-			},
-			wantErr:                    true,
-			mustCreateDrinksBeforeTest: false,
 		},
 	}
 	drinkModel := model.New(db)
-	m := New(db)
+	m := New(db, newTestHasher())
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			if tt.mustCreateDrinksBeforeTest {
@@ -122,8 +178,10 @@ func TestSQLUserModel_CreateUser(t *testing.T) {
 				assert.Error(t, err)
 			} else {
 				assert.NoError(t, err)
-				tt.beforeCreate.ID = have.ID
-				assert.Equal(t, tt.beforeCreate, have)
+				assert.Equal(t, tt.beforeCreate.Username, have.Username)
+				assert.Equal(t, "user", have.Role)
+				assert.Empty(t, have.Password)
+				assert.Equal(t, tt.beforeCreate.FavouritesDrinkName, favNames(have))
 			}
 
 		})
@@ -135,67 +193,62 @@ func TestSQLUserModel_UserByID(t *testing.T) {
 	assert.NoError(t, err)
 	defer db.Close()
 	defer db.Exec(ctx, QUERY_DROP_TABLES)
+	drinkModel := model.New(db)
+	m := New(db, newTestHasher())
+	for _, drink := range []drEnt.Drink{
+		{Name: "cola", Tags: []string{"spicy", "spice"}},
+		{Name: "pussy", Tags: []string{"sweet", "spyce"}},
+	} {
+		_, err := drinkModel.CreateDrink(ctx, drink)
+		assert.NoError(t, err)
+	}
+	withFavs, err := m.CreateUser(ctx, entities.User{Username: "Stas228", Password: "amahasla", FavouritesDrinkName: []string{"pussy", "cola"}})
+	assert.NoError(t, err)
+	withoutFavs, err := m.CreateUser(ctx, entities.User{Username: "Stas229", Password: "amahasla"})
+	assert.NoError(t, err)
 	tests := []struct {
-		name                       string
-		want                       entities.User
-		wantErr                    bool
-		mustCreateUserBeforeTest   bool
-		mustCreateDrinksBeforeTest bool
-		drinks                     []drEnt.Drink
+		name     string
+		id       int
+		wantName string
+		wantFavs entities.Drinknames
+		wantErr  bool
 	}{
 		{
-			name: "SimpleTest",
-			want: entities.User{
-				ID:                  0,
-				Username:            "Stas228",
-				Password:            "amahasla",
-				FavouritesDrinkName: []string{"cola", "pussy"},
-			},
-			wantErr:                    false,
-			mustCreateUserBeforeTest:   true,
-			mustCreateDrinksBeforeTest: true,
-			drinks: []drEnt.Drink{
-				{Name: "cola", Tags: []string{"spicy", "spice"}},
-				{Name: "pussy", Tags: []string{"sweet", "spyce"}},
-			},
+			// любимые напитки идут в порядке id, а не в порядке добавления
+			name:     "SimpleTest",
+			id:       withFavs.ID,
+			wantName: "Stas228",
+			wantFavs: entities.Drinknames{"cola", "pussy"},
 		},
 		{
-			name: "UserNotExist",
-			want: entities.User{
-				ID: 228,
-			},
-			wantErr:                    true,
-			mustCreateUserBeforeTest:   false,
-			mustCreateDrinksBeforeTest: false,
-			drinks:                     nil,
+			// пользователь без любимых напитков тоже находится
+			name:     "WithoutFavourites",
+			id:       withoutFavs.ID,
+			wantName: "Stas229",
+			wantFavs: entities.Drinknames{},
+		},
+		{
+			name:    "UserNotExist",
+			id:      228,
+			wantErr: true,
 		},
 	}
-	drinkModel := model.New(db)
-	m := New(db)
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if tt.mustCreateUserBeforeTest {
-				if tt.mustCreateDrinksBeforeTest {
-					for _, drink := range tt.drinks {
-						drinkModel.CreateDrink(ctx, drink)
-					}
-				}
-				user, err := m.CreateUser(ctx, tt.want)
-				if tt.wantErr {
-					assert.Error(t, err)
-				} else {
-					assert.NoError(t, err)
-					tt.want.ID = user.ID
-					assert.Equal(t, tt.want, user)
-				}
-			} else {
-				user, err := m.UserByID(ctx, tt.want.ID)
-				if tt.wantErr {
-					assert.Error(t, err)
-				} else {
-					assert.NoError(t, err)
-					assert.Equal(t, tt.want, user)
-				}
+			user, err := m.UserByID(ctx, tt.id)
+			if tt.wantErr {
+				assert.True(t, errlib.CheckErrNotFound(err))
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.id, user.ID)
+			assert.Equal(t, tt.wantName, user.Username)
+			assert.Equal(t, "user", user.Role)
+			assert.NotNil(t, user.Favourites)
+			assert.Equal(t, tt.wantFavs, favNames(user))
+			for _, drink := range user.Favourites {
+				assert.NotZero(t, drink.ID)
+				assert.NotEmpty(t, drink.Tags)
 			}
 		})
 	}
@@ -225,7 +278,6 @@ func TestSQLUserModel_AddFav(t *testing.T) {
 			},
 			want: entities.User{
 				Username:            "Stas228",
-				Password:            "amahasla",
 				FavouritesDrinkName: []string{"cola", "pussy", "pepsi"},
 			},
 			wantErr:                    false,
@@ -238,9 +290,25 @@ func TestSQLUserModel_AddFav(t *testing.T) {
 			},
 			addingDrinkName: "pepsi",
 		},
+		{
+			// повторное добавление ничего не меняет
+			name: "AlreadyFavourite",
+			beforeAdd: entities.User{
+				Username:            "Stas229",
+				Password:            "amahasla",
+				FavouritesDrinkName: []string{"cola"},
+			},
+			want: entities.User{
+				Username:            "Stas229",
+				FavouritesDrinkName: []string{"cola"},
+			},
+			wantErr:                  false,
+			mustCreateUserBeforeTest: true,
+			addingDrinkName:          "cola",
+		},
 	}
 	drinkModel := model.New(db)
-	m := New(db)
+	m := New(db, newTestHasher())
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			if tt.mustCreateUserBeforeTest {
@@ -253,12 +321,13 @@ func TestSQLUserModel_AddFav(t *testing.T) {
 				assert.NoError(t, err)
 
 				have, err := m.AddFav(ctx, tt.addingDrinkName, createdUser.ID)
-				tt.want.ID = createdUser.ID
 				if tt.wantErr {
 					assert.Error(t, err)
 				} else {
 					assert.NoError(t, err)
-					assert.Equal(t, tt.want, have)
+					assert.Equal(t, createdUser.ID, have.ID)
+					assert.Equal(t, tt.want.Username, have.Username)
+					assert.Equal(t, tt.want.FavouritesDrinkName, favNames(have))
 				}
 			} else {
 				if tt.mustCreateDrinksBeforeTest {
@@ -274,3 +343,132 @@ func TestSQLUserModel_AddFav(t *testing.T) {
 		})
 	}
 }
+
+func TestSQLUserModel_RemoveFav(t *testing.T) {
+	ctx, db, err := NewTest().Init()
+	assert.NoError(t, err)
+	defer db.Close()
+	defer db.Exec(ctx, QUERY_DROP_TABLES)
+	drinkModel := model.New(db)
+	m := New(db, newTestHasher())
+
+	_, err = drinkModel.CreateDrink(ctx, drEnt.Drink{Name: "cola"})
+	assert.NoError(t, err)
+	_, err = drinkModel.CreateDrink(ctx, drEnt.Drink{Name: "pepsi"})
+	assert.NoError(t, err)
+	user, err := m.CreateUser(ctx, entities.User{Username: "Stas001", Password: "amahasla", FavouritesDrinkName: []string{"cola", "pepsi"}})
+	assert.NoError(t, err)
+
+	assert.NoError(t, m.RemoveFav(ctx, "cola", user.ID))
+	assert.True(t, errlib.CheckErrNotFound(m.RemoveFav(ctx, "cola", user.ID)))
+	assert.True(t, errlib.CheckErrNotFound(m.RemoveFav(ctx, "sprite", user.ID)))
+	have, err := m.UserByID(ctx, user.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, entities.Drinknames{"pepsi"}, favNames(have))
+}
+
+func TestSQLUserModel_Recommendations(t *testing.T) {
+	ctx, db, err := NewTest().Init()
+	assert.NoError(t, err)
+	defer db.Close()
+	defer db.Exec(ctx, QUERY_DROP_TABLES)
+	drinkModel := model.New(db)
+	m := New(db, newTestHasher())
+	for _, drink := range []drEnt.Drink{
+		{Name: "Bad Touch", Tags: []string{"sweet", "sour"}},
+		{Name: "Sugar Rush", Tags: []string{"sweet"}},
+		{Name: "Piano Man", Tags: []string{"sour", "bitter"}},
+		{Name: "Fluffy Dream", Tags: []string{"spicy"}},
+		{Name: "Zen Star", Tags: []string{"salty"}},
+	} {
+		_, err := drinkModel.CreateDrink(ctx, drink)
+		assert.NoError(t, err)
+	}
+	users := map[string][]string{
+		"Stas001": {"Bad Touch"},
+		"Vova001": {"Bad Touch", "Fluffy Dream"},
+		"Olga001": {"Bad Touch", "Fluffy Dream"},
+		// нет общих любимых напитков со Stas001, Zen Star ему не советуем
+		"Ivan001": {"Zen Star"},
+		"Anna001": nil,
+	}
+	ids := map[string]int{}
+	for username, favs := range users {
+		user, err := m.CreateUser(ctx, entities.User{Username: username, Password: "amahasla", FavouritesDrinkName: favs})
+		assert.NoError(t, err)
+		ids[username] = user.ID
+	}
+
+	have, err := m.Recommendations(ctx, ids["Stas001"], 10)
+	assert.NoError(t, err)
+	names := []string{}
+	for _, r := range have {
+		names = append(names, r.Drink.Name)
+	}
+	// Fluffy Dream: 0.4 * 2/2, Sugar Rush: 0.6 * 1/2, Piano Man: 0.6 * 1/3
+	assert.Equal(t, []string{"Fluffy Dream", "Sugar Rush", "Piano Man"}, names)
+	if assert.Len(t, have, 3) {
+		assert.InDelta(t, 0.4, have[0].Score, 1e-9)
+		assert.Equal(t, 2, have[0].Explanation.CoFavourites)
+		assert.Empty(t, have[0].Explanation.SharedTags)
+		assert.Equal(t, "favourited by 2 users who like your drinks", have[0].Explanation.Reason)
+		assert.InDelta(t, 0.3, have[1].Score, 1e-9)
+		assert.Equal(t, []string{"sweet"}, have[1].Explanation.SharedTags)
+		assert.InDelta(t, 0.5, have[1].Explanation.TagScore, 1e-9)
+		assert.InDelta(t, 0.2, have[2].Score, 1e-9)
+	}
+
+	have, err = m.Recommendations(ctx, ids["Stas001"], 1)
+	assert.NoError(t, err)
+	assert.Len(t, have, 1)
+
+	have, err = m.Recommendations(ctx, ids["Anna001"], 10)
+	assert.NoError(t, err)
+	assert.NotNil(t, have)
+	assert.Empty(t, have)
+}
+
+func TestSQLUserModel_VerifyPassword(t *testing.T) {
+	ctx, db, err := NewTest().Init()
+	assert.NoError(t, err)
+	defer db.Close()
+	defer db.Exec(ctx, QUERY_DROP_TABLES)
+	m := New(db, newTestHasher())
+
+	user, err := m.CreateUser(ctx, entities.User{Username: "Stas001", Password: "amahasla"})
+	assert.NoError(t, err)
+	assert.NoError(t, m.VerifyPassword(ctx, user.ID, "amahasla"))
+	assert.True(t, errlib.CheckErrUnauthorized(m.VerifyPassword(ctx, user.ID, "amahaslo")))
+	assert.True(t, errlib.CheckErrUnauthorized(m.VerifyUnknownUserPassword(ctx, "amahasla")))
+
+	// пользователь из времён, когда пароли хранились открытым текстом
+	var legacyID int
+	err = db.QueryRow(ctx, `INSERT INTO users (username, password) VALUES ('Stas002', 'amahasla') RETURNING id`).Scan(&legacyID)
+	assert.NoError(t, err)
+	assert.True(t, errlib.CheckErrUnauthorized(m.VerifyPassword(ctx, legacyID, "amahaslo")))
+	assert.NoError(t, m.VerifyPassword(ctx, legacyID, "amahasla"))
+
+	var stored string
+	err = db.QueryRow(ctx, `SELECT password FROM users WHERE id = $1`, legacyID).Scan(&stored)
+	assert.NoError(t, err)
+	assert.NotEqual(t, "amahasla", stored)
+	assert.NoError(t, m.VerifyPassword(ctx, legacyID, "amahasla"))
+}
+
+func TestSQLUserModel_UserByUsername(t *testing.T) {
+	ctx, db, err := NewTest().Init()
+	assert.NoError(t, err)
+	defer db.Close()
+	defer db.Exec(ctx, QUERY_DROP_TABLES)
+	m := New(db, newTestHasher())
+
+	created, err := m.CreateUser(ctx, entities.User{Username: "Stas001", Password: "amahasla"})
+	assert.NoError(t, err)
+
+	user, err := m.UserByUsername(ctx, "Stas001")
+	assert.NoError(t, err)
+	assert.Equal(t, entities.User{ID: created.ID, Username: "Stas001"}, user)
+
+	_, err = m.UserByUsername(ctx, "Stas002")
+	assert.True(t, errlib.CheckErrNotFound(err))
+}
@@ -0,0 +1,221 @@
+// Package validate checks user and drink input before it reaches the models
+// and reports every failed rule at once
+package validate
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/SapolovichSV/backprogeng/internal/config"
+	drEnt "github.com/SapolovichSV/backprogeng/internal/drink/entities"
+	"github.com/SapolovichSV/backprogeng/internal/errlib"
+	ratingEnt "github.com/SapolovichSV/backprogeng/internal/rating/entities"
+	"github.com/SapolovichSV/backprogeng/internal/user/entities"
+)
+
+// Rules reported in errlib.FieldError.Rule
+const (
+	RuleRequired    = "required"
+	RuleMinLength   = "min_length"
+	RuleMaxLength   = "max_length"
+	RuleCharset     = "charset"
+	RuleNotUsername = "not_username"
+	RuleBreached    = "breached"
+	RuleOneOf       = "one_of"
+	RuleMin         = "min"
+	RuleMax         = "max"
+	RulePositive    = "positive"
+	RuleUnique      = "unique"
+	// RuleRequirePrefix is followed by the class of characters: require_digit
+	RuleRequirePrefix = "require_"
+)
+
+// drinkTextMaxLength is the size of varchar columns of the drink catalog
+const drinkTextMaxLength = 255
+
+// Errors are all failed rules of the input, http_infra sends them as the errors list
+type Errors []errlib.FieldError
+
+func (e Errors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, fe := range e {
+		msgs = append(msgs, fe.Field+" "+fe.Message)
+	}
+	return "validation failed: " + strings.Join(msgs, "; ")
+}
+
+// Err returns nil if nothing failed, so Errors can be returned as error
+func (e Errors) Err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+func (e *Errors) add(field, rule, message string) {
+	*e = append(*e, errlib.FieldError{Field: field, Rule: rule, Message: message})
+}
+
+type Validator struct {
+	cfg      config.Validation
+	username *regexp.Regexp
+	breached map[string]struct{}
+}
+
+// New compiles the rules and loads the breached passwords wordlist
+func New(cfg config.Validation) (*Validator, error) {
+	username, err := regexp.Compile("^[" + cfg.UsernameCharset + "]+$")
+	if err != nil {
+		return nil, errlib.WrapErr(err, "username charset")
+	}
+	v := &Validator{
+		cfg:      cfg,
+		username: username,
+		breached: map[string]struct{}{},
+	}
+	if cfg.BreachedPasswordsFile != "" {
+		if err := v.loadBreached(cfg.BreachedPasswordsFile); err != nil {
+			return nil, err
+		}
+	}
+	return v, nil
+}
+
+// loadBreached читает словарь утёкших паролей, сравниваем без учёта регистра
+func (v *Validator) loadBreached(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return errlib.WrapErr(err, "breached passwords file")
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if word := strings.TrimSpace(scanner.Text()); word != "" {
+			v.breached[strings.ToLower(word)] = struct{}{}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return errlib.WrapErr(err, "breached passwords file")
+	}
+	return nil
+}
+
+// User checks username and password of the new user
+func (v *Validator) User(user entities.User) error {
+	var errs Errors
+	v.checkUsername(&errs, user.Username)
+	v.checkPassword(&errs, user.Password, user.Username)
+	return errs.Err()
+}
+func (v *Validator) checkUsername(errs *Errors, username string) {
+	if username == "" {
+		errs.add("username", RuleRequired, "is required")
+		return
+	}
+	checkLength(errs, "username", username, v.cfg.UsernameMinLength, v.cfg.UsernameMaxLength)
+	if !v.username.MatchString(username) {
+		errs.add("username", RuleCharset, "may contain only "+v.cfg.UsernameCharset)
+	}
+}
+func (v *Validator) checkPassword(errs *Errors, password string, username string) {
+	if password == "" {
+		errs.add("password", RuleRequired, "is required")
+		return
+	}
+	checkLength(errs, "password", password, v.cfg.PasswordMinLength, v.cfg.PasswordMaxLength)
+	for _, class := range v.cfg.PasswordRequire {
+		if !strings.ContainsFunc(password, classes[class]) {
+			errs.add("password", RuleRequirePrefix+class, "must contain a "+class+" character")
+		}
+	}
+	if username != "" && strings.Contains(strings.ToLower(password), strings.ToLower(username)) {
+		errs.add("password", RuleNotUsername, "must not contain username")
+	}
+	if _, ok := v.breached[strings.ToLower(password)]; ok {
+		errs.add("password", RuleBreached, "is found in leaked passwords, choose another one")
+	}
+}
+
+// classes are checks of character classes from config.Validation.PasswordRequire
+var classes = map[string]func(rune) bool{
+	"lower":  unicode.IsLower,
+	"upper":  unicode.IsUpper,
+	"letter": unicode.IsLetter,
+	"digit":  unicode.IsDigit,
+	"symbol": func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) && !unicode.IsSpace(r) },
+}
+
+// Drink checks the drink before it is created or replaced
+func (v *Validator) Drink(drink drEnt.Drink) error {
+	var errs Errors
+	if strings.TrimSpace(drink.Name) == "" {
+		errs.add("name", RuleRequired, "is required")
+	} else {
+		checkLength(&errs, "name", drink.Name, 1, drinkTextMaxLength)
+	}
+	if drink.Price < 0 {
+		errs.add("price", RuleMin, "must not be negative")
+	}
+	if !drink.Preparation.Valid() {
+		errs.add("preparation", RuleOneOf, "must be one of mixed, aged, iced, blended")
+	}
+	seen := map[string]bool{}
+	for i, tag := range drink.Tags {
+		field := "tags[" + strconv.Itoa(i) + "]"
+		if strings.TrimSpace(tag) == "" {
+			errs.add(field, RuleRequired, "must not be empty")
+			continue
+		}
+		checkLength(&errs, field, tag, 1, drinkTextMaxLength)
+		// теги сравниваются без учёта регистра, см. entities.TagQuery
+		if seen[strings.ToLower(tag)] {
+			errs.add(field, RuleUnique, "is repeated")
+		}
+		seen[strings.ToLower(tag)] = true
+	}
+	seenIngredients := map[string]bool{}
+	for i, ingredient := range drink.Ingredients {
+		field := "ingredients[" + strconv.Itoa(i) + "]"
+		name := strings.ToLower(strings.TrimSpace(ingredient.Name))
+		if name == "" {
+			errs.add(field+".name", RuleRequired, "is required")
+		} else if seenIngredients[name] {
+			// в рецепте ингредиент встречается один раз, имена в каталоге без учёта регистра
+			errs.add(field+".name", RuleUnique, "is repeated, put the whole amount in one line")
+		}
+		seenIngredients[name] = true
+		if ingredient.Amount <= 0 {
+			errs.add(field+".amount", RulePositive, "must be positive")
+		}
+	}
+	return errs.Err()
+}
+
+// Rating checks the score and the review of the drink rating
+func (v *Validator) Rating(rating ratingEnt.NewRating) error {
+	var errs Errors
+	if rating.Score < ratingEnt.MinScore {
+		errs.add("score", RuleMin, fmt.Sprintf("must be at least %d", ratingEnt.MinScore))
+	}
+	if rating.Score > ratingEnt.MaxScore {
+		errs.add("score", RuleMax, fmt.Sprintf("must be at most %d", ratingEnt.MaxScore))
+	}
+	checkLength(&errs, "review", rating.Review, 0, ratingEnt.ReviewMaxLength)
+	return errs.Err()
+}
+
+// checkLength считает длину в символах, а не в байтах
+func checkLength(errs *Errors, field, value string, min, max int) {
+	n := utf8.RuneCountInString(value)
+	if n < min {
+		errs.add(field, RuleMinLength, fmt.Sprintf("must be at least %d characters", min))
+	}
+	if n > max {
+		errs.add(field, RuleMaxLength, fmt.Sprintf("must be at most %d characters", max))
+	}
+}
@@ -0,0 +1,150 @@
+package validate
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/SapolovichSV/backprogeng/internal/config"
+	drEnt "github.com/SapolovichSV/backprogeng/internal/drink/entities"
+	"github.com/SapolovichSV/backprogeng/internal/errlib"
+	ratingEnt "github.com/SapolovichSV/backprogeng/internal/rating/entities"
+	"github.com/SapolovichSV/backprogeng/internal/user/entities"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testRules(t *testing.T) config.Validation {
+	breached := filepath.Join(t.TempDir(), "breached.txt")
+	require.NoError(t, os.WriteFile(breached, []byte("password1\n\nQwerty123\n"), 0o600))
+	return config.Validation{
+		UsernameMinLength:     4,
+		UsernameMaxLength:     16,
+		UsernameCharset:       "A-Za-z0-9_",
+		PasswordMinLength:     8,
+		PasswordMaxLength:     72,
+		PasswordRequire:       []string{"letter", "digit"},
+		BreachedPasswordsFile: breached,
+	}
+}
+
+// rules возвращает пары поле/правило, сообщения проверяются отдельно
+func rules(err error) []string {
+	var res []string
+	for _, fe := range err.(Errors) {
+		res = append(res, fe.Field+":"+fe.Rule)
+	}
+	return res
+}
+
+func TestValidator_User(t *testing.T) {
+	v, err := New(testRules(t))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name string
+		user entities.User
+		want []string
+	}{
+		{
+			name: "valid",
+			user: entities.User{Username: "Stas_001", Password: "amahasla1"},
+		},
+		{
+			name: "empty",
+			user: entities.User{},
+			want: []string{"username:required", "password:required"},
+		},
+		{
+			name: "every_rule",
+			user: entities.User{Username: "Стас", Password: "стас"},
+			want: []string{"username:charset", "password:min_length", "password:require_digit", "password:not_username"},
+		},
+		{
+			name: "too_long",
+			user: entities.User{Username: strings.Repeat("s", 17), Password: strings.Repeat("a1", 37)},
+			want: []string{"username:max_length", "password:max_length"},
+		},
+		{
+			name: "breached_case_insensitive",
+			user: entities.User{Username: "Stas001", Password: "qwerty123"},
+			want: []string{"password:breached"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := v.User(tt.user)
+			if tt.want == nil {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Equal(t, tt.want, rules(err))
+		})
+	}
+}
+
+func TestValidator_Drink(t *testing.T) {
+	v, err := New(testRules(t))
+	require.NoError(t, err)
+
+	assert.NoError(t, v.Drink(drEnt.Drink{
+		Name:        "Bad Touch",
+		Tags:        []string{"Sour", "Classy"},
+		Price:       250,
+		Preparation: drEnt.PreparationIced,
+		Ingredients: []drEnt.Ingredient{{Name: "Karmotrine", Amount: 2}},
+	}))
+
+	err = v.Drink(drEnt.Drink{
+		Name:        strings.Repeat("a", 256),
+		Tags:        []string{"Sour", "", "sour"},
+		Price:       -1,
+		Preparation: "shaken",
+		Ingredients: []drEnt.Ingredient{{Amount: -1}, {Name: "Karmotrine", Amount: 1}, {Name: " karmotrine", Amount: 2}},
+	})
+	require.Error(t, err)
+	assert.Equal(t, []string{
+		"name:max_length",
+		"price:min",
+		"preparation:one_of",
+		"tags[1]:required",
+		"tags[2]:unique",
+		"ingredients[0].name:required",
+		"ingredients[0].amount:positive",
+		"ingredients[2].name:unique",
+	}, rules(err))
+	assert.True(t, strings.HasPrefix(err.Error(), "validation failed: name must be at most 255 characters; price"))
+}
+
+func TestValidator_Rating(t *testing.T) {
+	v, err := New(testRules(t))
+	require.NoError(t, err)
+
+	assert.NoError(t, v.Rating(ratingEnt.NewRating{Score: 1}))
+	assert.NoError(t, v.Rating(ratingEnt.NewRating{Score: 5, Review: strings.Repeat("ы", ratingEnt.ReviewMaxLength)}))
+
+	err = v.Rating(ratingEnt.NewRating{Score: 0, Review: strings.Repeat("ы", ratingEnt.ReviewMaxLength+1)})
+	assert.Equal(t, []string{"score:min", "review:max_length"}, rules(err))
+	assert.Equal(t, []string{"score:max"}, rules(v.Rating(ratingEnt.NewRating{Score: 6})))
+}
+
+func TestNew(t *testing.T) {
+	rules := testRules(t)
+	rules.BreachedPasswordsFile = filepath.Join(t.TempDir(), "missing.txt")
+	_, err := New(rules)
+	assert.Error(t, err)
+
+	rules = testRules(t)
+	rules.UsernameCharset = "z-a"
+	_, err = New(rules)
+	assert.Error(t, err)
+}
+
+func TestErrors_Err(t *testing.T) {
+	var errs Errors
+	assert.NoError(t, errs.Err())
+	errs.add("name", RuleRequired, "is required")
+	assert.Equal(t, Errors{errlib.FieldError{Field: "name", Rule: RuleRequired, Message: "is required"}}, errs.Err())
+}
@@ -13,10 +13,17 @@ import (
 	"github.com/SapolovichSV/backprogeng/internal/config"
 	drinkController "github.com/SapolovichSV/backprogeng/internal/drink/controller"
 	drinkModel "github.com/SapolovichSV/backprogeng/internal/drink/model"
+	"github.com/SapolovichSV/backprogeng/internal/hasher"
 	httpinfra "github.com/SapolovichSV/backprogeng/internal/http_infra"
+	ingredientController "github.com/SapolovichSV/backprogeng/internal/ingredient/controller"
+	ingredientModel "github.com/SapolovichSV/backprogeng/internal/ingredient/model"
 	"github.com/SapolovichSV/backprogeng/internal/logger"
+	"github.com/SapolovichSV/backprogeng/internal/ratelimit"
+	ratingController "github.com/SapolovichSV/backprogeng/internal/rating/controller"
+	ratingModel "github.com/SapolovichSV/backprogeng/internal/rating/model"
 	userController "github.com/SapolovichSV/backprogeng/internal/user/controller"
 	userModel "github.com/SapolovichSV/backprogeng/internal/user/model"
+	"github.com/SapolovichSV/backprogeng/internal/validate"
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/golang-migrate/migrate/v4/database/postgres"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
@@ -53,19 +60,40 @@ func Run() {
 	}
 	//Создаём модель дринков
 	modelDrink := drinkModel.New(conn)
-	modelUser := userModel.New(conn)
+	passwordHasher, err := hasher.New(config.PasswordHash)
+	if err != nil {
+		panic(err)
+	}
+	modelUser := userModel.New(conn, passwordHasher)
+	modelIngredient := ingredientModel.New(conn)
+	modelRating := ratingModel.New(conn)
 
+	validator, err := validate.New(config.Validation)
+	if err != nil {
+		panic(err)
+	}
 	//Создаём контроллер дринков
-	drinkHandler := drinkController.New(modelDrink, ctx)
+	drinkHandler := drinkController.New(modelDrink, validator, ctx)
+	ingredientHandler := ingredientController.New(modelIngredient, ctx)
+	ratingHandler := ratingController.New(modelRating, validator, ctx)
 
-	authmiddle := authmiddleware.New()
-	userHandler := userController.New(modelUser, authmiddle, ctx)
+	refreshStore := authmiddleware.NewSQLRefreshStore(conn)
+	signingKeys, err := authmiddleware.LoadKeys(config.Auth, config.Env == "production")
+	if err != nil {
+		panic(err)
+	}
+	authmiddle := authmiddleware.New(config.Auth, signingKeys, refreshStore, newRevocationStore(config.Auth, conn), modelUser, authmiddleware.NewSQLAPIKeyStore(conn))
+	loginLimiter := ratelimit.NewFailureLimiter(config.LoginMaxFailures, config.LoginLockout)
+	userHandler := userController.New(modelUser, authmiddle, loginLimiter, validator, ctx)
 	//Создаём сервер и в его роутер записываем роуты дринктов и еще юзеров(ещё их не наиписал)
 	server := httpinfra.NewServer(config.Port)
-	router := server.GetRouter()
+	api := server.Group("/api")
 
-	drinkHandler.AddRoutes("api", router)
-	userHandler.AddRoutes("api", router)
+	drinkHandler.AddRoutes(api, authmiddle.Middleware)
+	ingredientHandler.AddRoutes(api, authmiddle.Middleware)
+	userHandler.AddRoutes(api, authmiddle.Middleware)
+	ratingHandler.AddRoutes(api, authmiddle.Middleware)
+	server.Group("/.well-known").GET("/jwks.json", authmiddle.JWKS)
 	//Запускаем сервер
 	err = server.Start()
 	if err != nil {
@@ -82,6 +110,12 @@ func Run() {
 		}
 	}()
 }
+func newRevocationStore(cfg config.Auth, conn *pgxpool.Pool) authmiddleware.RevocationStore {
+	if cfg.RevocationStore == "memory" {
+		return authmiddleware.NewMemoryRevocationStore()
+	}
+	return authmiddleware.NewSQLRevocationStore(conn)
+}
 func migrateAndUp(config *config.Config, logger *slog.Logger) {
 	db, err := sql.Open("pgx", config.DbAddr)
 	if err != nil {
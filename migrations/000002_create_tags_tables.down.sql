@@ -0,0 +1,10 @@
+ALTER TABLE drinks ADD COLUMN tags TEXT;
+UPDATE drinks
+SET tags = (
+    SELECT string_agg(tags.name, ',' ORDER BY tags.name)
+    FROM drink_tags
+    JOIN tags ON tags.id = drink_tags.tag_id
+    WHERE drink_tags.drink_id = drinks.id
+);
+DROP TABLE IF EXISTS drink_tags;
+DROP TABLE IF EXISTS tags;
@@ -0,0 +1,30 @@
+CREATE TABLE tags (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL
+);
+CREATE UNIQUE INDEX tags_name_lower_idx ON tags (lower(name));
+CREATE TABLE drink_tags (
+    drink_id INT NOT NULL,
+    FOREIGN KEY (drink_id) REFERENCES drinks(id) ON DELETE CASCADE,
+    tag_id INT NOT NULL,
+    FOREIGN KEY (tag_id) REFERENCES tags(id) ON DELETE CASCADE,
+    PRIMARY KEY (drink_id, tag_id)
+);
+CREATE INDEX drink_tags_tag_id_idx ON drink_tags (tag_id);
+
+-- переносим теги из старой колонки drinks.tags
+INSERT INTO tags (name)
+SELECT btrim(old.tag)
+FROM drinks
+CROSS JOIN LATERAL unnest(string_to_array(drinks.tags, ',')) AS old(tag)
+WHERE btrim(old.tag) <> ''
+ON CONFLICT ((lower(name))) DO NOTHING;
+
+INSERT INTO drink_tags (drink_id, tag_id)
+SELECT drinks.id, tags.id
+FROM drinks
+CROSS JOIN LATERAL unnest(string_to_array(drinks.tags, ',')) AS old(tag)
+JOIN tags ON lower(tags.name) = lower(btrim(old.tag))
+ON CONFLICT DO NOTHING;
+
+ALTER TABLE drinks DROP COLUMN tags;
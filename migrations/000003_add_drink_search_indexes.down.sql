@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS tags_name_trgm_idx;
+DROP INDEX IF EXISTS drinks_name_fts_idx;
+DROP INDEX IF EXISTS drinks_name_trgm_idx;
+DROP EXTENSION IF EXISTS pg_trgm;
@@ -0,0 +1,4 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+CREATE INDEX drinks_name_trgm_idx ON drinks USING GIN (lower(name) gin_trgm_ops);
+CREATE INDEX drinks_name_fts_idx ON drinks USING GIN (to_tsvector('simple', name));
+CREATE INDEX tags_name_trgm_idx ON tags USING GIN (lower(name) gin_trgm_ops);
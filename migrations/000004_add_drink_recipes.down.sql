@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS drink_ingredients;
+DROP TABLE IF EXISTS ingredients;
+ALTER TABLE drinks
+    DROP COLUMN IF EXISTS preparation,
+    DROP COLUMN IF EXISTS alcoholic,
+    DROP COLUMN IF EXISTS price,
+    DROP COLUMN IF EXISTS description;
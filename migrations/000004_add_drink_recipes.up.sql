@@ -0,0 +1,22 @@
+ALTER TABLE drinks
+    ADD COLUMN description TEXT NOT NULL DEFAULT '',
+    ADD COLUMN price INT NOT NULL DEFAULT 0 CHECK (price >= 0),
+    ADD COLUMN alcoholic BOOLEAN NOT NULL DEFAULT true,
+    ADD COLUMN preparation VARCHAR(16) NOT NULL DEFAULT 'mixed'
+        CHECK (preparation IN ('mixed', 'aged', 'iced', 'blended'));
+CREATE TABLE ingredients (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL
+);
+CREATE UNIQUE INDEX ingredients_name_lower_idx ON ingredients (lower(name));
+CREATE TABLE drink_ingredients (
+    drink_id INT NOT NULL,
+    FOREIGN KEY (drink_id) REFERENCES drinks(id) ON DELETE CASCADE,
+    ingredient_id INT NOT NULL,
+    FOREIGN KEY (ingredient_id) REFERENCES ingredients(id) ON DELETE RESTRICT,
+    position INT NOT NULL,
+    amount NUMERIC(10, 2) NOT NULL CHECK (amount > 0),
+    unit VARCHAR(32) NOT NULL DEFAULT '',
+    PRIMARY KEY (drink_id, ingredient_id)
+);
+CREATE INDEX drink_ingredients_ingredient_id_idx ON drink_ingredients (ingredient_id);
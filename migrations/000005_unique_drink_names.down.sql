@@ -0,0 +1 @@
+DROP INDEX IF EXISTS drinks_name_lower_key;
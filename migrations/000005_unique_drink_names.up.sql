@@ -0,0 +1,24 @@
+-- дубли по имени без учёта регистра сливаются в напиток с наименьшим id:
+-- теги и избранное переносятся, рецепт остаётся у оставшегося напитка
+CREATE TEMP TABLE drink_duplicates AS
+SELECT id, min(id) OVER (PARTITION BY lower(name)) AS keep_id
+FROM drinks;
+DELETE FROM drink_duplicates
+WHERE id = keep_id;
+
+INSERT INTO drink_tags (drink_id, tag_id)
+SELECT drink_duplicates.keep_id, drink_tags.tag_id
+FROM drink_tags
+JOIN drink_duplicates ON drink_duplicates.id = drink_tags.drink_id
+ON CONFLICT DO NOTHING;
+
+UPDATE favs
+SET drink_id = drink_duplicates.keep_id
+FROM drink_duplicates
+WHERE favs.drink_id = drink_duplicates.id;
+
+DELETE FROM drinks
+WHERE id IN (SELECT id FROM drink_duplicates);
+DROP TABLE drink_duplicates;
+
+CREATE UNIQUE INDEX drinks_name_lower_key ON drinks (lower(name));
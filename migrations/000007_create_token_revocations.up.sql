@@ -0,0 +1,12 @@
+-- отозванные до истечения срока access токены, строки с истёкшим expires_at можно удалять
+CREATE TABLE revoked_tokens (
+    jti VARCHAR(64) PRIMARY KEY,
+    expires_at TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX revoked_tokens_expires_at_idx ON revoked_tokens (expires_at);
+-- все токены пользователя, выпущенные не позже revoked_before, отозваны
+CREATE TABLE user_revocations (
+    user_id INT PRIMARY KEY,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    revoked_before TIMESTAMPTZ NOT NULL
+);
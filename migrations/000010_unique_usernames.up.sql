@@ -0,0 +1,10 @@
+-- дубли имён без учёта регистра переименовываются: кроме пользователя с наименьшим id
+-- все получают суффикс _<id>, пользователей и их избранное не удаляем
+UPDATE users
+SET username = users.username || '_' || users.id
+WHERE users.id NOT IN (
+    SELECT min(id)
+    FROM users
+    GROUP BY lower(username)
+);
+CREATE UNIQUE INDEX users_username_lower_key ON users (lower(username));
@@ -0,0 +1 @@
+ALTER TABLE favs DROP CONSTRAINT IF EXISTS favs_pkey;
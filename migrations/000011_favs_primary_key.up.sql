@@ -0,0 +1,8 @@
+-- повторные строки избранного остались от старого AddFav, оставляем по одной
+DELETE FROM favs
+WHERE ctid NOT IN (
+    SELECT min(ctid)
+    FROM favs
+    GROUP BY user_id, drink_id
+);
+ALTER TABLE favs ADD PRIMARY KEY (user_id, drink_id);
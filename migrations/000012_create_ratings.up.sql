@@ -0,0 +1,14 @@
+-- одна оценка на пару пользователь-напиток, повторная оценка заменяет прежнюю
+CREATE TABLE ratings (
+    user_id INT NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    drink_id INT NOT NULL,
+    FOREIGN KEY (drink_id) REFERENCES drinks(id) ON DELETE CASCADE,
+    score SMALLINT NOT NULL CHECK (score BETWEEN 1 AND 5),
+    review VARCHAR(1000) NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (user_id, drink_id)
+);
+-- отзывы о напитке и средняя оценка считаются по drink_id
+CREATE INDEX ratings_drink_id_idx ON ratings (drink_id, user_id);
@@ -10,9 +10,12 @@
 package mock
 
 import (
+	context "context"
 	reflect "reflect"
+	time "time"
 
-	entities "github.com/SapolovichSV/backprogeng/internal/user/entities"
+	entities "github.com/SapolovichSV/backprogeng/internal/authmiddleware/entities"
+	entities0 "github.com/SapolovichSV/backprogeng/internal/user/entities"
 	echo "github.com/labstack/echo/v4"
 	gomock "go.uber.org/mock/gomock"
 )
@@ -41,11 +44,26 @@ func (m *MockauthService) EXPECT() *MockauthServiceMockRecorder {
 	return m.recorder
 }
 
+// APIKeys mocks base method.
+func (m *MockauthService) APIKeys(c echo.Context, userID int) ([]entities0.APIKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "APIKeys", c, userID)
+	ret0, _ := ret[0].([]entities0.APIKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// APIKeys indicates an expected call of APIKeys.
+func (mr *MockauthServiceMockRecorder) APIKeys(c, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "APIKeys", reflect.TypeOf((*MockauthService)(nil).APIKeys), c, userID)
+}
+
 // Auth mocks base method.
-func (m *MockauthService) Auth(c echo.Context) (entities.User, error) {
+func (m *MockauthService) Auth(c echo.Context) (entities0.User, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "Auth", c)
-	ret0, _ := ret[0].(entities.User)
+	ret0, _ := ret[0].(entities0.User)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
@@ -56,31 +74,223 @@ func (mr *MockauthServiceMockRecorder) Auth(c any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Auth", reflect.TypeOf((*MockauthService)(nil).Auth), c)
 }
 
-// Login mocks base method.
-func (m *MockauthService) Login(c echo.Context) (entities.User, error) {
+// CreateAPIKey mocks base method.
+func (m *MockauthService) CreateAPIKey(c echo.Context, userID int, req entities0.NewAPIKey) (entities0.CreatedAPIKey, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Login", c)
-	ret0, _ := ret[0].(entities.User)
+	ret := m.ctrl.Call(m, "CreateAPIKey", c, userID, req)
+	ret0, _ := ret[0].(entities0.CreatedAPIKey)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// Login indicates an expected call of Login.
-func (mr *MockauthServiceMockRecorder) Login(c any) *gomock.Call {
+// CreateAPIKey indicates an expected call of CreateAPIKey.
+func (mr *MockauthServiceMockRecorder) CreateAPIKey(c, userID, req any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Login", reflect.TypeOf((*MockauthService)(nil).Login), c)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAPIKey", reflect.TypeOf((*MockauthService)(nil).CreateAPIKey), c, userID, req)
 }
 
-// Register mocks base method.
-func (m *MockauthService) Register(c echo.Context, user entities.User) error {
+// Logout mocks base method.
+func (m *MockauthService) Logout(c echo.Context) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Register", c, user)
+	ret := m.ctrl.Call(m, "Logout", c)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Logout indicates an expected call of Logout.
+func (mr *MockauthServiceMockRecorder) Logout(c any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Logout", reflect.TypeOf((*MockauthService)(nil).Logout), c)
+}
+
+// LogoutAll mocks base method.
+func (m *MockauthService) LogoutAll(c echo.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LogoutAll", c)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
+// LogoutAll indicates an expected call of LogoutAll.
+func (mr *MockauthServiceMockRecorder) LogoutAll(c any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LogoutAll", reflect.TypeOf((*MockauthService)(nil).LogoutAll), c)
+}
+
+// Refresh mocks base method.
+func (m *MockauthService) Refresh(c echo.Context) (entities0.Session, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Refresh", c)
+	ret0, _ := ret[0].(entities0.Session)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Refresh indicates an expected call of Refresh.
+func (mr *MockauthServiceMockRecorder) Refresh(c any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Refresh", reflect.TypeOf((*MockauthService)(nil).Refresh), c)
+}
+
+// Register mocks base method.
+func (m *MockauthService) Register(c echo.Context, user entities0.User) (entities0.Tokens, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Register", c, user)
+	ret0, _ := ret[0].(entities0.Tokens)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
 // Register indicates an expected call of Register.
 func (mr *MockauthServiceMockRecorder) Register(c, user any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Register", reflect.TypeOf((*MockauthService)(nil).Register), c, user)
 }
+
+// RevokeAPIKey mocks base method.
+func (m *MockauthService) RevokeAPIKey(c echo.Context, userID, keyID int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeAPIKey", c, userID, keyID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeAPIKey indicates an expected call of RevokeAPIKey.
+func (mr *MockauthServiceMockRecorder) RevokeAPIKey(c, userID, keyID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeAPIKey", reflect.TypeOf((*MockauthService)(nil).RevokeAPIKey), c, userID, keyID)
+}
+
+// RevokeAccess mocks base method.
+func (m *MockauthService) RevokeAccess(c echo.Context, userID int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeAccess", c, userID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeAccess indicates an expected call of RevokeAccess.
+func (mr *MockauthServiceMockRecorder) RevokeAccess(c, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeAccess", reflect.TypeOf((*MockauthService)(nil).RevokeAccess), c, userID)
+}
+
+// MockrefreshStore is a mock of refreshStore interface.
+type MockrefreshStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockrefreshStoreMockRecorder
+	isgomock struct{}
+}
+
+// MockrefreshStoreMockRecorder is the mock recorder for MockrefreshStore.
+type MockrefreshStoreMockRecorder struct {
+	mock *MockrefreshStore
+}
+
+// NewMockrefreshStore creates a new mock instance.
+func NewMockrefreshStore(ctrl *gomock.Controller) *MockrefreshStore {
+	mock := &MockrefreshStore{ctrl: ctrl}
+	mock.recorder = &MockrefreshStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockrefreshStore) EXPECT() *MockrefreshStoreMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method.
+func (m *MockrefreshStore) Create(ctx context.Context, t entities.RefreshToken) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", ctx, t)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockrefreshStoreMockRecorder) Create(ctx, t any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockrefreshStore)(nil).Create), ctx, t)
+}
+
+// RevokeFamily mocks base method.
+func (m *MockrefreshStore) RevokeFamily(ctx context.Context, hash string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeFamily", ctx, hash)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeFamily indicates an expected call of RevokeFamily.
+func (mr *MockrefreshStoreMockRecorder) RevokeFamily(ctx, hash any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeFamily", reflect.TypeOf((*MockrefreshStore)(nil).RevokeFamily), ctx, hash)
+}
+
+// RevokeUser mocks base method.
+func (m *MockrefreshStore) RevokeUser(ctx context.Context, userID int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeUser", ctx, userID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeUser indicates an expected call of RevokeUser.
+func (mr *MockrefreshStoreMockRecorder) RevokeUser(ctx, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeUser", reflect.TypeOf((*MockrefreshStore)(nil).RevokeUser), ctx, userID)
+}
+
+// Rotate mocks base method.
+func (m *MockrefreshStore) Rotate(ctx context.Context, hash string, next entities.RefreshToken, now time.Time) (entities.RefreshToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Rotate", ctx, hash, next, now)
+	ret0, _ := ret[0].(entities.RefreshToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Rotate indicates an expected call of Rotate.
+func (mr *MockrefreshStoreMockRecorder) Rotate(ctx, hash, next, now any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Rotate", reflect.TypeOf((*MockrefreshStore)(nil).Rotate), ctx, hash, next, now)
+}
+
+// MockroleSource is a mock of roleSource interface.
+type MockroleSource struct {
+	ctrl     *gomock.Controller
+	recorder *MockroleSourceMockRecorder
+	isgomock struct{}
+}
+
+// MockroleSourceMockRecorder is the mock recorder for MockroleSource.
+type MockroleSourceMockRecorder struct {
+	mock *MockroleSource
+}
+
+// NewMockroleSource creates a new mock instance.
+func NewMockroleSource(ctrl *gomock.Controller) *MockroleSource {
+	mock := &MockroleSource{ctrl: ctrl}
+	mock.recorder = &MockroleSourceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockroleSource) EXPECT() *MockroleSourceMockRecorder {
+	return m.recorder
+}
+
+// RoleByUserID mocks base method.
+func (m *MockroleSource) RoleByUserID(ctx context.Context, userID int) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RoleByUserID", ctx, userID)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RoleByUserID indicates an expected call of RoleByUserID.
+func (mr *MockroleSourceMockRecorder) RoleByUserID(ctx, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RoleByUserID", reflect.TypeOf((*MockroleSource)(nil).RoleByUserID), ctx, userID)
+}
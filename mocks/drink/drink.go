@@ -14,6 +14,7 @@ import (
 	reflect "reflect"
 
 	entities "github.com/SapolovichSV/backprogeng/internal/drink/entities"
+	pagination "github.com/SapolovichSV/backprogeng/internal/pagination"
 	gomock "go.uber.org/mock/gomock"
 )
 
@@ -42,18 +43,18 @@ func (m *MockDrinkModel) EXPECT() *MockDrinkModelMockRecorder {
 }
 
 // AllDrinks mocks base method.
-func (m *MockDrinkModel) AllDrinks(ctx context.Context, id int) ([]entities.Drink, error) {
+func (m *MockDrinkModel) AllDrinks(ctx context.Context, page pagination.Request) (pagination.Page[entities.Drink], error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "AllDrinks", ctx, id)
-	ret0, _ := ret[0].([]entities.Drink)
+	ret := m.ctrl.Call(m, "AllDrinks", ctx, page)
+	ret0, _ := ret[0].(pagination.Page[entities.Drink])
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // AllDrinks indicates an expected call of AllDrinks.
-func (mr *MockDrinkModelMockRecorder) AllDrinks(ctx, id any) *gomock.Call {
+func (mr *MockDrinkModelMockRecorder) AllDrinks(ctx, page any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AllDrinks", reflect.TypeOf((*MockDrinkModel)(nil).AllDrinks), ctx, id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AllDrinks", reflect.TypeOf((*MockDrinkModel)(nil).AllDrinks), ctx, page)
 }
 
 // CreateDrink mocks base method.
@@ -85,6 +86,21 @@ func (mr *MockDrinkModelMockRecorder) DeleteDrink(ctx, name any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteDrink", reflect.TypeOf((*MockDrinkModel)(nil).DeleteDrink), ctx, name)
 }
 
+// DrinkByID mocks base method.
+func (m *MockDrinkModel) DrinkByID(ctx context.Context, id int) (entities.Drink, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DrinkByID", ctx, id)
+	ret0, _ := ret[0].(entities.Drink)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DrinkByID indicates an expected call of DrinkByID.
+func (mr *MockDrinkModelMockRecorder) DrinkByID(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DrinkByID", reflect.TypeOf((*MockDrinkModel)(nil).DrinkByID), ctx, id)
+}
+
 // DrinkByName mocks base method.
 func (m *MockDrinkModel) DrinkByName(ctx context.Context, name string) (entities.Drink, error) {
 	m.ctrl.T.Helper()
@@ -100,19 +116,64 @@ func (mr *MockDrinkModelMockRecorder) DrinkByName(ctx, name any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DrinkByName", reflect.TypeOf((*MockDrinkModel)(nil).DrinkByName), ctx, name)
 }
 
+// DrinksByTagQuery mocks base method.
+func (m *MockDrinkModel) DrinksByTagQuery(ctx context.Context, tq entities.TagQuery, page pagination.Request) (pagination.Page[entities.Drink], error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DrinksByTagQuery", ctx, tq, page)
+	ret0, _ := ret[0].(pagination.Page[entities.Drink])
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DrinksByTagQuery indicates an expected call of DrinksByTagQuery.
+func (mr *MockDrinkModelMockRecorder) DrinksByTagQuery(ctx, tq, page any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DrinksByTagQuery", reflect.TypeOf((*MockDrinkModel)(nil).DrinksByTagQuery), ctx, tq, page)
+}
+
 // DrinksByTags mocks base method.
-func (m *MockDrinkModel) DrinksByTags(ctx context.Context, tagsCont []string) ([]entities.Drink, error) {
+func (m *MockDrinkModel) DrinksByTags(ctx context.Context, tagsCont []string, page pagination.Request) (pagination.Page[entities.Drink], error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DrinksByTags", ctx, tagsCont)
-	ret0, _ := ret[0].([]entities.Drink)
+	ret := m.ctrl.Call(m, "DrinksByTags", ctx, tagsCont, page)
+	ret0, _ := ret[0].(pagination.Page[entities.Drink])
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // DrinksByTags indicates an expected call of DrinksByTags.
-func (mr *MockDrinkModelMockRecorder) DrinksByTags(ctx, tagsCont any) *gomock.Call {
+func (mr *MockDrinkModelMockRecorder) DrinksByTags(ctx, tagsCont, page any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DrinksByTags", reflect.TypeOf((*MockDrinkModel)(nil).DrinksByTags), ctx, tagsCont, page)
+}
+
+// ReplaceDrink mocks base method.
+func (m *MockDrinkModel) ReplaceDrink(ctx context.Context, id int, dCont entities.Drink) (entities.Drink, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReplaceDrink", ctx, id, dCont)
+	ret0, _ := ret[0].(entities.Drink)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReplaceDrink indicates an expected call of ReplaceDrink.
+func (mr *MockDrinkModelMockRecorder) ReplaceDrink(ctx, id, dCont any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReplaceDrink", reflect.TypeOf((*MockDrinkModel)(nil).ReplaceDrink), ctx, id, dCont)
+}
+
+// SearchDrinks mocks base method.
+func (m *MockDrinkModel) SearchDrinks(ctx context.Context, text string, limit int) ([]entities.Drink, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SearchDrinks", ctx, text, limit)
+	ret0, _ := ret[0].([]entities.Drink)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SearchDrinks indicates an expected call of SearchDrinks.
+func (mr *MockDrinkModelMockRecorder) SearchDrinks(ctx, text, limit any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DrinksByTags", reflect.TypeOf((*MockDrinkModel)(nil).DrinksByTags), ctx, tagsCont)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SearchDrinks", reflect.TypeOf((*MockDrinkModel)(nil).SearchDrinks), ctx, text, limit)
 }
 
 // UpdateDrink mocks base method.
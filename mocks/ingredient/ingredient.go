@@ -0,0 +1,132 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/ingredient/model/ingredient.go
+//
+// Generated by this command:
+//
+//	mockgen -source=internal/ingredient/model/ingredient.go -destination=mocks/ingredient/ingredient.go -package=mock
+//
+
+// Package mock is a generated GoMock package.
+package mock
+
+import (
+	context "context"
+	reflect "reflect"
+
+	entities "github.com/SapolovichSV/backprogeng/internal/ingredient/entities"
+	pagination "github.com/SapolovichSV/backprogeng/internal/pagination"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockIngredientModel is a mock of IngredientModel interface.
+type MockIngredientModel struct {
+	ctrl     *gomock.Controller
+	recorder *MockIngredientModelMockRecorder
+	isgomock struct{}
+}
+
+// MockIngredientModelMockRecorder is the mock recorder for MockIngredientModel.
+type MockIngredientModelMockRecorder struct {
+	mock *MockIngredientModel
+}
+
+// NewMockIngredientModel creates a new mock instance.
+func NewMockIngredientModel(ctrl *gomock.Controller) *MockIngredientModel {
+	mock := &MockIngredientModel{ctrl: ctrl}
+	mock.recorder = &MockIngredientModelMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockIngredientModel) EXPECT() *MockIngredientModelMockRecorder {
+	return m.recorder
+}
+
+// AllIngredients mocks base method.
+func (m *MockIngredientModel) AllIngredients(ctx context.Context, page pagination.Request) (pagination.Page[entities.Ingredient], error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AllIngredients", ctx, page)
+	ret0, _ := ret[0].(pagination.Page[entities.Ingredient])
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AllIngredients indicates an expected call of AllIngredients.
+func (mr *MockIngredientModelMockRecorder) AllIngredients(ctx, page any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AllIngredients", reflect.TypeOf((*MockIngredientModel)(nil).AllIngredients), ctx, page)
+}
+
+// CreateIngredient mocks base method.
+func (m *MockIngredientModel) CreateIngredient(ctx context.Context, ingredient entities.Ingredient) (entities.Ingredient, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateIngredient", ctx, ingredient)
+	ret0, _ := ret[0].(entities.Ingredient)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateIngredient indicates an expected call of CreateIngredient.
+func (mr *MockIngredientModelMockRecorder) CreateIngredient(ctx, ingredient any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateIngredient", reflect.TypeOf((*MockIngredientModel)(nil).CreateIngredient), ctx, ingredient)
+}
+
+// DeleteIngredient mocks base method.
+func (m *MockIngredientModel) DeleteIngredient(ctx context.Context, id int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteIngredient", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteIngredient indicates an expected call of DeleteIngredient.
+func (mr *MockIngredientModelMockRecorder) DeleteIngredient(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteIngredient", reflect.TypeOf((*MockIngredientModel)(nil).DeleteIngredient), ctx, id)
+}
+
+// IngredientByID mocks base method.
+func (m *MockIngredientModel) IngredientByID(ctx context.Context, id int) (entities.Ingredient, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IngredientByID", ctx, id)
+	ret0, _ := ret[0].(entities.Ingredient)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IngredientByID indicates an expected call of IngredientByID.
+func (mr *MockIngredientModelMockRecorder) IngredientByID(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IngredientByID", reflect.TypeOf((*MockIngredientModel)(nil).IngredientByID), ctx, id)
+}
+
+// Mix mocks base method.
+func (m *MockIngredientModel) Mix(ctx context.Context, inventory entities.Inventory) (entities.MixResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Mix", ctx, inventory)
+	ret0, _ := ret[0].(entities.MixResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Mix indicates an expected call of Mix.
+func (mr *MockIngredientModelMockRecorder) Mix(ctx, inventory any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Mix", reflect.TypeOf((*MockIngredientModel)(nil).Mix), ctx, inventory)
+}
+
+// UpdateIngredient mocks base method.
+func (m *MockIngredientModel) UpdateIngredient(ctx context.Context, ingredient entities.Ingredient) (entities.Ingredient, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateIngredient", ctx, ingredient)
+	ret0, _ := ret[0].(entities.Ingredient)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateIngredient indicates an expected call of UpdateIngredient.
+func (mr *MockIngredientModelMockRecorder) UpdateIngredient(ctx, ingredient any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateIngredient", reflect.TypeOf((*MockIngredientModel)(nil).UpdateIngredient), ctx, ingredient)
+}
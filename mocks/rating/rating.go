@@ -0,0 +1,102 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/rating/model/rating.go
+//
+// Generated by this command:
+//
+//	mockgen -source=internal/rating/model/rating.go -destination=mocks/rating/rating.go -package=mock
+//
+
+// Package mock is a generated GoMock package.
+package mock
+
+import (
+	context "context"
+	reflect "reflect"
+
+	pagination "github.com/SapolovichSV/backprogeng/internal/pagination"
+	entities "github.com/SapolovichSV/backprogeng/internal/rating/entities"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockRatingModel is a mock of RatingModel interface.
+type MockRatingModel struct {
+	ctrl     *gomock.Controller
+	recorder *MockRatingModelMockRecorder
+	isgomock struct{}
+}
+
+// MockRatingModelMockRecorder is the mock recorder for MockRatingModel.
+type MockRatingModelMockRecorder struct {
+	mock *MockRatingModel
+}
+
+// NewMockRatingModel creates a new mock instance.
+func NewMockRatingModel(ctrl *gomock.Controller) *MockRatingModel {
+	mock := &MockRatingModel{ctrl: ctrl}
+	mock.recorder = &MockRatingModelMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRatingModel) EXPECT() *MockRatingModelMockRecorder {
+	return m.recorder
+}
+
+// DeleteRating mocks base method.
+func (m *MockRatingModel) DeleteRating(ctx context.Context, userID, drinkID int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteRating", ctx, userID, drinkID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteRating indicates an expected call of DeleteRating.
+func (mr *MockRatingModelMockRecorder) DeleteRating(ctx, userID, drinkID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteRating", reflect.TypeOf((*MockRatingModel)(nil).DeleteRating), ctx, userID, drinkID)
+}
+
+// DrinkRatings mocks base method.
+func (m *MockRatingModel) DrinkRatings(ctx context.Context, drinkID int, page pagination.Request) (pagination.Page[entities.Rating], error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DrinkRatings", ctx, drinkID, page)
+	ret0, _ := ret[0].(pagination.Page[entities.Rating])
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DrinkRatings indicates an expected call of DrinkRatings.
+func (mr *MockRatingModelMockRecorder) DrinkRatings(ctx, drinkID, page any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DrinkRatings", reflect.TypeOf((*MockRatingModel)(nil).DrinkRatings), ctx, drinkID, page)
+}
+
+// Rate mocks base method.
+func (m *MockRatingModel) Rate(ctx context.Context, userID, drinkID int, rating entities.NewRating) (entities.Rating, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Rate", ctx, userID, drinkID, rating)
+	ret0, _ := ret[0].(entities.Rating)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Rate indicates an expected call of Rate.
+func (mr *MockRatingModelMockRecorder) Rate(ctx, userID, drinkID, rating any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Rate", reflect.TypeOf((*MockRatingModel)(nil).Rate), ctx, userID, drinkID, rating)
+}
+
+// UserRatings mocks base method.
+func (m *MockRatingModel) UserRatings(ctx context.Context, userID int, page pagination.Request) (pagination.Page[entities.Rating], error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UserRatings", ctx, userID, page)
+	ret0, _ := ret[0].(pagination.Page[entities.Rating])
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UserRatings indicates an expected call of UserRatings.
+func (mr *MockRatingModelMockRecorder) UserRatings(ctx, userID, page any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UserRatings", reflect.TypeOf((*MockRatingModel)(nil).UserRatings), ctx, userID, page)
+}
@@ -13,10 +13,79 @@ import (
 	context "context"
 	reflect "reflect"
 
-	entities "github.com/SapolovichSV/backprogeng/internal/user/entities"
+	entities "github.com/SapolovichSV/backprogeng/internal/drink/entities"
+	pagination "github.com/SapolovichSV/backprogeng/internal/pagination"
+	entities0 "github.com/SapolovichSV/backprogeng/internal/user/entities"
 	gomock "go.uber.org/mock/gomock"
 )
 
+// MockpasswordHasher is a mock of passwordHasher interface.
+type MockpasswordHasher struct {
+	ctrl     *gomock.Controller
+	recorder *MockpasswordHasherMockRecorder
+	isgomock struct{}
+}
+
+// MockpasswordHasherMockRecorder is the mock recorder for MockpasswordHasher.
+type MockpasswordHasherMockRecorder struct {
+	mock *MockpasswordHasher
+}
+
+// NewMockpasswordHasher creates a new mock instance.
+func NewMockpasswordHasher(ctrl *gomock.Controller) *MockpasswordHasher {
+	mock := &MockpasswordHasher{ctrl: ctrl}
+	mock.recorder = &MockpasswordHasherMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockpasswordHasher) EXPECT() *MockpasswordHasherMockRecorder {
+	return m.recorder
+}
+
+// Hash mocks base method.
+func (m *MockpasswordHasher) Hash(password string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Hash", password)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Hash indicates an expected call of Hash.
+func (mr *MockpasswordHasherMockRecorder) Hash(password any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Hash", reflect.TypeOf((*MockpasswordHasher)(nil).Hash), password)
+}
+
+// Verify mocks base method.
+func (m *MockpasswordHasher) Verify(hash, password string) (bool, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Verify", hash, password)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// Verify indicates an expected call of Verify.
+func (mr *MockpasswordHasherMockRecorder) Verify(hash, password any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Verify", reflect.TypeOf((*MockpasswordHasher)(nil).Verify), hash, password)
+}
+
+// VerifyDummy mocks base method.
+func (m *MockpasswordHasher) VerifyDummy(password string) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "VerifyDummy", password)
+}
+
+// VerifyDummy indicates an expected call of VerifyDummy.
+func (mr *MockpasswordHasherMockRecorder) VerifyDummy(password any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyDummy", reflect.TypeOf((*MockpasswordHasher)(nil).VerifyDummy), password)
+}
+
 // MockuserModel is a mock of userModel interface.
 type MockuserModel struct {
 	ctrl     *gomock.Controller
@@ -42,10 +111,10 @@ func (m *MockuserModel) EXPECT() *MockuserModelMockRecorder {
 }
 
 // AddFav mocks base method.
-func (m *MockuserModel) AddFav(ctx context.Context, drinkName string, useriD int) (entities.User, error) {
+func (m *MockuserModel) AddFav(ctx context.Context, drinkName string, useriD int) (entities0.User, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "AddFav", ctx, drinkName, useriD)
-	ret0, _ := ret[0].(entities.User)
+	ret0, _ := ret[0].(entities0.User)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
@@ -57,10 +126,10 @@ func (mr *MockuserModelMockRecorder) AddFav(ctx, drinkName, useriD any) *gomock.
 }
 
 // CreateUser mocks base method.
-func (m *MockuserModel) CreateUser(ctx context.Context, user entities.User) (entities.User, error) {
+func (m *MockuserModel) CreateUser(ctx context.Context, user entities0.User) (entities0.User, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "CreateUser", ctx, user)
-	ret0, _ := ret[0].(entities.User)
+	ret0, _ := ret[0].(entities0.User)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
@@ -71,11 +140,85 @@ func (mr *MockuserModelMockRecorder) CreateUser(ctx, user any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUser", reflect.TypeOf((*MockuserModel)(nil).CreateUser), ctx, user)
 }
 
+// Favourites mocks base method.
+func (m *MockuserModel) Favourites(ctx context.Context, userID int, page pagination.Request) (pagination.Page[entities.Drink], error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Favourites", ctx, userID, page)
+	ret0, _ := ret[0].(pagination.Page[entities.Drink])
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Favourites indicates an expected call of Favourites.
+func (mr *MockuserModelMockRecorder) Favourites(ctx, userID, page any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Favourites", reflect.TypeOf((*MockuserModel)(nil).Favourites), ctx, userID, page)
+}
+
+// Recommendations mocks base method.
+func (m *MockuserModel) Recommendations(ctx context.Context, userID, limit int) ([]entities0.Recommendation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Recommendations", ctx, userID, limit)
+	ret0, _ := ret[0].([]entities0.Recommendation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Recommendations indicates an expected call of Recommendations.
+func (mr *MockuserModelMockRecorder) Recommendations(ctx, userID, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Recommendations", reflect.TypeOf((*MockuserModel)(nil).Recommendations), ctx, userID, limit)
+}
+
+// RemoveFav mocks base method.
+func (m *MockuserModel) RemoveFav(ctx context.Context, drinkName string, userID int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoveFav", ctx, drinkName, userID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RemoveFav indicates an expected call of RemoveFav.
+func (mr *MockuserModelMockRecorder) RemoveFav(ctx, drinkName, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveFav", reflect.TypeOf((*MockuserModel)(nil).RemoveFav), ctx, drinkName, userID)
+}
+
+// RoleByUserID mocks base method.
+func (m *MockuserModel) RoleByUserID(ctx context.Context, userID int) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RoleByUserID", ctx, userID)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RoleByUserID indicates an expected call of RoleByUserID.
+func (mr *MockuserModelMockRecorder) RoleByUserID(ctx, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RoleByUserID", reflect.TypeOf((*MockuserModel)(nil).RoleByUserID), ctx, userID)
+}
+
+// SetRole mocks base method.
+func (m *MockuserModel) SetRole(ctx context.Context, userID int, role string) (entities0.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetRole", ctx, userID, role)
+	ret0, _ := ret[0].(entities0.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SetRole indicates an expected call of SetRole.
+func (mr *MockuserModelMockRecorder) SetRole(ctx, userID, role any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetRole", reflect.TypeOf((*MockuserModel)(nil).SetRole), ctx, userID, role)
+}
+
 // UserByID mocks base method.
-func (m *MockuserModel) UserByID(ctx context.Context, id int) (entities.User, error) {
+func (m *MockuserModel) UserByID(ctx context.Context, id int) (entities0.User, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "UserByID", ctx, id)
-	ret0, _ := ret[0].(entities.User)
+	ret0, _ := ret[0].(entities0.User)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
@@ -85,3 +228,46 @@ func (mr *MockuserModelMockRecorder) UserByID(ctx, id any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UserByID", reflect.TypeOf((*MockuserModel)(nil).UserByID), ctx, id)
 }
+
+// UserByUsername mocks base method.
+func (m *MockuserModel) UserByUsername(ctx context.Context, username string) (entities0.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UserByUsername", ctx, username)
+	ret0, _ := ret[0].(entities0.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UserByUsername indicates an expected call of UserByUsername.
+func (mr *MockuserModelMockRecorder) UserByUsername(ctx, username any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UserByUsername", reflect.TypeOf((*MockuserModel)(nil).UserByUsername), ctx, username)
+}
+
+// VerifyPassword mocks base method.
+func (m *MockuserModel) VerifyPassword(ctx context.Context, userID int, password string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VerifyPassword", ctx, userID, password)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// VerifyPassword indicates an expected call of VerifyPassword.
+func (mr *MockuserModelMockRecorder) VerifyPassword(ctx, userID, password any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyPassword", reflect.TypeOf((*MockuserModel)(nil).VerifyPassword), ctx, userID, password)
+}
+
+// VerifyUnknownUserPassword mocks base method.
+func (m *MockuserModel) VerifyUnknownUserPassword(ctx context.Context, password string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VerifyUnknownUserPassword", ctx, password)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// VerifyUnknownUserPassword indicates an expected call of VerifyUnknownUserPassword.
+func (mr *MockuserModelMockRecorder) VerifyUnknownUserPassword(ctx, password any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyUnknownUserPassword", reflect.TypeOf((*MockuserModel)(nil).VerifyUnknownUserPassword), ctx, password)
+}